@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/backtest"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+)
+
+// runBacktest implements the backtest subcommand, replaying a metric trace
+// through the configured policy and printing the resulting capacity timeline
+func runBacktest(args []string) error {
+	flags := flag.NewFlagSet("backtest", flag.ContinueOnError)
+	tracePath := flags.String("trace", "", "path to the metric trace (CSV or JSONL)")
+	traceFormat := flags.String("format", "", "trace format: csv or jsonl (default: inferred from the file extension)")
+	configPath := flags.String("config", "", "file of KEY=VALUE lines with the autoscaler configuration to test")
+	initialCapacity := flags.Int("initial-capacity", 1, "capacity of the resource when the trace starts")
+	scaleUpDelay := flags.Duration("scale-up-delay", 2*time.Minute, "time for added capacity to become ACTIVE")
+	scaleDownDelay := flags.Duration("scale-down-delay", 30*time.Second, "time for removed capacity to become ACTIVE")
+	perReplica := flags.String("per-replica", "", "comma-separated trace metrics recording total demand, divided by capacity")
+	sloMetric := flags.String("slo-metric", "", "trace metric checked against the SLO threshold")
+	sloThreshold := flags.Float64("slo-threshold", 0, "value of the SLO metric above which the SLO is violated")
+	output := flags.String("output", "text", "output format: text or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *tracePath == "" {
+		return fmt.Errorf("-trace is required")
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid -output value: %s", *output)
+	}
+
+	// Keep the replay quiet unless a log level was asked for
+	if os.Getenv("LOG_LEVEL") == "" {
+		os.Setenv("LOG_LEVEL", "warn")
+		logger.InitLogger()
+	}
+	if os.Getenv("AUTOSCALER_TARGET_RESOURCE") == "" {
+		os.Setenv("AUTOSCALER_TARGET_RESOURCE", "backtest")
+	}
+	if *configPath != "" {
+		if err := loadEnvFile(*configPath); err != nil {
+			return err
+		}
+	}
+
+	cfg, err := config.NewConfigFromEnv()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	trace, err := backtest.LoadTrace(*tracePath, backtest.Format(*traceFormat))
+	if err != nil {
+		return err
+	}
+
+	var perReplicaMetrics []string
+	for _, name := range strings.Split(*perReplica, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			perReplicaMetrics = append(perReplicaMetrics, name)
+		}
+	}
+
+	result, err := backtest.Run(context.Background(), cfg, trace, backtest.Options{
+		InitialCapacity: *initialCapacity,
+		ScaleUpDelay:    *scaleUpDelay,
+		ScaleDownDelay:  *scaleDownDelay,
+		PerReplica:      perReplicaMetrics,
+		SLOMetric:       *sloMetric,
+		SLOThreshold:    *sloThreshold,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *output == "json" {
+		return backtest.WriteJSON(os.Stdout, result)
+	}
+	return backtest.WriteText(os.Stdout, result)
+}
+
+// loadEnvFile sets environment variables from a file of KEY=VALUE lines,
+// ignoring blank lines and comments
+func loadEnvFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+		if !ok {
+			return fmt.Errorf("%s line %d: expected KEY=VALUE", path, line)
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		if err := os.Setenv(strings.TrimSpace(key), value); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
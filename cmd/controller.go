@@ -3,19 +3,30 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/audit"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/auth"
 	"github.com/omnistrate-community/custom-auto-scaling-example/internal/autoscaler"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
 	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/proxy"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/tlsserver"
 )
 
+// ScaleRequest asks for a target capacity. Only admins may scale outside the
+// configured capacity bounds or skip the cooldown period with BypassCooldown.
 type ScaleRequest struct {
-	TargetCapacity int `json:"targetCapacity"`
+	TargetCapacity int  `json:"targetCapacity"`
+	BypassCooldown bool `json:"bypassCooldown,omitempty"`
 }
 
 type ScaleResponse struct {
@@ -25,16 +36,76 @@ type ScaleResponse struct {
 }
 
 type StatusResponse struct {
-	CurrentCapacity   int           `json:"currentCapacity"`
-	Status            string        `json:"status"`
-	TargetCapacity    int           `json:"targetCapacity"`
-	ScalingInProgress bool          `json:"scalingInProgress"`
-	LastActionTime    time.Time     `json:"lastActionTime"`
-	InCooldownPeriod  bool          `json:"inCooldownPeriod"`
-	CooldownRemaining time.Duration `json:"cooldownRemaining"`
-	InstanceID        string        `json:"instanceId"`
-	ResourceID        string        `json:"resourceId"`
-	ResourceAlias     string        `json:"resourceAlias"`
+	CurrentCapacity   int                            `json:"currentCapacity"`
+	Status            string                         `json:"status"`
+	TargetCapacity    int                            `json:"targetCapacity"`
+	ScalingInProgress bool                           `json:"scalingInProgress"`
+	LastActionTime    time.Time                      `json:"lastActionTime"`
+	InCooldownPeriod  bool                           `json:"inCooldownPeriod"`
+	CooldownRemaining time.Duration                  `json:"cooldownRemaining"`
+	InstanceID        string                         `json:"instanceId"`
+	ResourceID        string                         `json:"resourceId"`
+	ResourceAlias     string                         `json:"resourceAlias"`
+	LastDecision      *autoscaler.Decision           `json:"lastDecision,omitempty"`
+	Throttle          *omnistrate_api.ThrottleStatus `json:"throttle,omitempty"`
+	LastObservedTime  *time.Time                     `json:"lastObservedTimestamp,omitempty"`
+	ObservationAge    time.Duration                  `json:"observationAge"`
+	ObservationStale  bool                           `json:"observationStale"`
+	Warnings          []string                       `json:"warnings,omitempty"`
+	StatePolicy       string                         `json:"statePolicy"`
+	StateSince        time.Time                      `json:"stateSince"`
+	Recovery          autoscaler.RecoveryStatus      `json:"recovery"`
+	LastReload        *autoscaler.ReloadResult       `json:"lastReload,omitempty"`
+}
+
+// RecommendationsResponse represents the latest policy recommendation
+type RecommendationsResponse struct {
+	RecommendOnly  bool                       `json:"recommendOnly"`
+	Recommendation *autoscaler.Recommendation `json:"recommendation"`
+}
+
+// ShadowsResponse represents the divergence statistics of the shadow policies
+type ShadowsResponse struct {
+	Shadows []autoscaler.ShadowStats `json:"shadows"`
+}
+
+// TransitionsResponse represents the recent changes of the instance status
+type TransitionsResponse struct {
+	Transitions []autoscaler.StateTransition `json:"transitions"`
+}
+
+// DecisionsResponse represents the recent policy decisions
+type DecisionsResponse struct {
+	Decisions []autoscaler.Decision `json:"decisions"`
+}
+
+// ConfigResponse represents the configuration in effect, in the format of the
+// configuration file, and the runtime overrides applied to it
+type ConfigResponse struct {
+	Config    *config.File      `json:"config"`
+	Overrides []config.Override `json:"overrides"`
+}
+
+// ConfigPatchRequest changes settings at runtime. Settings uses the keys of the
+// configuration file; a TTL such as "4h" reverts the change once it has passed.
+type ConfigPatchRequest struct {
+	Settings json.RawMessage `json:"settings"`
+	TTL      string          `json:"ttl,omitempty"`
+	Reason   string          `json:"reason,omitempty"`
+}
+
+// ConfigPatchResponse represents the outcome of a runtime configuration change
+type ConfigPatchResponse struct {
+	Success  bool             `json:"success"`
+	Message  string           `json:"message,omitempty"`
+	Override *config.Override `json:"override,omitempty"`
+	Error    string           `json:"error,omitempty"`
+	Problems []string         `json:"problems,omitempty"`
+}
+
+// AuditResponse represents the recent changes made by operators
+type AuditResponse struct {
+	Entries []audit.Entry `json:"entries"`
 }
 
 var autoScaler *autoscaler.Autoscaler
@@ -49,6 +120,9 @@ func scaleHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if !authorize(w, r, auth.RoleOperator) {
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 
@@ -85,24 +159,25 @@ func scaleHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Perform scaling operation
-	ctx := context.Background()
-	err := autoScaler.ScaleToTarget(ctx, req.TargetCapacity)
+	// Operators scale within the capacity bounds and wait for the cooldown
+	// period; anything else takes an admin
+	if !auth.Allowed(r.Context(), auth.RoleAdmin) {
+		if message := operatorScaleViolation(req, autoScaler.GetConfig()); message != "" {
+			forbidden(w, r, message)
+			return
+		}
+	}
+
+	// Perform scaling operation, which outlives the request
+	ctx := autoscaler.WithPrincipal(context.Background(), requestActor(r))
+	err := autoScaler.RequestScale(ctx, req.TargetCapacity, req.BypassCooldown)
 	if err != nil {
 		logger.Warn().Err(err).Msg("Scaling failed")
 
 		// Get current status to include in error response
 		currentStatus, statusErr := autoScaler.GetStatus(ctx)
 
-		// Check if it's an "already in progress" error
-		errMsg := err.Error()
-		isInProgress := false
-		if len(errMsg) >= 34 && errMsg[:34] == "scaling operation already in progress" {
-			isInProgress = true
-			errMsg = "A scaling operation is already in progress. Please wait for it to complete."
-		} else {
-			errMsg = fmt.Sprintf("Scaling failed: %v", err)
-		}
+		statusCode, errMsg := scaleErrorResponse(err)
 
 		if statusErr == nil {
 			// Include current status information in the error response
@@ -117,12 +192,7 @@ func scaleHandler(w http.ResponseWriter, r *http.Request) {
 					"resourceAlias":   currentStatus.ResourceAlias,
 				},
 			}
-			// Use 409 Conflict for "already in progress" errors
-			if isInProgress {
-				w.WriteHeader(http.StatusConflict)
-			} else {
-				w.WriteHeader(http.StatusInternalServerError)
-			}
+			w.WriteHeader(statusCode)
 			err := json.NewEncoder(w).Encode(response)
 			if err != nil {
 				logger.Warn().Err(err).Msg("Failed to encode JSON response")
@@ -135,11 +205,7 @@ func scaleHandler(w http.ResponseWriter, r *http.Request) {
 				Success: false,
 				Error:   errMsg,
 			}
-			if isInProgress {
-				w.WriteHeader(http.StatusConflict)
-			} else {
-				w.WriteHeader(http.StatusInternalServerError)
-			}
+			w.WriteHeader(statusCode)
 			err := json.NewEncoder(w).Encode(response)
 			if err != nil {
 				logger.Warn().Err(err).Msg("Failed to encode JSON response")
@@ -163,11 +229,74 @@ func scaleHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// scaleErrorResponse maps a failed scaling operation to the HTTP status and
+// message reported to the caller
+func scaleErrorResponse(err error) (int, string) {
+	var apiErr *omnistrate_api.APIError
+	switch {
+	case errors.Is(err, autoscaler.ErrScalingInProgress):
+		return http.StatusConflict, "A scaling operation is already in progress. Please wait for it to complete."
+	case errors.Is(err, omnistrate_api.ErrNotFound):
+		return http.StatusNotFound, fmt.Sprintf("Scaling failed, the sidecar does not know the target resource: %v", err)
+	case errors.Is(err, omnistrate_api.ErrConflict):
+		return http.StatusConflict, fmt.Sprintf("Scaling failed, the resource cannot change capacity in its current state: %v", err)
+	case errors.Is(err, autoscaler.ErrCircuitOpen):
+		return http.StatusServiceUnavailable, fmt.Sprintf("Scaling is blocked by the circuit breaker, reset it with POST /recovery/reset: %v", err)
+	case errors.Is(err, autoscaler.ErrInstanceState):
+		return http.StatusConflict, fmt.Sprintf("Scaling failed, the instance cannot be scaled in its current state: %v", err)
+	case errors.Is(err, autoscaler.ErrStaleObservation):
+		return http.StatusServiceUnavailable, fmt.Sprintf("Scaling failed, the sidecar has no current observation of the resource: %v", err)
+	case errors.Is(err, omnistrate_api.ErrRateLimited):
+		return http.StatusTooManyRequests, fmt.Sprintf("Scaling failed, the sidecar is rate limiting requests: %v", err)
+	case errors.As(err, &apiErr) && apiErr.Retryable:
+		return http.StatusServiceUnavailable, fmt.Sprintf("Scaling failed, the sidecar is unavailable: %v", err)
+	case errors.As(err, &apiErr):
+		return http.StatusBadGateway, fmt.Sprintf("Scaling failed: %v", err)
+	default:
+		return http.StatusInternalServerError, fmt.Sprintf("Scaling failed: %v", err)
+	}
+}
+
+func wakeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !authorize(w, r, auth.RoleOperator) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// Wake in the background so that callers are not held for the provisioning time
+	ctx := autoscaler.WithPrincipal(context.Background(), requestActor(r))
+	go func() {
+		if err := autoScaler.Wake(ctx, "wake requested via API"); err != nil {
+			logger.Warn().Err(err).Msg("Wake failed")
+		}
+	}()
+
+	response := ScaleResponse{
+		Success: true,
+		Message: "Wake requested",
+	}
+	w.WriteHeader(http.StatusAccepted)
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to encode JSON response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
 func statusHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if !authorize(w, r, auth.RoleViewer) {
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 
@@ -200,6 +329,32 @@ func statusHandler(w http.ResponseWriter, r *http.Request) {
 		InstanceID:        capacity.InstanceID,
 		ResourceID:        capacity.ResourceID,
 		ResourceAlias:     capacity.ResourceAlias,
+		LastDecision:      capacity.LastDecision,
+		Throttle:          capacity.Throttle,
+		ObservationAge:    capacity.ObservationAge,
+		ObservationStale:  capacity.ObservationStale,
+		StatePolicy:       capacity.StatePolicy,
+		StateSince:        capacity.StateSince,
+		Recovery:          capacity.Recovery,
+		LastReload:        capacity.LastReload,
+	}
+	if !capacity.LastObservedTime.IsZero() {
+		response.LastObservedTime = &capacity.LastObservedTime
+	}
+	if capacity.Recovery.CircuitOpen {
+		response.Warnings = append(response.Warnings, fmt.Sprintf(
+			"Scaling is blocked by the circuit breaker (%s), reset it with POST /recovery/reset",
+			capacity.Recovery.Reason))
+	}
+	if capacity.LastReload != nil && !capacity.LastReload.Success {
+		response.Warnings = append(response.Warnings, fmt.Sprintf(
+			"The last configuration reload failed, the previous configuration stays in effect: %s",
+			capacity.LastReload.Error))
+	}
+	if capacity.ObservationStale {
+		response.Warnings = append(response.Warnings, fmt.Sprintf(
+			"The sidecar last observed the resource %s ago, scaling is paused until it reports a current observation",
+			capacity.ObservationAge.Round(time.Second)))
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -211,6 +366,310 @@ func statusHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func decisionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !authorize(w, r, auth.RoleViewer) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := DecisionsResponse{
+		Decisions: autoScaler.Decisions(),
+	}
+	if response.Decisions == nil {
+		response.Decisions = []autoscaler.Decision{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to encode JSON response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func recoveryResetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !authorize(w, r, auth.RoleAdmin) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := ScaleResponse{
+		Success: true,
+		Message: "Circuit breaker was not open",
+	}
+	if autoScaler.ResetCircuitBreaker(autoscaler.WithPrincipal(r.Context(), requestActor(r))) {
+		logger.Info().Str("principal", requestActor(r)).Msg("Circuit breaker reset via API")
+		response.Message = "Circuit breaker reset, scaling is unblocked"
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to encode JSON response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func transitionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !authorize(w, r, auth.RoleViewer) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := TransitionsResponse{
+		Transitions: autoScaler.Transitions(),
+	}
+	if response.Transitions == nil {
+		response.Transitions = []autoscaler.StateTransition{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to encode JSON response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func recommendationsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !authorize(w, r, auth.RoleViewer) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := RecommendationsResponse{
+		RecommendOnly:  autoScaler.GetConfig().RecommendOnly,
+		Recommendation: autoScaler.LatestRecommendation(),
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to encode JSON response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func shadowsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !authorize(w, r, auth.RoleViewer) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	response := ShadowsResponse{
+		Shadows: autoScaler.ShadowStats(),
+	}
+
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to encode JSON response")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func configHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if !authorize(w, r, auth.RoleViewer) {
+			return
+		}
+		file := config.NewFile(autoScaler.GetConfig())
+		file.Redact()
+		response := ConfigResponse{
+			Config:    file,
+			Overrides: autoScaler.Overrides(),
+		}
+		if response.Overrides == nil {
+			response.Overrides = []config.Override{}
+		}
+		writeJSON(w, http.StatusOK, response)
+	case http.MethodPatch:
+		if !authorize(w, r, auth.RoleAdmin) {
+			return
+		}
+		patchConfig(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// patchConfig validates a runtime configuration change and puts it into effect
+func patchConfig(w http.ResponseWriter, r *http.Request) {
+	var req ConfigPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ConfigPatchResponse{Error: fmt.Sprintf("Invalid JSON: %v", err)})
+		return
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			writeJSON(w, http.StatusBadRequest, ConfigPatchResponse{Error: fmt.Sprintf("TTL must be a positive duration such as 4h, got %q", req.TTL)})
+			return
+		}
+	}
+
+	settings, err := config.ParsePatch(req.Settings, autoScaler.GetConfig())
+	if err == nil {
+		var override config.Override
+		override, err = autoScaler.UpdateConfig(autoscaler.ConfigChange{
+			Settings: settings,
+			TTL:      ttl,
+			Actor:    requestActor(r),
+			Reason:   req.Reason,
+		})
+		if err == nil {
+			message := "Configuration changed"
+			if override.ExpiresAt != nil {
+				message = fmt.Sprintf("Configuration changed until %s", override.ExpiresAt.Format(time.RFC3339))
+			}
+			writeJSON(w, http.StatusOK, ConfigPatchResponse{Success: true, Message: message, Override: &override})
+			return
+		}
+	}
+
+	logger.Warn().Err(err).Msg("Configuration change rejected")
+	var validationErr *config.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		writeJSON(w, http.StatusBadRequest, ConfigPatchResponse{Error: "Invalid configuration", Problems: validationErr.Problems})
+	case errors.Is(err, autoscaler.ErrInvalidConfig), errors.Is(err, autoscaler.ErrRestartRequired):
+		writeJSON(w, http.StatusBadRequest, ConfigPatchResponse{Error: err.Error()})
+	default:
+		writeJSON(w, http.StatusInternalServerError, ConfigPatchResponse{Error: fmt.Sprintf("Configuration change failed: %v", err)})
+	}
+}
+
+func configOverrideHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !authorize(w, r, auth.RoleAdmin) {
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/config/overrides/")
+	override, err := autoScaler.RevertOverride(id, requestActor(r))
+	switch {
+	case errors.Is(err, config.ErrOverrideNotFound):
+		writeJSON(w, http.StatusNotFound, ConfigPatchResponse{Error: err.Error()})
+	case errors.Is(err, autoscaler.ErrInvalidConfig):
+		writeJSON(w, http.StatusConflict, ConfigPatchResponse{Error: err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, ConfigPatchResponse{Error: fmt.Sprintf("Reverting the change failed: %v", err)})
+	default:
+		writeJSON(w, http.StatusOK, ConfigPatchResponse{Success: true, Message: "Configuration change reverted", Override: &override})
+	}
+}
+
+func auditHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !authorize(w, r, auth.RoleViewer) {
+		return
+	}
+
+	response := AuditResponse{
+		Entries: autoScaler.AuditTrail(),
+	}
+	if response.Entries == nil {
+		response.Entries = []audit.Entry{}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// operatorScaleViolation returns why operators may not make a scale request,
+// or an empty string when they may
+func operatorScaleViolation(req ScaleRequest, cfg *config.Config) string {
+	if req.BypassCooldown {
+		return "Overriding the cooldown period requires the admin role"
+	}
+	if req.TargetCapacity < cfg.MinCapacity || (cfg.MaxCapacity > 0 && req.TargetCapacity > cfg.MaxCapacity) {
+		bounds := fmt.Sprintf("to at least %d", cfg.MinCapacity)
+		if cfg.MaxCapacity > 0 {
+			bounds = fmt.Sprintf("between %d and %d", cfg.MinCapacity, cfg.MaxCapacity)
+		}
+		return fmt.Sprintf("Operators can scale %s, scaling to %d requires the admin role", bounds, req.TargetCapacity)
+	}
+	return ""
+}
+
+// authorize checks that the caller of a request has the required role,
+// answering 403 when it does not
+func authorize(w http.ResponseWriter, r *http.Request, required auth.Role) bool {
+	if auth.Allowed(r.Context(), required) {
+		return true
+	}
+	forbidden(w, r, fmt.Sprintf("This request requires the %s role", required))
+	return false
+}
+
+// forbidden answers a request the caller is not allowed to make
+func forbidden(w http.ResponseWriter, r *http.Request, message string) {
+	principal := auth.FromContext(r.Context())
+	logger.Info().
+		Str("principal", principal.Name).
+		Str("role", string(principal.Role)).
+		Str("method", r.Method).
+		Str("path", r.URL.Path).
+		Msg("Rejected request of insufficient role")
+	writeJSON(w, http.StatusForbidden, ScaleResponse{Success: false, Error: message})
+}
+
+// requestActor identifies who made a request in the audit trail: the
+// authenticated principal, or the remote address without authentication
+func requestActor(r *http.Request) string {
+	if principal := auth.FromContext(r.Context()); principal != nil {
+		return principal.Name
+	}
+	return r.RemoteAddr
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, statusCode int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to encode JSON response")
+	}
+}
+
 func healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -222,7 +681,11 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func homeHandler(w http.ResponseWriter, r *http.Request) {
+	if !authorize(w, r, auth.RoleViewer) {
+		return
+	}
 	config := autoScaler.GetConfig()
+	sessionBar, csrfToken := sessionBar(r)
 	_, err := fmt.Fprintf(w, `
 <!DOCTYPE html>
 <html>
@@ -302,6 +765,34 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
             line-height: 1.6;
         }
         
+        .config-line input {
+            width: 140px;
+            margin-bottom: 0;
+            padding: 6px 10px;
+        }
+        
+        input[type="text"] {
+            font-family: 'Inter', sans-serif;
+            font-size: 14px;
+            color: #2d3748;
+            border: 1px solid #cbd5e0;
+            border-radius: 8px;
+        }
+        
+        .override-line {
+            margin: 8px 0;
+            display: flex;
+            justify-content: space-between;
+            align-items: center;
+            font-size: 12px;
+            color: #4a5568;
+        }
+        
+        .override-line button {
+            padding: 6px 12px;
+            font-size: 12px;
+        }
+        
         .label { 
             color: #4a5568;
             font-weight: 500;
@@ -366,6 +857,12 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
             display: flex;
         }
         
+        .bypass-cooldown {
+            display: block;
+            font-size: 13px;
+            color: #4a5568;
+        }
+        
         .control-group.status-control button {
             flex: 1;
         }
@@ -440,6 +937,21 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
             font-weight: 600;
         }
         
+        .session-bar {
+            display: flex;
+            justify-content: flex-end;
+            align-items: center;
+            gap: 12px;
+            font-size: 13px;
+            color: #4a5568;
+            margin-bottom: 16px;
+        }
+        
+        .session-bar button {
+            padding: 6px 12px;
+            font-size: 13px;
+        }
+        
         .timestamp {
             text-align: center;
             font-size: 12px;
@@ -451,6 +963,7 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
 <body>
     <div class="crt">
         <div class="screen">
+            %s
             <div class="header">
                 <h1>Custom Autoscaler</h1>
                 <div class="subtitle">Omnistrate Example</div>
@@ -462,9 +975,37 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
                     <span class="value">%s</span>
                 </div>
                 <div class="config-line">
-                    <span class="label">Cooldown Duration</span>
-                    <span class="value">%v</span>
+                    <span class="label">Cooldown (seconds)</span>
+                    <input type="number" id="configCooldown" min="0">
+                </div>
+                <div class="config-line">
+                    <span class="label">Steps</span>
+                    <input type="number" id="configSteps" min="1">
+                </div>
+                <div class="config-line">
+                    <span class="label">Min Capacity</span>
+                    <input type="number" id="configMinCapacity" min="0">
+                </div>
+                <div class="config-line">
+                    <span class="label">Max Capacity (0 = unbounded)</span>
+                    <input type="number" id="configMaxCapacity" min="0">
+                </div>
+                <div class="config-line" id="configPolicyTargetLine" style="display: none;">
+                    <span class="label">Policy Target</span>
+                    <input type="number" id="configPolicyTarget" step="any">
+                </div>
+                <div class="config-line">
+                    <span class="label">Revert After (hours, optional)</span>
+                    <input type="number" id="configTTL" min="0" step="any" placeholder="keep">
+                </div>
+                <div class="config-line">
+                    <span class="label">Reason</span>
+                    <input type="text" id="configReason" placeholder="e.g. incident response">
+                </div>
+                <div class="control-group status-control">
+                    <button onclick="saveConfig()">Apply Configuration</button>
                 </div>
+                <div id="configOverrides"></div>
             </div>
             
             <div class="status-display" id="statusDisplay">
@@ -479,6 +1020,7 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
                     <input type="number" id="targetCapacity" placeholder="Enter target capacity" min="0" value="1">
                     <button onclick="scaleTarget()">Scale to Target</button>
                 </div>
+                <label class="bypass-cooldown"><input type="checkbox" id="bypassCooldown"> Override cooldown period (admin)</label>
                 <div class="control-group status-control">
                     <button onclick="getStatus()">Get Status</button>
                 </div>
@@ -489,6 +1031,9 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
     </div>
     
     <script>
+        // CSRF token of the dashboard session, sent with every request that changes something
+        const csrfToken = '%s';
+        
         function updateTimestamp() {
             const now = new Date();
             document.getElementById('timestamp').textContent = 
@@ -540,7 +1085,39 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
                         const cooldownSecs = Math.round(data.cooldownRemaining / 1000000000);
                         statusDisplay += '<div class="status-line" style="color: #ed8936;">⏱ Cooldown period: ' + cooldownSecs + 's remaining</div>';
                     }
-                    
+
+                    // Observation age and staleness warnings
+                    if (data.lastObservedTimestamp) {
+                        const ageSecs = Math.round(data.observationAge / 1000000000);
+                        const ageStyle = data.observationStale ? ' style="color: #e53e3e;"' : '';
+                        statusDisplay += '<div class="status-line"' + ageStyle + '><strong>Observed:</strong> ' + ageSecs + 's ago</div>';
+                    }
+                    if (data.warnings) {
+                        data.warnings.forEach(function(warning) {
+                            statusDisplay += '<div class="status-line" style="color: #e53e3e;">⚠ ' + warning + '</div>';
+                        });
+                    }
+
+                    // Sidecar throttling
+                    if (data.throttle && data.throttle.throttled) {
+                        let throttleStr = '🐢 Sidecar requests throttled';
+                        if (data.throttle.retryAfterUntil) {
+                            const retrySecs = Math.max(0, Math.round((new Date(data.throttle.retryAfterUntil) - new Date()) / 1000));
+                            throttleStr += ' (retry after ' + retrySecs + 's)';
+                        }
+                        statusDisplay += '<div class="status-line" style="color: #ed8936;">' + throttleStr + '</div>';
+                    }
+
+                    // Last configuration reload
+                    if (data.lastReload && data.lastReload.success) {
+                        const reloadSecs = Math.round((new Date() - new Date(data.lastReload.time)) / 1000);
+                        let reloadStr = '↻ Configuration reloaded ' + reloadSecs + 's ago (' + data.lastReload.trigger + ')';
+                        if (data.lastReload.restartRequired) {
+                            reloadStr += ', restart to apply ' + data.lastReload.restartRequired.join(', ');
+                        }
+                        statusDisplay += '<div class="status-line" style="color: #667eea;">' + reloadStr + '</div>';
+                    }
+
                     // Last action time if available
                     if (data.lastActionTime && data.lastActionTime !== '0001-01-01T00:00:00Z') {
                         const lastAction = new Date(data.lastActionTime);
@@ -592,8 +1169,11 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
             try {
                 const response = await fetch('/scale', {
                     method: 'POST',
-                    headers: { 'Content-Type': 'application/json' },
-                    body: JSON.stringify({ targetCapacity: capacity })
+                    headers: { 'Content-Type': 'application/json', 'X-CSRF-Token': csrfToken },
+                    body: JSON.stringify({
+                        targetCapacity: capacity,
+                        bypassCooldown: document.getElementById('bypassCooldown').checked
+                    })
                 });
                 
                 const data = await response.json();
@@ -636,6 +1216,139 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
             }
         }
         
+        let currentConfig = null;
+        
+        async function loadConfig() {
+            try {
+                const response = await fetch('/config');
+                const data = await response.json();
+                if (!response.ok) {
+                    return;
+                }
+                currentConfig = data.config;
+                document.getElementById('configCooldown').value = currentConfig.cooldown;
+                document.getElementById('configSteps').value = currentConfig.steps;
+                document.getElementById('configMinCapacity').value = currentConfig.minCapacity;
+                document.getElementById('configMaxCapacity').value = currentConfig.maxCapacity;
+                const hasTarget = currentConfig.policy && (currentConfig.policy.type === 'target-tracking' || currentConfig.policy.type === 'concurrency');
+                document.getElementById('configPolicyTargetLine').style.display = hasTarget ? 'flex' : 'none';
+                if (hasTarget) {
+                    document.getElementById('configPolicyTarget').value = currentConfig.policy.target;
+                }
+                
+                // Overrides carry operator-supplied text, so the rows are built
+                // as DOM nodes rather than markup.
+                const overrides = document.getElementById('configOverrides');
+                overrides.replaceChildren();
+                for (const override of data.overrides) {
+                    let description = Object.keys(override.settings).join(', ');
+                    if (override.expiresAt) {
+                        description += ' until ' + new Date(override.expiresAt).toLocaleString();
+                    }
+                    if (override.reason) {
+                        description += ' (' + override.reason + ')';
+                    }
+                    const line = document.createElement('div');
+                    line.className = 'override-line';
+                    const text = document.createElement('span');
+                    text.textContent = '✎ ' + description;
+                    const revert = document.createElement('button');
+                    revert.textContent = 'Revert';
+                    revert.addEventListener('click', () => revertOverride(override.id));
+                    line.append(text, revert);
+                    overrides.append(line);
+                }
+            } catch (error) {
+                console.error('Failed to load configuration', error);
+            }
+        }
+        
+        async function saveConfig() {
+            if (!currentConfig) {
+                return;
+            }
+            const settings = {};
+            const fields = [
+                ['configCooldown', 'cooldown'],
+                ['configSteps', 'steps'],
+                ['configMinCapacity', 'minCapacity'],
+                ['configMaxCapacity', 'maxCapacity']
+            ];
+            for (const [id, key] of fields) {
+                const value = parseInt(document.getElementById(id).value);
+                if (!isNaN(value) && value !== currentConfig[key]) {
+                    settings[key] = value;
+                }
+            }
+            const target = parseFloat(document.getElementById('configPolicyTarget').value);
+            if (currentConfig.policy && !isNaN(target) && target !== currentConfig.policy.target) {
+                settings.policy = { target: target };
+            }
+            if (Object.keys(settings).length === 0) {
+                displayStatus('<div class="status-line">No configuration changes to apply</div>');
+                return;
+            }
+            
+            const request = { settings: settings, reason: document.getElementById('configReason').value };
+            const ttlHours = parseFloat(document.getElementById('configTTL').value);
+            if (!isNaN(ttlHours) && ttlHours > 0) {
+                request.ttl = ttlHours + 'h';
+            }
+            
+            showLoading(true);
+            try {
+                const response = await fetch('/config', {
+                    method: 'PATCH',
+                    headers: { 'Content-Type': 'application/json', 'X-CSRF-Token': csrfToken },
+                    body: JSON.stringify(request)
+                });
+                const data = await response.json();
+                if (response.ok && data.success) {
+                    displayStatus(
+                        '<div class="status-line success"><strong>✓ Configuration Changed</strong></div>' +
+                        '<div class="status-line">' + data.message + '</div>'
+                    );
+                } else {
+                    let errorDisplay = '<div class="status-line error"><strong>✗ Configuration Rejected</strong></div>' +
+                        '<div class="status-line error">' + (data.error || 'Unknown error') + '</div>';
+                    for (const problem of (data.problems || [])) {
+                        errorDisplay += '<div class="status-line error">- ' + problem + '</div>';
+                    }
+                    displayStatus(errorDisplay, true);
+                }
+            } catch (error) {
+                displayStatus(
+                    '<div class="status-line error"><strong>✗ Connection Error</strong></div>' +
+                    '<div class="status-line error">' + error.message + '</div>',
+                    true
+                );
+            } finally {
+                showLoading(false);
+                loadConfig();
+            }
+        }
+        
+        async function revertOverride(id) {
+            showLoading(true);
+            try {
+                const response = await fetch('/config/overrides/' + encodeURIComponent(id), {
+                    method: 'DELETE',
+                    headers: { 'X-CSRF-Token': csrfToken }
+                });
+                const data = await response.json();
+                if (response.ok && data.success) {
+                    displayStatus('<div class="status-line success"><strong>✓ ' + data.message + '</strong></div>');
+                } else {
+                    displayStatus('<div class="status-line error"><strong>✗ ' + (data.error || 'Unknown error') + '</strong></div>', true);
+                }
+            } catch (error) {
+                displayStatus('<div class="status-line error"><strong>✗ Connection Error</strong></div>', true);
+            } finally {
+                showLoading(false);
+                loadConfig();
+            }
+        }
+        
         // Allow Enter key to submit
         document.getElementById('targetCapacity').addEventListener('keypress', function(e) {
             if (e.key === 'Enter') {
@@ -646,11 +1359,12 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
         // Automatically fetch status on page load
         document.addEventListener('DOMContentLoaded', function() {
             getStatus();
+            loadConfig();
         });
     </script>
 </body>
 </html>
-`, config.TargetResource, config.CooldownDuration)
+`, sessionBar, config.TargetResource, csrfToken)
 	if err != nil {
 		logger.Warn().Err(err).Msg("Failed to write HTML response")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -662,21 +1376,93 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
  * Autoscaler controller main function
  *
  * The controller reads configuration from environment variables:
+ * - AUTOSCALER_CONFIG_FILE: Optional YAML or JSON configuration file, overridden by the variables
  * - AUTOSCALER_COOLDOWN: Cooldown period in seconds (default: 300)
  * - AUTOSCALER_TARGET_RESOURCE: Resource alias to scale
+ * - AUTOSCALER_METRICS / AUTOSCALER_POLICY_*: Optional metric sources and scaling policy
+ *
+ * The configuration is reloaded on SIGHUP and whenever the configuration file
+ * changes. Scaling operations in progress keep the settings they started with.
+ * Settings changed through PATCH /config override both the variables and the
+ * file; they are persisted, audited, and reverted when their TTL passes.
+ *
+ * When AUTOSCALER_AUTH_* settings are given, every request except GET /health
+ * must carry an API key, an HMAC-signed token or a JWT, and is rejected with
+ * 401 otherwise. The role of the caller decides what it may do: viewers see
+ * the status and the dashboard, operators scale within the capacity bounds,
+ * and admins change the configuration, override the cooldown period and
+ * reset the circuit breaker.
+ *
+ * When AUTOSCALER_AUTH_USERS_FILE or AUTOSCALER_AUTH_OIDC_* settings are given,
+ * users sign in to the dashboard at /login with a password or through an
+ * OpenID Connect provider. They stay signed in with a secure session cookie,
+ * and requests of the dashboard that change something carry a CSRF token.
+ *
+ * When AUTOSCALER_TLS_CERT_FILE and AUTOSCALER_TLS_KEY_FILE are given, the
+ * endpoints are served over TLS, and the certificate is reloaded when its files
+ * change. With AUTOSCALER_TLS_CLIENT_CA_FILE, clients may authenticate with a
+ * certificate signed by that CA; its common name names the caller, and its
+ * organizations name the caller's role.
+ *
+ * The validate-config subcommand checks the configuration without starting,
+ * the issue-token subcommand signs a token for the API, and the hash-password
+ * subcommand hashes a password for the users file.
  *
  * It exposes HTTP endpoints:
  * - POST /scale: Scale to target capacity
+ * - POST /wake: Scale up from zero, bypassing the cooldown period
  * - GET /status: Get current capacity and status
+ * - GET /transitions: Get recent instance status changes
+ * - POST /recovery/reset: Reset the circuit breaker after the instance failed
+ * - GET/PATCH /config: Get or change the configuration in effect
+ * - DELETE /config/overrides/{id}: Revert a runtime configuration change
+ * - GET /audit: Get recent changes made by operators
  * - GET /health: Health check
+ * - GET/POST /login, POST /logout: Sign in to and out of the dashboard
  *
  * The autoscaler will:
  * 1. Get current capacity using omnistrate_api
  * 2. Wait for instance to be ACTIVE if not already
  * 3. Respect cooldown period between scaling operations
  * 4. Add or remove capacity to match target
+ *
+ * When a scaling policy is configured, it is evaluated periodically against the
+ * configured metric sources and drives the target capacity automatically.
+ *
+ * When AUTOSCALER_PROXY_TARGET is set, a reverse proxy in front of the scaled
+ * service counts requests for concurrency-based scaling and holds requests
+ * while the service is woken from zero.
  */
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "backtest" {
+		if err := runBacktest(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "backtest:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "validate-config" {
+		if err := runValidateConfig(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "validate-config:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "issue-token" {
+		if err := runIssueToken(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "issue-token:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "hash-password" {
+		if err := runHashPassword(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "hash-password:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Create shutdown context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -689,11 +1475,92 @@ func main() {
 	}
 	logger.Info().Msg("Autoscaler initialized successfully")
 
+	// Authenticate API requests, sign users in to the dashboard and accept
+	// verified TLS client certificates
+	apiAuthenticator, err := auth.New(autoScaler.GetConfig().Auth)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to initialize API authentication")
+	}
+	dashboard, err = auth.NewDashboard(autoScaler.GetConfig().Auth)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to initialize dashboard login")
+	}
+	var clientCerts auth.Authenticator
+	if autoScaler.GetConfig().TLS.ClientCAFile != "" {
+		clientCerts = auth.ClientCerts{}
+	}
+	authenticator := auth.Combine(dashboard.Authenticator(), apiAuthenticator, clientCerts)
+	if authenticator == nil {
+		logger.Warn().Msg("API authentication is disabled, anyone who can reach the controller can scale")
+	}
+
+	// Start the request-counting reverse proxy in front of the scaled service
+	var proxyServer *http.Server
+	if proxyConfig := autoScaler.GetConfig().Proxy; proxyConfig.Enabled() {
+		requestProxy, err := proxy.New(proxyConfig, autoScaler)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to initialize reverse proxy")
+		}
+		for _, source := range requestProxy.Sources() {
+			autoScaler.AddSource(source)
+		}
+
+		proxyServer = &http.Server{
+			Addr:              ":" + proxyConfig.Port,
+			Handler:           requestProxy,
+			ReadHeaderTimeout: 30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+		}
+		go func() {
+			logger.Info().Str("port", proxyConfig.Port).Str("target", proxyConfig.Target).Msg("Starting reverse proxy")
+			if err := proxyServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Fatal().Err(err).Msg("Reverse proxy failed to start")
+			}
+		}()
+	}
+
+	// Start the policy evaluation loop
+	runCtx, stopRun := context.WithCancel(context.Background())
+	defer stopRun()
+	go autoScaler.Run(runCtx)
+	go autoScaler.RunOverrideExpiry(runCtx)
+
+	// Reload the configuration on SIGHUP and when the configuration file changes
+	chReload := make(chan os.Signal, 1)
+	signal.Notify(chReload, syscall.SIGHUP)
+	go func() {
+		for range chReload {
+			autoScaler.ReloadConfig("SIGHUP")
+		}
+	}()
+	if cfg := autoScaler.GetConfig(); cfg.ConfigFile != "" && cfg.ConfigWatchInterval > 0 {
+		go config.Watch(runCtx, cfg.ConfigFile, cfg.ConfigWatchInterval, func() {
+			autoScaler.ReloadConfig("file change")
+		})
+	}
+
 	// Setup HTTP routes
 	http.HandleFunc("/", homeHandler)
 	http.HandleFunc("/scale", scaleHandler)
+	http.HandleFunc("/wake", wakeHandler)
 	http.HandleFunc("/status", statusHandler)
+	http.HandleFunc("/decisions", decisionsHandler)
+	http.HandleFunc("/transitions", transitionsHandler)
+	http.HandleFunc("/recovery/reset", recoveryResetHandler)
+	http.HandleFunc("/recommendations", recommendationsHandler)
+	http.HandleFunc("/shadows", shadowsHandler)
+	http.HandleFunc("/config", configHandler)
+	http.HandleFunc("/config/overrides/", configOverrideHandler)
+	http.HandleFunc("/audit", auditHandler)
 	http.HandleFunc("/health", healthHandler)
+	exempt, loginPath := []string{"/health"}, ""
+	if dashboard != nil {
+		http.HandleFunc("/login", loginHandler)
+		http.HandleFunc("/login/oidc", oidcLoginHandler)
+		http.HandleFunc("/login/callback", oidcCallbackHandler)
+		http.HandleFunc("/logout", logoutHandler)
+		exempt, loginPath = append(exempt, "/login", "/login/oidc", "/login/callback"), "/login"
+	}
 
 	// Setup graceful shutdown
 	chExit := make(chan os.Signal, 1)
@@ -707,24 +1574,63 @@ func main() {
 
 	server := &http.Server{
 		Addr:         ":" + port,
+		Handler:      auth.Middleware(authenticator, exempt, loginPath, http.DefaultServeMux),
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Serve over TLS, reloading the certificate when it is renewed
+	if tlsConfig := autoScaler.GetConfig().TLS; tlsConfig.Enabled() {
+		serverTLS, certificate, err := tlsserver.NewConfig(tlsConfig)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to initialize TLS")
+		}
+		server.TLSConfig = serverTLS
+		if tlsConfig.ReloadInterval > 0 {
+			go certificate.Watch(runCtx, tlsConfig.ReloadInterval)
+		}
+	}
+
 	go func() {
-		logger.Info().Str("port", port).Msg("Starting autoscaler controller")
+		logger.Info().Str("port", port).Bool("tls", server.TLSConfig != nil).Msg("Starting autoscaler controller")
 		logger.Info().Msg("Environment variables required:")
 		logger.Info().Msg("  - AUTOSCALER_TARGET_RESOURCE: Resource alias to scale")
 		logger.Info().Msg("  - AUTOSCALER_COOLDOWN: Cooldown period in seconds (optional)")
 		logger.Info().Msg("  - AUTOSCALER_STEPS: Number of steps for scaling (optional)")
+		logger.Info().Msg("  - AUTOSCALER_METRICS: Metric sources to scrape (optional)")
+		logger.Info().Msg("  - AUTOSCALER_POLICY_TYPE: Scaling policy to evaluate (optional)")
+		logger.Info().Msg("  - AUTOSCALER_RECOMMEND_ONLY: Compute recommendations without scaling (optional)")
+		logger.Info().Msg("  - AUTOSCALER_SIDECAR_URL: Sidecar API address (optional)")
+		logger.Info().Msg("  - AUTOSCALER_AUTH_*: API keys, token secret or JWKS to authenticate requests, users or OIDC to sign in (optional)")
+		logger.Info().Msg("  - AUTOSCALER_TLS_*: Certificate to serve over TLS and CA to verify client certificates (optional)")
 		logger.Info().Msg("")
 		logger.Info().Msg("Available endpoints:")
 		logger.Info().Msg("  POST /scale - Scale to target capacity")
+		logger.Info().Msg("  POST /wake - Scale up from zero")
 		logger.Info().Msg("  GET /status - Get current status")
+		logger.Info().Msg("  GET /decisions - Get recent policy decisions")
+		logger.Info().Msg("  GET /transitions - Get recent instance status changes")
+		logger.Info().Msg("  POST /recovery/reset - Reset the circuit breaker")
+		logger.Info().Msg("  GET /recommendations - Get the latest policy recommendation")
+		logger.Info().Msg("  GET /shadows - Get shadow policy divergence statistics")
+		logger.Info().Msg("  GET /config - Get the configuration in effect")
+		logger.Info().Msg("  PATCH /config - Change settings at runtime")
+		logger.Info().Msg("  DELETE /config/overrides/{id} - Revert a runtime change")
+		logger.Info().Msg("  GET /audit - Get recent changes made by operators")
 		logger.Info().Msg("  GET /health - Health check")
+		if dashboard != nil {
+			logger.Info().Msg("  GET/POST /login - Sign in to the dashboard")
+			logger.Info().Msg("  POST /logout - Sign out of the dashboard")
+		}
 
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			logger.Fatal().Err(err).Msg("Server failed to start")
 		}
 	}()
@@ -732,12 +1638,22 @@ func main() {
 	// Wait for shutdown signal
 	<-chExit
 	logger.Info().Msg("Shutting down gracefully...")
+	stopRun()
 	cancel()
 
 	// Shutdown server
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Error().Err(err).Msg("Error during shutdown")
 	}
+	if proxyServer != nil {
+		// ctx is already cancelled, so give in-flight proxied requests their
+		// own time to finish
+		proxyCtx, proxyCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer proxyCancel()
+		if err := proxyServer.Shutdown(proxyCtx); err != nil {
+			logger.Error().Err(err).Msg("Error during reverse proxy shutdown")
+		}
+	}
 
 	logger.Info().Msg("Autoscaler controller stopped")
 }
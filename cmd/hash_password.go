@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/auth"
+)
+
+// runHashPassword implements the hash-password subcommand, reading a password
+// from standard input and printing a line for the users file
+func runHashPassword(args []string) error {
+	flags := flag.NewFlagSet("hash-password", flag.ContinueOnError)
+	name := flags.String("user", "", "name of the user, to print a complete line of the users file")
+	roleName := flags.String("role", string(auth.RoleViewer), "role of the user: viewer, operator or admin")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	role, err := auth.ParseRole(*roleName)
+	if err != nil {
+		return err
+	}
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return errors.New("expected the password on standard input")
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		return errors.New("the password must not be empty")
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if *name == "" {
+		fmt.Println(hash)
+		return nil
+	}
+	fmt.Println(*name, hash, role)
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/auth"
+)
+
+// runIssueToken implements the issue-token subcommand, printing a token for
+// the API signed with the configured HMAC secret
+func runIssueToken(args []string) error {
+	flags := flag.NewFlagSet("issue-token", flag.ContinueOnError)
+	secretFile := flags.String("secret-file", os.Getenv("AUTOSCALER_AUTH_HMAC_SECRET_FILE"), "path to the HMAC secret tokens are signed with")
+	subject := flags.String("subject", "", "name of the caller the token is issued to")
+	roleName := flags.String("role", string(auth.RoleViewer), "role of the caller: viewer, operator or admin")
+	ttl := flags.Duration("ttl", 24*time.Hour, "how long the token is valid")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *secretFile == "" {
+		return errors.New("-secret-file or AUTOSCALER_AUTH_HMAC_SECRET_FILE is required")
+	}
+	if *subject == "" {
+		return errors.New("-subject is required")
+	}
+	if *ttl <= 0 {
+		return errors.New("-ttl must be positive")
+	}
+	role, err := auth.ParseRole(*roleName)
+	if err != nil {
+		return err
+	}
+	secret, err := auth.LoadHMACSecret(*secretFile)
+	if err != nil {
+		return err
+	}
+	token, err := auth.IssueToken(secret, *subject, role, *ttl, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Println(token)
+	return nil
+}
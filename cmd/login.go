@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/auth"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+)
+
+// dashboard signs users in to the dashboard, nil when they do not sign in
+var dashboard *auth.Dashboard
+
+// loginErrors are the messages shown on the login page for the error query
+// parameter; only known codes are shown so the page cannot be made to say
+// anything else
+var loginErrors = map[string]string{
+	"password": "Wrong user name or password.",
+	"oidc":     "Signing in with the identity provider failed.",
+	"session":  "Could not start a session, try again.",
+	"form":     "The login form has expired, try again.",
+	"locked":   "Too many failed logins, try again later.",
+}
+
+// loginHandler shows the login page and signs users in with a password. The
+// form carries a CSRF token of its own, and clients and user names with
+// repeated failed logins are locked out for a while.
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		csrfToken := ""
+		if dashboard.Users != nil {
+			var err error
+			if csrfToken, err = auth.StartLoginCSRF(w); err != nil {
+				logger.Warn().Err(err).Msg("Failed to start dashboard login")
+				http.Error(w, "Failed to start login", http.StatusInternalServerError)
+				return
+			}
+		}
+		writeLoginPage(w, loginErrors[r.URL.Query().Get("error")], csrfToken)
+	case http.MethodPost:
+		if dashboard.Users == nil {
+			http.Error(w, "Password login is not configured", http.StatusNotFound)
+			return
+		}
+		if !auth.CheckLoginCSRF(r) {
+			logger.Info().Str("remoteAddr", r.RemoteAddr).Str("origin", r.Header.Get("Origin")).Msg("Rejected dashboard login without CSRF token")
+			http.Redirect(w, r, "/login?error=form", http.StatusSeeOther)
+			return
+		}
+		name := r.PostFormValue("username")
+		keys := loginKeys(r, name)
+		if wait := dashboard.Logins.Wait(keys...); wait > 0 {
+			logger.Info().Str("user", name).Str("remoteAddr", r.RemoteAddr).Dur("wait", wait).Msg("Rejected throttled dashboard login")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Redirect(w, r, "/login?error=locked", http.StatusSeeOther)
+			return
+		}
+		principal, err := dashboard.Users.Verify(name, r.PostFormValue("password"))
+		if err != nil {
+			dashboard.Logins.Failed(keys...)
+			logger.Info().Str("user", name).Str("remoteAddr", r.RemoteAddr).Msg("Rejected dashboard login")
+			http.Redirect(w, r, "/login?error=password", http.StatusSeeOther)
+			return
+		}
+		// Only the user name is forgiven, so signing in to one account does
+		// not let a client go on guessing the passwords of others
+		dashboard.Logins.Succeeded(keys[1])
+		startSession(w, r, principal)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// loginKeys are the keys failed logins are throttled by: the client address
+// and the user name
+func loginKeys(r *http.Request, name string) []string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return []string{"client:" + host, "user:" + name}
+}
+
+// oidcLoginHandler sends users to the OpenID Connect provider to sign in
+func oidcLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if dashboard.OIDC == nil {
+		http.Error(w, "OIDC login is not configured", http.StatusNotFound)
+		return
+	}
+	if err := dashboard.OIDC.Start(w, r); err != nil {
+		logger.Warn().Err(err).Msg("Failed to start OIDC login")
+		http.Redirect(w, r, "/login?error=oidc", http.StatusSeeOther)
+	}
+}
+
+// oidcCallbackHandler signs users in when the OpenID Connect provider sends
+// them back
+func oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if dashboard.OIDC == nil {
+		http.Error(w, "OIDC login is not configured", http.StatusNotFound)
+		return
+	}
+	principal, err := dashboard.OIDC.Finish(w, r)
+	if err != nil {
+		logger.Info().Err(err).Str("remoteAddr", r.RemoteAddr).Msg("Rejected OIDC login")
+		http.Redirect(w, r, "/login?error=oidc", http.StatusSeeOther)
+		return
+	}
+	startSession(w, r, principal)
+}
+
+// logoutHandler ends the session of a user. The session authenticator has
+// already checked the CSRF token of the form.
+func logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	dashboard.Sessions.End(w, r)
+	logger.Info().Str("principal", requestActor(r)).Msg("Signed out of the dashboard")
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// startSession signs a user in and sends them to the dashboard
+func startSession(w http.ResponseWriter, r *http.Request, principal *auth.Principal) {
+	if _, err := dashboard.Sessions.Start(w, principal); err != nil {
+		logger.Warn().Err(err).Msg("Failed to start dashboard session")
+		http.Redirect(w, r, "/login?error=session", http.StatusSeeOther)
+		return
+	}
+	logger.Info().
+		Str("principal", principal.Name).
+		Str("method", principal.Method).
+		Str("role", string(principal.Role)).
+		Msg("Signed in to the dashboard")
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// sessionBar returns the HTML showing who is signed in to the dashboard with
+// a logout button, and the CSRF token scripts send with their requests. Both
+// are empty without a session.
+func sessionBar(r *http.Request) (string, string) {
+	if dashboard == nil {
+		return "", ""
+	}
+	session := dashboard.Sessions.Get(r)
+	if session == nil {
+		return "", ""
+	}
+	csrfToken := html.EscapeString(session.CSRFToken)
+	bar := fmt.Sprintf(`<form class="session-bar" method="POST" action="/logout">
+                <span>Signed in as <strong>%s</strong> (%s)</span>
+                <input type="hidden" name="%s" value="%s">
+                <button type="submit">Log Out</button>
+            </form>`,
+		html.EscapeString(session.Principal.Name), html.EscapeString(string(session.Principal.Role)),
+		auth.CSRFField, csrfToken)
+	return bar, csrfToken
+}
+
+// writeLoginPage shows the login options that are configured, the password
+// form with the given CSRF token
+func writeLoginPage(w http.ResponseWriter, message, csrfToken string) {
+	errorLine := ""
+	if message != "" {
+		errorLine = `<div class="error">` + html.EscapeString(message) + `</div>`
+	}
+	passwordForm := ""
+	if dashboard.Users != nil {
+		passwordForm = `<form method="POST" action="/login">
+                <input type="hidden" name="` + auth.CSRFField + `" value="` + html.EscapeString(csrfToken) + `">
+                <input type="text" name="username" placeholder="User name" autocomplete="username" required autofocus>
+                <input type="password" name="password" placeholder="Password" autocomplete="current-password" required>
+                <button type="submit">Sign In</button>
+            </form>`
+	}
+	oidcLink := ""
+	if dashboard.OIDC != nil {
+		oidcLink = `<a class="sso" href="/login/oidc">Sign in with SSO</a>`
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	_, err := fmt.Fprintf(w, `
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Omnistrate Autoscaler Login</title>
+    <style>
+        body {
+            font-family: 'Inter', -apple-system, BlinkMacSystemFont, 'Segoe UI', sans-serif;
+            background: linear-gradient(135deg, #667eea 0%%, #764ba2 100%%);
+            min-height: 100vh;
+            display: flex;
+            justify-content: center;
+            align-items: center;
+            margin: 0;
+        }
+
+        .screen {
+            background: #ffffff;
+            border-radius: 16px;
+            padding: 40px;
+            width: 320px;
+            box-shadow: 0 20px 60px rgba(0, 0, 0, 0.3);
+        }
+
+        h1 {
+            font-size: 24px;
+            margin: 0 0 24px;
+            color: #1a202c;
+            text-align: center;
+        }
+
+        input, button, .sso {
+            display: block;
+            box-sizing: border-box;
+            width: 100%%;
+            padding: 12px;
+            margin: 8px 0;
+            border-radius: 8px;
+            font-size: 14px;
+            text-align: center;
+        }
+
+        input {
+            border: 1px solid #e2e8f0;
+            text-align: left;
+        }
+
+        button, .sso {
+            border: none;
+            background: #667eea;
+            color: #ffffff;
+            font-weight: 600;
+            cursor: pointer;
+            text-decoration: none;
+        }
+
+        .error {
+            color: #e53e3e;
+            font-size: 14px;
+            margin-bottom: 8px;
+        }
+    </style>
+</head>
+<body>
+    <div class="screen">
+        <h1>Custom Autoscaler</h1>
+        %s
+        %s
+        %s
+    </div>
+</body>
+</html>
+`, errorLine, passwordForm, oidcLink)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to write HTML response")
+	}
+}
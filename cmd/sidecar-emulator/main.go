@@ -0,0 +1,72 @@
+// Command sidecar-emulator serves the Omnistrate sidecar API from simulated
+// resources, for running the controller end-to-end without an Omnistrate
+// account. See the "Local Sidecar Emulator" section of the README.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/emulator"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+)
+
+func main() {
+	logger.InitLogger()
+
+	addr := flag.String("addr", "127.0.0.1:49750", "address to serve the sidecar API on")
+	initialCapacity := flag.Int("initial-capacity", 1, "capacity of each resource when it is first used")
+	scaleUpDelay := flag.Duration("scale-up-delay", 30*time.Second, "time added capacity stays STARTING")
+	scaleDownDelay := flag.Duration("scale-down-delay", 10*time.Second, "time removed capacity stays STARTING")
+	maxReplicas := flag.Int("max-replicas", 0, "largest capacity a resource accepts (0 = unbounded)")
+	latency := flag.Duration("latency", 0, "latency added to every API call")
+	failureRate := flag.Float64("failure-rate", 0, "fraction of capacity changes that end in FAILED")
+	failureDuration := flag.Duration("failure-duration", 0, "time before a failed resource returns to ACTIVE (0 = never)")
+	flag.Parse()
+
+	if *initialCapacity < 0 || *maxReplicas < 0 || *failureRate < 0 || *failureRate > 1 {
+		logger.Fatal().Msg("initial-capacity and max-replicas must not be negative, failure-rate must be between 0 and 1")
+	}
+
+	server := &http.Server{
+		Addr: *addr,
+		Handler: emulator.New(clock.Real{}, config.SimulationConfig{
+			InitialCapacity: *initialCapacity,
+			ScaleUpDelay:    *scaleUpDelay,
+			ScaleDownDelay:  *scaleDownDelay,
+			MaxReplicas:     *maxReplicas,
+			Latency:         *latency,
+			FailureRate:     *failureRate,
+			FailureDuration: *failureDuration,
+		}),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+
+	go func() {
+		logger.Info().Str("addr", *addr).Msg("Starting sidecar emulator")
+		logger.Info().Msg("Sidecar API: GET /resource/{alias}/capacity, POST /resource/{alias}/capacity/add, POST /resource/{alias}/capacity/remove")
+		logger.Info().Msg("Control API: GET /control/resources[/{alias}], PUT|DELETE /control/resources/{alias}/faults, POST /control/resources/{alias}/capacity, POST /control/resources/{alias}/fail, POST /control/reset")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal().Err(err).Msg("Sidecar emulator failed to start")
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	logger.Info().Msg("Shutting down sidecar emulator")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Warn().Err(err).Msg("Sidecar emulator shutdown failed")
+	}
+}
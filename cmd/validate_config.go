@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+)
+
+// errInvalidConfig is returned by the validate-config subcommand after the
+// problems of the configuration were printed
+var errInvalidConfig = errors.New("configuration is invalid")
+
+// runValidateConfig implements the validate-config subcommand, loading the
+// configuration the controller would start with and printing every problem
+func runValidateConfig(args []string) error {
+	flags := flag.NewFlagSet("validate-config", flag.ContinueOnError)
+	path := flags.String("file", os.Getenv("AUTOSCALER_CONFIG_FILE"), "path to the YAML or JSON configuration file")
+	noEnv := flags.Bool("no-env", false, "validate the file alone, ignoring environment variable overrides")
+	schema := flags.Bool("schema", false, "print the JSON Schema of the configuration file and exit")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *schema {
+		_, err := os.Stdout.Write(config.Schema)
+		return err
+	}
+
+	lookupEnv := os.LookupEnv
+	if *noEnv {
+		lookupEnv = func(string) (string, bool) { return "", false }
+	}
+	cfg, err := config.Load(*path, lookupEnv)
+	var validationErr *config.ValidationError
+	if errors.As(err, &validationErr) {
+		for _, problem := range validationErr.Problems {
+			fmt.Fprintln(os.Stderr, problem)
+		}
+		return errInvalidConfig
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Configuration is valid: scaling %s\n", cfg.TargetResource)
+	return nil
+}
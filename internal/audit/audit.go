@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+)
+
+// Actions recorded in the audit trail
+const (
+	ActionConfigUpdate = "config_update"
+	ActionConfigRevert = "config_revert"
+	ActionConfigExpire = "config_expire"
+	ActionCircuitReset = "circuit_reset"
+)
+
+// historySize bounds how many entries are kept in memory for inspection
+const historySize = 100
+
+// Entry records a change an operator made to the running controller
+type Entry struct {
+	Time     time.Time         `json:"time"`
+	Actor    string            `json:"actor,omitempty"`
+	Action   string            `json:"action"`
+	Override string            `json:"override,omitempty"`
+	Reason   string            `json:"reason,omitempty"`
+	Settings map[string]string `json:"settings,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// Log is the audit trail. Entries are appended as JSON lines to a file, when
+// one is configured, and the most recent ones are kept in memory.
+type Log struct {
+	path    string
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// Open opens the audit trail at path, reading back its most recent entries.
+// With an empty path the audit trail is only kept in memory.
+func Open(path string) (*Log, error) {
+	l := &Log{path: path}
+	if path == "" {
+		return l, nil
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit trail: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			logger.Warn().Err(err).Str("path", path).Msg("Skipping unreadable audit trail entry")
+			continue
+		}
+		l.remember(entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit trail: %w", err)
+	}
+	return l, nil
+}
+
+// Record adds an entry to the audit trail. The entry is logged even when it
+// cannot be written to the file.
+func (l *Log) Record(entry Entry) error {
+	logger.Info().
+		Str("actor", entry.Actor).
+		Str("action", entry.Action).
+		Str("override", entry.Override).
+		Str("reason", entry.Reason).
+		Interface("settings", entry.Settings).
+		Msg("Audit")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.remember(entry)
+	if l.path == "" {
+		return nil
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit trail: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit trail: %w", err)
+	}
+	return nil
+}
+
+// Entries returns the most recent entries, oldest first
+func (l *Log) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Entry(nil), l.entries...)
+}
+
+// remember keeps an entry in memory. The caller must hold the lock, if needed.
+func (l *Log) remember(entry Entry) {
+	l.entries = append(l.entries, entry)
+	if len(l.entries) > historySize {
+		l.entries = l.entries[len(l.entries)-historySize:]
+	}
+}
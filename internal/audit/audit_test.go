@@ -0,0 +1,38 @@
+package audit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog_RecordsAndReadsBack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	log, err := Open(path)
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0).UTC()
+	require.NoError(t, log.Record(Entry{Time: now, Actor: "alice", Action: ActionConfigUpdate, Settings: map[string]string{"AUTOSCALER_STEPS": "2"}}))
+	require.NoError(t, log.Record(Entry{Time: now.Add(time.Hour), Action: ActionConfigExpire}))
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	entries := reopened.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "alice", entries[0].Actor)
+	assert.Equal(t, "2", entries[0].Settings["AUTOSCALER_STEPS"])
+	assert.Equal(t, ActionConfigExpire, entries[1].Action)
+}
+
+func TestLog_KeepsRecentEntries(t *testing.T) {
+	log, err := Open("")
+	require.NoError(t, err)
+
+	for i := 0; i < historySize+10; i++ {
+		require.NoError(t, log.Record(Entry{Action: ActionConfigUpdate, Reason: string(rune('a' + i%26))}))
+	}
+	assert.Len(t, log.Entries(), historySize)
+}
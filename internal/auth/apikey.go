@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// hashPrefix marks the algorithm of the hashes in the API keys file
+const hashPrefix = "sha256:"
+
+// APIKeys authenticates requests carrying a static API key, either in an
+// X-API-Key header or as a bearer token. Only hashes of the keys are kept.
+type APIKeys struct {
+	keys []apiKey
+}
+
+type apiKey struct {
+	name string
+	hash []byte
+	role Role
+}
+
+// LoadAPIKeys reads an API keys file. Every line names a key, gives the
+// SHA-256 hash of it and optionally the role of its holder, e.g.
+// "ci-pipeline sha256:<hex> operator"; keys without a role are viewers. Empty
+// lines and lines starting with # are ignored.
+func LoadAPIKeys(path string) (*APIKeys, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open API keys file: %w", err)
+	}
+	defer file.Close()
+
+	keys := &APIKeys{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields) > 3 || !strings.HasPrefix(fields[1], hashPrefix) {
+			return nil, fmt.Errorf("%s:%d: expected a name, a %s hash and an optional role", path, lineNumber, hashPrefix)
+		}
+		hash, err := hex.DecodeString(strings.TrimPrefix(fields[1], hashPrefix))
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("%s:%d: invalid SHA-256 hash of key %s", path, lineNumber, fields[0])
+		}
+		role := RoleViewer
+		if len(fields) == 3 {
+			if role, err = ParseRole(fields[2]); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+			}
+		}
+		keys.keys = append(keys.keys, apiKey{name: fields[0], hash: hash, role: role})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read API keys file: %w", err)
+	}
+	if len(keys.keys) == 0 {
+		return nil, fmt.Errorf("API keys file %s has no keys", path)
+	}
+	return keys, nil
+}
+
+// HashAPIKey returns the hash of an API key as written in the API keys file
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hashPrefix + hex.EncodeToString(hash[:])
+}
+
+func (k *APIKeys) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		token, ok := bearerToken(r)
+		// Signed tokens have dots, API keys do not
+		if !ok || strings.Contains(token, ".") {
+			return nil, ErrNoCredentials
+		}
+		key = token
+	}
+
+	hash := sha256.Sum256([]byte(key))
+	for _, candidate := range k.keys {
+		if subtle.ConstantTimeCompare(hash[:], candidate.hash) == 1 {
+			return &Principal{Name: candidate.name, Method: MethodAPIKey, Role: candidate.role}, nil
+		}
+	}
+	return nil, invalid("unknown API key")
+}
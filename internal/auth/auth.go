@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+)
+
+// Authentication methods
+const (
+	MethodAPIKey     = "api-key"
+	MethodHMAC       = "hmac"
+	MethodJWT        = "jwt"
+	MethodPassword   = "password"
+	MethodOIDC       = "oidc"
+	MethodClientCert = "client-cert"
+)
+
+// ErrNoCredentials is returned by an authenticator when a request carries no
+// credentials of the kind it checks
+var ErrNoCredentials = errors.New("no credentials")
+
+// ErrCSRF is returned for a request that changes something with a session
+// cookie but without the CSRF token of the session
+var ErrCSRF = errors.New("missing or invalid CSRF token")
+
+// Principal is the authenticated caller of a request and the role it has
+type Principal struct {
+	Name   string `json:"name"`
+	Method string `json:"method"`
+	Role   Role   `json:"role"`
+}
+
+// Authenticator establishes who made a request from its credentials. It
+// returns ErrNoCredentials when the request carries none it understands, and
+// another error when they are not valid.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// Chain accepts a request when any of its authenticators does
+type Chain []Authenticator
+
+func (c Chain) Authenticate(r *http.Request) (*Principal, error) {
+	err := ErrNoCredentials
+	for _, authenticator := range c {
+		principal, authErr := authenticator.Authenticate(r)
+		if authErr == nil {
+			return principal, nil
+		}
+		if !errors.Is(authErr, ErrNoCredentials) {
+			err = authErr
+		}
+	}
+	return nil, err
+}
+
+// Combine chains the authenticators that are not nil. It returns nil when all
+// of them are.
+func Combine(authenticators ...Authenticator) Authenticator {
+	var chain Chain
+	for _, authenticator := range authenticators {
+		if authenticator != nil {
+			chain = append(chain, authenticator)
+		}
+	}
+	if len(chain) == 0 {
+		return nil
+	}
+	return chain
+}
+
+// New creates the authenticators of API credentials described by the
+// configuration. It returns nil when no API credentials are configured.
+func New(cfg config.AuthConfig) (Authenticator, error) {
+	if !cfg.APIEnabled() {
+		return nil, nil
+	}
+
+	var chain Chain
+	if cfg.APIKeysFile != "" {
+		keys, err := LoadAPIKeys(cfg.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, keys)
+	}
+	if cfg.HMACSecretFile != "" {
+		tokens, err := LoadHMACTokens(cfg.HMACSecretFile)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, tokens)
+	}
+	if cfg.JWKS != "" {
+		jwts, err := NewJWT(cfg)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, jwts)
+	}
+	return chain, nil
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the authenticated principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns the authenticated principal of a request, or nil when
+// authentication is disabled
+func FromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// Middleware requires every request, except those to the exempt paths, to
+// authenticate and passes the principal on in the request context. Browsers
+// asking for a page without credentials are redirected to the login path, when
+// there is one. With a nil authenticator every request is let through.
+func Middleware(authenticator Authenticator, exempt []string, loginPath string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authenticator == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		for _, path := range exempt {
+			if r.URL.Path == path {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		principal, err := authenticator.Authenticate(r)
+		if err != nil {
+			if errors.Is(err, ErrNoCredentials) && loginPath != "" && wantsPage(r) {
+				http.Redirect(w, r, loginPath, http.StatusSeeOther)
+				return
+			}
+			if errors.Is(err, ErrCSRF) {
+				logger.Info().Str("path", r.URL.Path).Str("remoteAddr", r.RemoteAddr).Msg("Rejected request without CSRF token")
+				writeError(w, http.StatusForbidden, err.Error())
+				return
+			}
+			message := "invalid credentials"
+			if errors.Is(err, ErrNoCredentials) {
+				message = "authentication required"
+			} else {
+				logger.Info().Err(err).Str("path", r.URL.Path).Str("remoteAddr", r.RemoteAddr).Msg("Rejected request with invalid credentials")
+			}
+			unauthorized(w, message)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// unauthorized answers a request that failed to authenticate
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="autoscaler"`)
+	writeError(w, http.StatusUnauthorized, message)
+}
+
+// writeError answers a request with a JSON error
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	err := json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": message})
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to encode JSON response")
+	}
+}
+
+// wantsPage reports whether a request was made by a browser navigating to a
+// page rather than by a script or API client
+func wantsPage(r *http.Request) bool {
+	return r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// bearerToken returns the token of an Authorization: Bearer header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// invalid wraps the reason credentials were rejected
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid credentials: "+format, args...)
+}
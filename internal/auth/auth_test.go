@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+	return path
+}
+
+// serve sends a request with the given headers through the middleware and
+// returns the response and the principal the handler saw
+func serve(authenticator Authenticator, path string, headers map[string]string) (*httptest.ResponseRecorder, *Principal) {
+	var principal *Principal
+	handler := Middleware(authenticator, []string{"/health"}, "", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal = FromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder, principal
+}
+
+func TestMiddleware(t *testing.T) {
+	keys, err := LoadAPIKeys(writeFile(t, "keys", "ci "+HashAPIKey("ci-key")+" viewer\n"))
+	require.NoError(t, err)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	now := time.Unix(1700000000, 0)
+	chain := Chain{keys, NewHMACTokens(secret, clock.NewSimulated(now))}
+	token, err := IssueToken(secret, "deployer", RoleOperator, time.Hour, now)
+	require.NoError(t, err)
+
+	// Missing and invalid credentials are rejected
+	recorder, _ := serve(chain, "/scale", nil)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "authentication required")
+	assert.Equal(t, `Bearer realm="autoscaler"`, recorder.Header().Get("WWW-Authenticate"))
+	recorder, _ = serve(chain, "/scale", map[string]string{"X-API-Key": "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "invalid credentials")
+
+	// Any authenticator of the chain can accept a request
+	recorder, principal := serve(chain, "/scale", map[string]string{"X-API-Key": "ci-key"})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, &Principal{Name: "ci", Method: MethodAPIKey, Role: RoleViewer}, principal)
+	recorder, principal = serve(chain, "/scale", map[string]string{"Authorization": "Bearer " + token})
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, &Principal{Name: "deployer", Method: MethodHMAC, Role: RoleOperator}, principal)
+
+	// Health checks need no credentials
+	recorder, principal = serve(chain, "/health", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Nil(t, principal)
+}
+
+func TestMiddleware_Disabled(t *testing.T) {
+	recorder, principal := serve(nil, "/scale", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Nil(t, principal)
+}
+
+func TestNew(t *testing.T) {
+	authenticator, err := New(config.AuthConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, authenticator)
+
+	_, err = New(config.AuthConfig{APIKeysFile: filepath.Join(t.TempDir(), "missing")})
+	assert.Error(t, err)
+
+	authenticator, err = New(config.AuthConfig{APIKeysFile: writeFile(t, "keys", "ci "+HashAPIKey("ci-key")+"\n")})
+	require.NoError(t, err)
+	assert.Len(t, authenticator, 1)
+}
@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"crypto/x509"
+	"net/http"
+)
+
+// ClientCerts authenticates requests made over TLS with a client certificate
+// the server verified. The holder is named by the common name of the
+// certificate, or else its first URI or DNS name, e.g. a SPIFFE ID. Like for
+// Kubernetes, the organizations of the subject are its groups, and the most
+// privileged of them that names a role is the role of the holder.
+type ClientCerts struct{}
+
+func (ClientCerts) Authenticate(r *http.Request) (*Principal, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, ErrNoCredentials
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	name := certificateName(cert)
+	if name == "" {
+		return nil, invalid("client certificate with serial number %s names no subject", cert.SerialNumber)
+	}
+	return &Principal{Name: name, Method: MethodClientCert, Role: highestRole(cert.Subject.Organization)}, nil
+}
+
+// certificateName returns the name of the holder of a certificate
+func certificateName(cert *x509.Certificate) string {
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+	return ""
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withClientCert returns a request as if made over TLS with the verified
+// client certificate
+func withClientCert(cert *x509.Certificate) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	return req
+}
+
+func TestClientCerts(t *testing.T) {
+	principal, err := ClientCerts{}.Authenticate(withClientCert(&x509.Certificate{
+		Subject: pkix.Name{CommonName: "deploy-bot", Organization: []string{"ci", "admin", "operator"}},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, &Principal{Name: "deploy-bot", Method: MethodClientCert, Role: RoleAdmin}, principal)
+
+	// Certificates without a common name are named by their SAN and without a
+	// role organization are viewers
+	spiffeID, _ := url.Parse("spiffe://example.org/ns/prod/sa/scheduler")
+	principal, err = ClientCerts{}.Authenticate(withClientCert(&x509.Certificate{URIs: []*url.URL{spiffeID}, DNSNames: []string{"scheduler"}}))
+	require.NoError(t, err)
+	assert.Equal(t, &Principal{Name: spiffeID.String(), Method: MethodClientCert, Role: RoleViewer}, principal)
+
+	principal, err = ClientCerts{}.Authenticate(withClientCert(&x509.Certificate{DNSNames: []string{"scheduler.internal"}}))
+	require.NoError(t, err)
+	assert.Equal(t, "scheduler.internal", principal.Name)
+
+	_, err = ClientCerts{}.Authenticate(withClientCert(&x509.Certificate{SerialNumber: big.NewInt(42)}))
+	assert.ErrorContains(t, err, "names no subject")
+
+	// Requests without a verified certificate are left to other authenticators
+	_, err = ClientCerts{}.Authenticate(httptest.NewRequest(http.MethodGet, "/status", nil))
+	assert.ErrorIs(t, err, ErrNoCredentials)
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+)
+
+// Dashboard signs users in to the dashboard with a password, through an
+// OpenID Connect provider or both, and keeps them signed in with sessions.
+// Users and OIDC are nil when the respective login is not configured; Logins
+// throttles failed password logins.
+type Dashboard struct {
+	Users    *Users
+	OIDC     *OIDC
+	Sessions *Sessions
+	Logins   *LoginThrottle
+}
+
+// NewDashboard creates the dashboard login described by the configuration. It
+// returns nil when users do not sign in.
+func NewDashboard(cfg config.AuthConfig) (*Dashboard, error) {
+	if !cfg.LoginEnabled() {
+		return nil, nil
+	}
+
+	dashboard := &Dashboard{
+		Sessions: NewSessions(cfg.SessionTTL, clock.Real{}),
+		Logins:   NewLoginThrottle(clock.Real{}),
+	}
+	if cfg.UsersFile != "" {
+		users, err := LoadUsers(cfg.UsersFile)
+		if err != nil {
+			return nil, err
+		}
+		dashboard.Users = users
+	}
+	if cfg.OIDCIssuer != "" {
+		oidc, err := NewOIDC(cfg)
+		if err != nil {
+			return nil, err
+		}
+		dashboard.OIDC = oidc
+	}
+	return dashboard, nil
+}
+
+// Authenticator returns the authenticator of the sessions, or nil without a
+// dashboard login
+func (d *Dashboard) Authenticator() Authenticator {
+	if d == nil {
+		return nil
+	}
+	return d.Sessions
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+)
+
+// minSecretLength is the shortest HMAC secret accepted, in bytes
+const minSecretLength = 32
+
+// tokenClaims is the payload of an HMAC-signed token
+type tokenClaims struct {
+	Subject   string `json:"sub"`
+	Role      Role   `json:"role,omitempty"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// HMACTokens authenticates bearer tokens signed with a shared secret. A token
+// is the base64url encoded JSON payload {"sub", "role", "exp"} and its
+// HMAC-SHA256 signature, separated by a dot. Tokens without a role are viewers.
+type HMACTokens struct {
+	secret []byte
+	clock  clock.Clock
+}
+
+// LoadHMACTokens reads the shared secret from a file. Surrounding whitespace
+// is ignored.
+func LoadHMACTokens(path string) (*HMACTokens, error) {
+	secret, err := LoadHMACSecret(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewHMACTokens(secret, clock.Real{}), nil
+}
+
+// LoadHMACSecret reads the secret tokens are signed with
+func LoadHMACSecret(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HMAC secret: %w", err)
+	}
+	secret := bytes.TrimSpace(data)
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("HMAC secret in %s must be at least %d bytes", path, minSecretLength)
+	}
+	return secret, nil
+}
+
+// NewHMACTokens creates an authenticator for tokens signed with the secret
+func NewHMACTokens(secret []byte, clk clock.Clock) *HMACTokens {
+	return &HMACTokens{secret: secret, clock: clk}
+}
+
+// IssueToken signs a token for the subject with the role that is valid for the TTL
+func IssueToken(secret []byte, subject string, role Role, ttl time.Duration, now time.Time) (string, error) {
+	payload, err := json.Marshal(tokenClaims{Subject: subject, Role: role, ExpiresAt: now.Add(ttl).Unix()})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token: %w", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(sign(secret, encoded)), nil
+}
+
+func (h *HMACTokens) Authenticate(r *http.Request) (*Principal, error) {
+	token, ok := bearerToken(r)
+	if !ok || strings.Count(token, ".") != 1 {
+		return nil, ErrNoCredentials
+	}
+
+	encoded, signature, _ := strings.Cut(token, ".")
+	decodedSignature, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(decodedSignature, sign(h.secret, encoded)) {
+		return nil, invalid("bad token signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, invalid("malformed token")
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, invalid("malformed token: %v", err)
+	}
+	if claims.Subject == "" {
+		return nil, invalid("token has no subject")
+	}
+	if !h.clock.Now().Before(time.Unix(claims.ExpiresAt, 0)) {
+		return nil, invalid("token of %s expired", claims.Subject)
+	}
+	role := RoleViewer
+	if claims.Role != "" {
+		if role, err = ParseRole(string(claims.Role)); err != nil {
+			return nil, invalid("token of %s: %v", claims.Subject, err)
+		}
+	}
+	return &Principal{Name: claims.Subject, Method: MethodHMAC, Role: role}, nil
+}
+
+// sign computes the signature of an encoded token payload
+func sign(secret []byte, encoded string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHMACTokens(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	start := time.Unix(1700000000, 0)
+	clk := clock.NewSimulated(start)
+	tokens := NewHMACTokens(secret, clk)
+	token, err := IssueToken(secret, "deployer", RoleOperator, time.Hour, start)
+	require.NoError(t, err)
+
+	authenticate := func(token string) (*Principal, error) {
+		req := httptest.NewRequest(http.MethodPost, "/scale", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return tokens.Authenticate(req)
+	}
+
+	principal, err := authenticate(token)
+	require.NoError(t, err)
+	assert.Equal(t, &Principal{Name: "deployer", Method: MethodHMAC, Role: RoleOperator}, principal)
+
+	// A token signed with another secret is rejected
+	forged, err := IssueToken([]byte("another secret of at least 32 bytes"), "deployer", RoleAdmin, time.Hour, start)
+	require.NoError(t, err)
+	_, err = authenticate(forged)
+	assert.ErrorContains(t, err, "bad token signature")
+
+	// Tokens expire
+	clk.Advance(time.Hour)
+	_, err = authenticate(token)
+	assert.ErrorContains(t, err, "expired")
+}
+
+func TestLoadHMACSecret_RejectsShortSecrets(t *testing.T) {
+	_, err := LoadHMACSecret(writeFile(t, "secret", "too short\n"))
+	assert.ErrorContains(t, err, "at least 32 bytes")
+
+	secret, err := LoadHMACSecret(writeFile(t, "secret", "0123456789abcdef0123456789abcdef\n"))
+	require.NoError(t, err)
+	assert.Len(t, secret, 32)
+}
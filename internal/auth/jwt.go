@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+)
+
+// jwtLeeway is the clock skew tolerated when checking the validity of a JWT
+const jwtLeeway = time.Minute
+
+// minKeyRefetch bounds how often a token signed with an unknown key makes the
+// key set be fetched again
+const minKeyRefetch = 30 * time.Second
+
+// maxFetchBackoff bounds the wait between attempts to fetch a key set that
+// keeps failing, which doubles from minKeyRefetch with every failure
+const maxFetchBackoff = 5 * time.Minute
+
+// jwtAlgorithms are the signature algorithms accepted for JWTs. Symmetric
+// algorithms are left out, since the keys come from a public key set.
+var jwtAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// JWT authenticates bearer JWTs signed with a key of a JSON Web Key Set. The
+// key set is read from a file or fetched from a URL, and read again every
+// refresh interval or when a token names a key it does not have. The role of
+// the subject is the most privileged one named by the roles claim, a string or
+// a list of strings; subjects without a role are viewers.
+type JWT struct {
+	location   string
+	refresh    time.Duration
+	issuer     string
+	audience   string
+	rolesClaim string
+	httpClient *http.Client
+	clock      clock.Clock
+
+	mu        sync.Mutex
+	keys      *jose.JSONWebKeySet
+	fetched   time.Time
+	attempted time.Time
+	failures  int
+	fetchErr  error
+}
+
+// NewJWT creates an authenticator for JWTs from the JWKS, issuer and audience
+// settings. A key set file must be readable; a key set URL that cannot be
+// reached yet is fetched again by a later request, once the backoff has passed.
+func NewJWT(cfg config.AuthConfig) (*JWT, error) {
+	return newJWT(cfg, clock.Real{})
+}
+
+func newJWT(cfg config.AuthConfig, clk clock.Clock) (*JWT, error) {
+	j := &JWT{
+		location:   cfg.JWKS,
+		refresh:    cfg.JWKSRefresh,
+		issuer:     cfg.JWTIssuer,
+		audience:   cfg.JWTAudience,
+		rolesClaim: cfg.JWTRolesClaim,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		clock:      clk,
+	}
+	if _, err := j.keySet(context.Background(), ""); err != nil {
+		if !j.remote() {
+			return nil, err
+		}
+		logger.Warn().Err(err).Str("jwks", j.location).Msg("Failed to fetch JWKS, retrying on a later request")
+	}
+	return j, nil
+}
+
+func (j *JWT) Authenticate(r *http.Request) (*Principal, error) {
+	raw, ok := bearerToken(r)
+	if !ok || strings.Count(raw, ".") != 2 {
+		return nil, ErrNoCredentials
+	}
+	principal, _, err := j.verify(r.Context(), raw)
+	return principal, err
+}
+
+// verify checks the signature and claims of a JWT and returns its subject
+// together with the claims that are not registered ones
+func (j *JWT) verify(ctx context.Context, raw string) (*Principal, map[string]interface{}, error) {
+	token, err := jwt.ParseSigned(raw, jwtAlgorithms)
+	if err != nil {
+		return nil, nil, invalid("malformed JWT: %v", err)
+	}
+	var kid string
+	if len(token.Headers) > 0 {
+		kid = token.Headers[0].KeyID
+	}
+	keys, err := j.keySet(ctx, kid)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get JWKS: %w", err)
+	}
+
+	var claims jwt.Claims
+	var custom map[string]interface{}
+	if err := token.Claims(keys, &claims, &custom); err != nil {
+		return nil, nil, invalid("JWT signature: %v", err)
+	}
+	if claims.Expiry == nil {
+		return nil, nil, invalid("JWT has no expiry")
+	}
+	expected := jwt.Expected{Issuer: j.issuer, Time: j.clock.Now()}
+	if j.audience != "" {
+		expected.AnyAudience = jwt.Audience{j.audience}
+	}
+	if err := claims.ValidateWithLeeway(expected, jwtLeeway); err != nil {
+		return nil, nil, invalid("JWT of %s: %v", claims.Subject, err)
+	}
+	if claims.Subject == "" {
+		return nil, nil, invalid("JWT has no subject")
+	}
+	role := highestRole(roleNames(custom[j.rolesClaim]))
+	return &Principal{Name: claims.Subject, Method: MethodJWT, Role: role}, custom, nil
+}
+
+// roleNames returns the names in the value of a roles claim
+func roleNames(value interface{}) []string {
+	switch value := value.(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		var names []string
+		for _, item := range value {
+			if name, ok := item.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// keySet returns the key set, reading it again when it is older than the
+// refresh interval or lacks the key with the given ID. After a failed attempt
+// it is not read again until the backoff has passed, so requests arriving
+// while the source is unavailable do not each wait for it.
+func (j *JWT) keySet(ctx context.Context, kid string) (*jose.JSONWebKeySet, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := j.clock.Now()
+	age := now.Sub(j.fetched)
+	stale := j.keys == nil || age >= j.refresh
+	missing := kid != "" && j.keys != nil && len(j.keys.Key(kid)) == 0 && age >= minKeyRefetch
+	if !stale && !missing {
+		return j.keys, nil
+	}
+	if j.failures > 0 && now.Sub(j.attempted) < fetchBackoff(j.failures) {
+		return j.lastKeys()
+	}
+
+	j.attempted = now
+	keys, err := j.fetch(ctx)
+	if err != nil {
+		j.failures++
+		j.fetchErr = err
+		if j.keys != nil {
+			logger.Warn().Err(err).Str("jwks", j.location).Int("failures", j.failures).Msg("Failed to refresh JWKS")
+		}
+		return j.lastKeys()
+	}
+	j.keys = keys
+	j.fetched = now
+	j.failures = 0
+	j.fetchErr = nil
+	return keys, nil
+}
+
+// lastKeys returns the keys we have, to keep verifying with them while the
+// source is unavailable, or the error of the last attempt when there are none
+func (j *JWT) lastKeys() (*jose.JSONWebKeySet, error) {
+	if j.keys == nil {
+		return nil, j.fetchErr
+	}
+	return j.keys, nil
+}
+
+// fetchBackoff is the wait before fetching the key set again after the given
+// number of consecutive failures
+func fetchBackoff(failures int) time.Duration {
+	backoff := minKeyRefetch
+	for i := 1; i < failures && backoff < maxFetchBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxFetchBackoff)
+}
+
+// fetch reads the key set from its file or URL
+func (j *JWT) fetch(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	var data []byte
+	if j.remote() {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.location, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := j.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch JWKS: %s", resp.Status)
+		}
+		data, err = io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+		}
+	} else {
+		var err error
+		data, err = os.ReadFile(j.location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWKS: %w", err)
+		}
+	}
+
+	var keys jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS %s: %w", j.location, err)
+	}
+	if len(keys.Keys) == 0 {
+		return nil, fmt.Errorf("JWKS %s has no keys", j.location)
+	}
+	return &keys, nil
+}
+
+// remote reports whether the key set is fetched from a URL
+func (j *JWT) remote() bool {
+	return strings.HasPrefix(j.location, "http://") || strings.HasPrefix(j.location, "https://")
+}
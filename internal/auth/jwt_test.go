@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testIssuer signs JWTs and publishes its public keys as a JWKS
+type testIssuer struct {
+	mu   sync.Mutex
+	keys map[string]*ecdsa.PrivateKey
+}
+
+func newTestIssuer(t *testing.T, kids ...string) *testIssuer {
+	issuer := &testIssuer{keys: map[string]*ecdsa.PrivateKey{}}
+	for _, kid := range kids {
+		issuer.addKey(t, kid)
+	}
+	return issuer
+}
+
+func (i *testIssuer) addKey(t *testing.T, kid string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.keys[kid] = key
+}
+
+func (i *testIssuer) jwks() []byte {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	var set jose.JSONWebKeySet
+	for kid, key := range i.keys {
+		set.Keys = append(set.Keys, jose.JSONWebKey{Key: key.Public(), KeyID: kid, Algorithm: string(jose.ES256), Use: "sig"})
+	}
+	data, _ := json.Marshal(set)
+	return data
+}
+
+func (i *testIssuer) sign(t *testing.T, kid string, claims jwt.Claims, custom ...interface{}) string {
+	i.mu.Lock()
+	key := i.keys[kid]
+	i.mu.Unlock()
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, (&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", kid))
+	require.NoError(t, err)
+	builder := jwt.Signed(signer).Claims(claims)
+	for _, extra := range custom {
+		builder = builder.Claims(extra)
+	}
+	token, err := builder.Serialize()
+	require.NoError(t, err)
+	return token
+}
+
+func authenticateJWT(authenticator *JWT, token string) (*Principal, error) {
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return authenticator.Authenticate(req)
+}
+
+func TestJWT(t *testing.T) {
+	issuer := newTestIssuer(t, "key-1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(issuer.jwks())
+	}))
+	defer server.Close()
+
+	now := time.Unix(1700000000, 0)
+	clk := clock.NewSimulated(now)
+	authenticator, err := newJWT(config.AuthConfig{
+		JWKS:          server.URL,
+		JWKSRefresh:   time.Hour,
+		JWTIssuer:     "https://idp.example.com",
+		JWTAudience:   "autoscaler",
+		JWTRolesClaim: "roles",
+	}, clk)
+	require.NoError(t, err)
+
+	valid := jwt.Claims{
+		Subject:  "alice",
+		Issuer:   "https://idp.example.com",
+		Audience: jwt.Audience{"autoscaler"},
+		Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+	principal, err := authenticateJWT(authenticator, issuer.sign(t, "key-1", valid))
+	require.NoError(t, err)
+	assert.Equal(t, &Principal{Name: "alice", Method: MethodJWT, Role: RoleViewer}, principal)
+
+	wrongAudience := valid
+	wrongAudience.Audience = jwt.Audience{"billing"}
+	_, err = authenticateJWT(authenticator, issuer.sign(t, "key-1", wrongAudience))
+	assert.ErrorContains(t, err, "audience")
+
+	wrongIssuer := valid
+	wrongIssuer.Issuer = "https://evil.example.com"
+	_, err = authenticateJWT(authenticator, issuer.sign(t, "key-1", wrongIssuer))
+	assert.ErrorContains(t, err, "issuer")
+
+	noExpiry := valid
+	noExpiry.Expiry = nil
+	_, err = authenticateJWT(authenticator, issuer.sign(t, "key-1", noExpiry))
+	assert.ErrorContains(t, err, "no expiry")
+
+	// A key rotated in at the issuer is fetched once the token names it
+	issuer.addKey(t, "key-2")
+	clk.Advance(minKeyRefetch)
+	principal, err = authenticateJWT(authenticator, issuer.sign(t, "key-2", valid))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", principal.Name)
+
+	clk.Advance(time.Hour + jwtLeeway)
+	_, err = authenticateJWT(authenticator, issuer.sign(t, "key-1", valid))
+	assert.ErrorContains(t, err, "expired")
+}
+
+func TestJWT_KeySetFile(t *testing.T) {
+	issuer := newTestIssuer(t, "key-1")
+	authenticator, err := NewJWT(config.AuthConfig{JWKS: writeFile(t, "jwks.json", string(issuer.jwks())), JWKSRefresh: time.Hour})
+	require.NoError(t, err)
+
+	principal, err := authenticateJWT(authenticator, issuer.sign(t, "key-1", jwt.Claims{
+		Subject: "bob",
+		Expiry:  jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "bob", principal.Name)
+
+	// A token signed by someone else is rejected
+	other := newTestIssuer(t, "key-1")
+	_, err = authenticateJWT(authenticator, other.sign(t, "key-1", jwt.Claims{
+		Subject: "bob",
+		Expiry:  jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}))
+	assert.ErrorContains(t, err, "JWT signature")
+
+	_, err = NewJWT(config.AuthConfig{JWKS: writeFile(t, "empty.json", `{"keys": []}`), JWKSRefresh: time.Hour})
+	assert.ErrorContains(t, err, "has no keys")
+}
+
+func TestJWT_Roles(t *testing.T) {
+	issuer := newTestIssuer(t, "key-1")
+	authenticator, err := NewJWT(config.AuthConfig{
+		JWKS:          writeFile(t, "jwks.json", string(issuer.jwks())),
+		JWKSRefresh:   time.Hour,
+		JWTRolesClaim: "groups",
+	})
+	require.NoError(t, err)
+	claims := jwt.Claims{Subject: "alice", Expiry: jwt.NewNumericDate(time.Now().Add(time.Hour))}
+
+	tests := []struct {
+		name   string
+		custom map[string]interface{}
+		role   Role
+	}{
+		{"no claim", nil, RoleViewer},
+		{"single role", map[string]interface{}{"groups": "operator"}, RoleOperator},
+		{"most privileged role wins", map[string]interface{}{"groups": []string{"sre", "admin", "viewer"}}, RoleAdmin},
+		{"unknown roles are ignored", map[string]interface{}{"groups": []string{"sre"}}, RoleViewer},
+		{"other claims are ignored", map[string]interface{}{"roles": "admin"}, RoleViewer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var custom []interface{}
+			if tt.custom != nil {
+				custom = append(custom, tt.custom)
+			}
+			principal, err := authenticateJWT(authenticator, issuer.sign(t, "key-1", claims, custom...))
+			require.NoError(t, err)
+			assert.Equal(t, tt.role, principal.Role)
+		})
+	}
+}
+
+func TestJWT_BacksOffWhileKeySetUnavailable(t *testing.T) {
+	issuer := newTestIssuer(t, "key-1")
+	var mu sync.Mutex
+	available := false
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches++
+		if !available {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(issuer.jwks())
+	}))
+	defer server.Close()
+
+	now := time.Unix(1700000000, 0)
+	clk := clock.NewSimulated(now)
+	authenticator, err := newJWT(config.AuthConfig{JWKS: server.URL, JWKSRefresh: time.Hour}, clk)
+	require.NoError(t, err)
+	token := issuer.sign(t, "key-1", jwt.Claims{Subject: "alice", Expiry: jwt.NewNumericDate(now.Add(time.Hour))})
+
+	// Requests within the backoff get the error of the last attempt without
+	// fetching the key set again
+	for i := 0; i < 5; i++ {
+		_, err = authenticateJWT(authenticator, token)
+		assert.ErrorContains(t, err, "503")
+	}
+	mu.Lock()
+	assert.Equal(t, 1, fetches)
+	available = true
+	mu.Unlock()
+
+	clk.Advance(minKeyRefetch)
+	principal, err := authenticateJWT(authenticator, token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", principal.Name)
+	mu.Lock()
+	assert.Equal(t, 2, fetches)
+	mu.Unlock()
+
+	assert.Equal(t, minKeyRefetch, fetchBackoff(1))
+	assert.Equal(t, 4*minKeyRefetch, fetchBackoff(3))
+	assert.Equal(t, maxFetchBackoff, fetchBackoff(100))
+}
@@ -0,0 +1,303 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+)
+
+// loginCookie binds a login started at the provider to the browser that
+// started it
+const loginCookie = "__Host-autoscaler-login"
+
+// loginTimeout bounds how long a user may take to sign in at the provider
+const loginTimeout = 10 * time.Minute
+
+// oidcScopes are requested from the provider; profile and email provide a
+// readable name for the audit trail
+const oidcScopes = "openid profile email"
+
+// OIDC signs dashboard users in through an OpenID Connect provider with the
+// authorization code flow and PKCE. The provider's endpoints are discovered
+// from the issuer, and ID tokens are verified against its key set. The role
+// of a user is read from the roles claim of the ID token like for JWTs.
+type OIDC struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	cfg          config.AuthConfig
+	httpClient   *http.Client
+	clock        clock.Clock
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	idTokens  *JWT
+	pending   map[string]pendingLogin
+}
+
+// oidcDiscovery is the part of the provider metadata the login needs
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// pendingLogin is a login waiting for the provider to redirect back
+type pendingLogin struct {
+	nonce     string
+	verifier  string
+	expiresAt time.Time
+}
+
+// NewOIDC creates the OpenID Connect login from the OIDC settings. A provider
+// that cannot be reached yet is discovered again on the first login.
+func NewOIDC(cfg config.AuthConfig) (*OIDC, error) {
+	return newOIDC(cfg, clock.Real{})
+}
+
+func newOIDC(cfg config.AuthConfig, clk clock.Clock) (*OIDC, error) {
+	o := &OIDC{
+		issuer:      strings.TrimSuffix(cfg.OIDCIssuer, "/"),
+		clientID:    cfg.OIDCClientID,
+		redirectURL: cfg.OIDCRedirectURL,
+		cfg:         cfg,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		clock:       clk,
+		pending:     map[string]pendingLogin{},
+	}
+	if cfg.OIDCClientSecretFile != "" {
+		secret, err := readSecret(cfg.OIDCClientSecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read OIDC client secret: %w", err)
+		}
+		o.clientSecret = secret
+	}
+	if _, _, err := o.provider(context.Background()); err != nil {
+		logger.Warn().Err(err).Str("issuer", o.issuer).Msg("Failed to discover OIDC provider, retrying on the first login")
+	}
+	return o, nil
+}
+
+// Start begins a login by redirecting the browser to the provider
+func (o *OIDC) Start(w http.ResponseWriter, r *http.Request) error {
+	discovery, _, err := o.provider(r.Context())
+	if err != nil {
+		return err
+	}
+	state, err := randomToken()
+	if err != nil {
+		return err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return err
+	}
+	verifier, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	now := o.clock.Now()
+	o.mu.Lock()
+	for otherState, other := range o.pending {
+		if !now.Before(other.expiresAt) {
+			delete(o.pending, otherState)
+		}
+	}
+	o.pending[state] = pendingLogin{nonce: nonce, verifier: verifier, expiresAt: now.Add(loginTimeout)}
+	o.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCookie,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   int(loginTimeout.Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {o.clientID},
+		"redirect_uri":          {o.redirectURL},
+		"scope":                 {oidcScopes},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	http.Redirect(w, r, discovery.AuthorizationEndpoint+"?"+query.Encode(), http.StatusFound)
+	return nil
+}
+
+// Finish completes a login when the provider redirects back, returning the
+// user who signed in
+func (o *OIDC) Finish(w http.ResponseWriter, r *http.Request) (*Principal, error) {
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		return nil, invalid("provider refused the login: %s %s", providerErr, query.Get("error_description"))
+	}
+	state := query.Get("state")
+	cookie, err := r.Cookie(loginCookie)
+	if state == "" || err != nil || cookie.Value != state {
+		return nil, invalid("login state does not match the browser")
+	}
+	http.SetCookie(w, &http.Cookie{Name: loginCookie, Value: "", Path: "/", MaxAge: -1, Secure: true, HttpOnly: true, SameSite: http.SameSiteLaxMode})
+
+	o.mu.Lock()
+	login, ok := o.pending[state]
+	delete(o.pending, state)
+	o.mu.Unlock()
+	if !ok || !o.clock.Now().Before(login.expiresAt) {
+		return nil, invalid("login expired, sign in again")
+	}
+
+	discovery, idTokens, err := o.provider(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	rawIDToken, err := o.exchange(r.Context(), discovery, query.Get("code"), login.verifier)
+	if err != nil {
+		return nil, err
+	}
+	principal, custom, err := idTokens.verify(r.Context(), rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+	if nonce, _ := custom["nonce"].(string); nonce != login.nonce {
+		return nil, invalid("ID token of %s has the wrong nonce", principal.Name)
+	}
+
+	principal.Method = MethodOIDC
+	for _, claim := range []string{"preferred_username", "email"} {
+		if name, ok := custom[claim].(string); ok && name != "" {
+			principal.Name = name
+			break
+		}
+	}
+	return principal, nil
+}
+
+// exchange trades an authorization code for an ID token at the token endpoint
+func (o *OIDC) exchange(ctx context.Context, discovery *oidcDiscovery, code, verifier string) (string, error) {
+	if code == "" {
+		return "", invalid("provider returned no authorization code")
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.redirectURL},
+		"client_id":     {o.clientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if o.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(o.clientID), url.QueryEscape(o.clientSecret))
+	}
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return "", fmt.Errorf("failed to exchange authorization code: %s", resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", invalid("provider rejected the authorization code: %s %s", tokens.Error, tokens.ErrorDescription)
+	}
+	if tokens.IDToken == "" {
+		return "", errors.New("provider returned no ID token")
+	}
+	return tokens.IDToken, nil
+}
+
+// provider returns the endpoints of the provider and the verifier of its ID
+// tokens, discovering them on first use
+func (o *OIDC) provider(ctx context.Context) (*oidcDiscovery, *JWT, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.discovery != nil {
+		return o.discovery, o.idTokens, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to discover OIDC provider: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("failed to discover OIDC provider: %s", resp.Status)
+	}
+	var discovery oidcDiscovery
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&discovery); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse OIDC provider metadata: %w", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != o.issuer {
+		return nil, nil, fmt.Errorf("OIDC provider metadata names issuer %s instead of %s", discovery.Issuer, o.issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, nil, errors.New("OIDC provider metadata lacks an authorization, token or JWKS endpoint")
+	}
+
+	o.discovery = &discovery
+	o.idTokens = &JWT{
+		location:   discovery.JWKSURI,
+		refresh:    o.cfg.JWKSRefresh,
+		issuer:     discovery.Issuer,
+		audience:   o.clientID,
+		rolesClaim: o.cfg.JWTRolesClaim,
+		httpClient: o.httpClient,
+		clock:      o.clock,
+	}
+	return o.discovery, o.idTokens, nil
+}
+
+// readSecret reads a secret from a file, ignoring surrounding whitespace
+func readSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+	return secret, nil
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/auth/oidctest"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRedirectURL = "https://autoscaler.example.com/login/callback"
+
+func newTestOIDC(t *testing.T, provider *oidctest.Provider, clk clock.Clock) *OIDC {
+	o, err := newOIDC(config.AuthConfig{
+		OIDCIssuer:           provider.Issuer(),
+		OIDCClientID:         provider.ClientID,
+		OIDCClientSecretFile: writeFile(t, "client-secret", provider.ClientSecret+"\n"),
+		OIDCRedirectURL:      testRedirectURL,
+		JWKSRefresh:          time.Hour,
+		JWTRolesClaim:        "roles",
+	}, clk)
+	require.NoError(t, err)
+	return o
+}
+
+// startLogin starts a login and follows the redirect to the provider. It
+// returns the callback request the provider redirected back with.
+func startLogin(t *testing.T, o *OIDC) *http.Request {
+	recorder := httptest.NewRecorder()
+	require.NoError(t, o.Start(recorder, httptest.NewRequest(http.MethodGet, "/login/oidc", nil)))
+	require.Equal(t, http.StatusFound, recorder.Code)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(recorder.Header().Get("Location"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	callback := httptest.NewRequest(http.MethodGet, resp.Header.Get("Location"), nil)
+	for _, cookie := range recorder.Result().Cookies() {
+		callback.AddCookie(cookie)
+	}
+	return callback
+}
+
+func TestOIDC(t *testing.T) {
+	provider := oidctest.NewProvider("autoscaler", "client-secret")
+	defer provider.Close()
+	provider.SetUser(oidctest.User{Subject: "u-42", Name: "alice", Email: "alice@example.com", Roles: []string{"operator"}})
+	o := newTestOIDC(t, provider, clock.Real{})
+
+	callback := startLogin(t, o)
+	assert.Equal(t, "autoscaler.example.com", callback.URL.Host)
+	principal, err := o.Finish(httptest.NewRecorder(), callback)
+	require.NoError(t, err)
+	assert.Equal(t, &Principal{Name: "alice", Method: MethodOIDC, Role: RoleOperator}, principal)
+
+	// A login can only be finished once
+	_, err = o.Finish(httptest.NewRecorder(), callback)
+	assert.ErrorContains(t, err, "login expired")
+}
+
+func TestOIDC_Rejections(t *testing.T) {
+	provider := oidctest.NewProvider("autoscaler", "client-secret")
+	defer provider.Close()
+	clk := clock.NewSimulated(time.Now())
+	o := newTestOIDC(t, provider, clk)
+
+	// The callback must come to the browser that started the login
+	callback := startLogin(t, o)
+	stolen := httptest.NewRequest(http.MethodGet, callback.URL.String(), nil)
+	_, err := o.Finish(httptest.NewRecorder(), stolen)
+	assert.ErrorContains(t, err, "login state does not match")
+
+	// Logins the user declined or took too long for are rejected
+	provider.Deny(true)
+	_, err = o.Finish(httptest.NewRecorder(), startLogin(t, o))
+	assert.ErrorContains(t, err, "access_denied")
+	provider.Deny(false)
+
+	callback = startLogin(t, o)
+	clk.Advance(loginTimeout)
+	_, err = o.Finish(httptest.NewRecorder(), callback)
+	assert.ErrorContains(t, err, "login expired")
+}
+
+func TestOIDC_WrongClientSecret(t *testing.T) {
+	provider := oidctest.NewProvider("autoscaler", "client-secret")
+	defer provider.Close()
+	o := newTestOIDC(t, provider, clock.Real{})
+	o.clientSecret = "wrong"
+
+	_, err := o.Finish(httptest.NewRecorder(), startLogin(t, o))
+	assert.ErrorContains(t, err, "invalid_client")
+}
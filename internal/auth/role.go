@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+)
+
+// Role is what an authenticated principal is allowed to do. Every role may do
+// everything the roles below it may.
+type Role string
+
+// Roles, from the least to the most privileged
+const (
+	// RoleViewer can see the status, decisions, configuration and dashboard
+	RoleViewer Role = "viewer"
+	// RoleOperator can also scale within the configured capacity bounds
+	RoleOperator Role = "operator"
+	// RoleAdmin can also scale beyond the bounds, override the cooldown
+	// period, change the configuration and reset the circuit breaker
+	RoleAdmin Role = "admin"
+)
+
+// roleRanks orders the roles by privilege
+var roleRanks = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// ParseRole returns the role with the given name
+func ParseRole(name string) (Role, error) {
+	role := Role(name)
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("unknown role %q, expected viewer, operator or admin", name)
+	}
+	return role, nil
+}
+
+// Allows reports whether the role grants what the required role does
+func (r Role) Allows(required Role) bool {
+	return roleRanks[r] >= roleRanks[required]
+}
+
+// highestRole returns the most privileged of the named roles, ignoring names
+// that are not roles, and the viewer role when there are none
+func highestRole(names []string) Role {
+	role := RoleViewer
+	for _, name := range names {
+		if candidate, err := ParseRole(name); err == nil && candidate.Allows(role) {
+			role = candidate
+		}
+	}
+	return role
+}
+
+// Allowed reports whether the principal of a request has the required role.
+// Without authentication there is no principal and everything is allowed.
+func Allowed(ctx context.Context, required Role) bool {
+	principal := FromContext(ctx)
+	return principal == nil || principal.Role.Allows(required)
+}
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRole_Allows(t *testing.T) {
+	assert.True(t, RoleAdmin.Allows(RoleOperator))
+	assert.True(t, RoleOperator.Allows(RoleOperator))
+	assert.False(t, RoleOperator.Allows(RoleAdmin))
+	assert.False(t, RoleViewer.Allows(RoleOperator))
+	assert.False(t, Role("").Allows(RoleViewer))
+
+	_, err := ParseRole("root")
+	assert.ErrorContains(t, err, `unknown role "root"`)
+}
+
+func TestAllowed(t *testing.T) {
+	// Without authentication everything is allowed
+	assert.True(t, Allowed(context.Background(), RoleAdmin))
+
+	ctx := WithPrincipal(context.Background(), &Principal{Name: "ci", Role: RoleOperator})
+	assert.True(t, Allowed(ctx, RoleViewer))
+	assert.True(t, Allowed(ctx, RoleOperator))
+	assert.False(t, Allowed(ctx, RoleAdmin))
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+)
+
+// SessionCookie is the cookie holding the ID of a dashboard session. The
+// __Host- prefix makes browsers only accept it over HTTPS, or from localhost,
+// for the whole host.
+const SessionCookie = "__Host-autoscaler-session"
+
+// CSRF tokens are sent in this header by scripts and in this field by forms
+const (
+	CSRFHeader = "X-CSRF-Token"
+	CSRFField  = "csrf_token"
+)
+
+// loginCSRFCookie holds the CSRF token of the login form, since there is no
+// session to hold it yet
+const loginCSRFCookie = "__Host-autoscaler-login-csrf"
+
+// Session is a signed in dashboard user
+type Session struct {
+	Principal Principal
+	CSRFToken string
+	ExpiresAt time.Time
+}
+
+// Sessions keeps dashboard users signed in with a session cookie. Sessions
+// are kept in memory and end after the TTL, on logout or on a restart.
+// Requests with a session that change something must carry its CSRF token.
+type Sessions struct {
+	ttl   time.Duration
+	clock clock.Clock
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewSessions creates a session store whose sessions last for the TTL
+func NewSessions(ttl time.Duration, clk clock.Clock) *Sessions {
+	return &Sessions{ttl: ttl, clock: clk, sessions: map[string]*Session{}}
+}
+
+// Start signs the principal in with a new session and sets its cookie
+func (s *Sessions) Start(w http.ResponseWriter, principal *Principal) (*Session, error) {
+	id, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	csrfToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	now := s.clock.Now()
+	session := &Session{Principal: *principal, CSRFToken: csrfToken, ExpiresAt: now.Add(s.ttl)}
+
+	s.mu.Lock()
+	for otherID, other := range s.sessions {
+		if !now.Before(other.ExpiresAt) {
+			delete(s.sessions, otherID)
+		}
+	}
+	s.sessions[id] = session
+	s.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    id,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return session, nil
+}
+
+// Get returns the session of a request, or nil when it has none or it ended
+func (s *Sessions) Get(r *http.Request) *Session {
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[cookie.Value]
+	if !ok {
+		return nil
+	}
+	if !s.clock.Now().Before(session.ExpiresAt) {
+		delete(s.sessions, cookie.Value)
+		return nil
+	}
+	return session
+}
+
+// End signs the user of a request out and clears the session cookie
+func (s *Sessions) End(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(SessionCookie); err == nil {
+		s.mu.Lock()
+		delete(s.sessions, cookie.Value)
+		s.mu.Unlock()
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (s *Sessions) Authenticate(r *http.Request) (*Principal, error) {
+	session := s.Get(r)
+	if session == nil {
+		return nil, ErrNoCredentials
+	}
+	if !safeMethod(r.Method) && !CheckCSRF(r, session) {
+		return nil, ErrCSRF
+	}
+	principal := session.Principal
+	return &principal, nil
+}
+
+// CheckCSRF reports whether a request carries the CSRF token of the session,
+// in the header or in a form field
+func CheckCSRF(r *http.Request, session *Session) bool {
+	token := r.Header.Get(CSRFHeader)
+	if token == "" {
+		token = r.PostFormValue(CSRFField)
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(session.CSRFToken)) == 1
+}
+
+// StartLoginCSRF sets the cookie with the CSRF token of a login form and
+// returns the token for the form, so another site cannot sign the browser in
+// to an account of its choosing
+func StartLoginCSRF(w http.ResponseWriter) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCSRFCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(loginTimeout.Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return token, nil
+}
+
+// CheckLoginCSRF reports whether a login comes from this site: a browser that
+// names the origin of the request must name this host, and the form field must
+// carry the token of the cookie set with the form
+func CheckLoginCSRF(r *http.Request) bool {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || u.Host != r.Host {
+			return false
+		}
+	}
+	cookie, err := r.Cookie(loginCSRFCookie)
+	if err != nil {
+		return false
+	}
+	token := r.PostFormValue(CSRFField)
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(cookie.Value)) == 1
+}
+
+// safeMethod reports whether requests with the method only read
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// randomToken returns 32 random bytes, base64url encoded
+func randomToken() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
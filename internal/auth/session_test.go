@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessions(t *testing.T) {
+	clk := clock.NewSimulated(time.Unix(1700000000, 0))
+	sessions := NewSessions(time.Hour, clk)
+	recorder := httptest.NewRecorder()
+	session, err := sessions.Start(recorder, &Principal{Name: "alice", Method: MethodPassword, Role: RoleOperator})
+	require.NoError(t, err)
+
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+	cookie := cookies[0]
+	assert.Equal(t, SessionCookie, cookie.Name)
+	assert.True(t, cookie.Secure)
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+
+	request := func(method string, headers map[string]string, form url.Values) *http.Request {
+		var req *http.Request
+		if form != nil {
+			req = httptest.NewRequest(method, "/scale", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		} else {
+			req = httptest.NewRequest(method, "/scale", nil)
+		}
+		req.AddCookie(cookie)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		return req
+	}
+
+	// Reading needs the cookie alone
+	principal, err := sessions.Authenticate(request(http.MethodGet, nil, nil))
+	require.NoError(t, err)
+	assert.Equal(t, &Principal{Name: "alice", Method: MethodPassword, Role: RoleOperator}, principal)
+
+	// Changes need the CSRF token, in a header or a form field
+	_, err = sessions.Authenticate(request(http.MethodPost, nil, nil))
+	assert.ErrorIs(t, err, ErrCSRF)
+	_, err = sessions.Authenticate(request(http.MethodPost, map[string]string{CSRFHeader: "forged"}, nil))
+	assert.ErrorIs(t, err, ErrCSRF)
+	_, err = sessions.Authenticate(request(http.MethodPost, map[string]string{CSRFHeader: session.CSRFToken}, nil))
+	assert.NoError(t, err)
+	_, err = sessions.Authenticate(request(http.MethodPost, nil, url.Values{CSRFField: {session.CSRFToken}}))
+	assert.NoError(t, err)
+
+	// Sessions end after the TTL
+	clk.Advance(time.Hour)
+	_, err = sessions.Authenticate(request(http.MethodGet, nil, nil))
+	assert.ErrorIs(t, err, ErrNoCredentials)
+}
+
+func TestSessions_End(t *testing.T) {
+	sessions := NewSessions(time.Hour, clock.Real{})
+	recorder := httptest.NewRecorder()
+	_, err := sessions.Start(recorder, &Principal{Name: "alice"})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(recorder.Result().Cookies()[0])
+
+	recorder = httptest.NewRecorder()
+	sessions.End(recorder, req)
+
+	assert.Nil(t, sessions.Get(req))
+	cleared := recorder.Result().Cookies()
+	require.Len(t, cleared, 1)
+	assert.Equal(t, "", cleared[0].Value)
+	assert.Less(t, cleared[0].MaxAge, 0)
+}
+
+func TestLoginCSRF(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	token, err := StartLoginCSRF(recorder)
+	require.NoError(t, err)
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+
+	login := func(token, origin string, withCookie bool) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "https://autoscaler.example.com/login", strings.NewReader(url.Values{CSRFField: {token}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if withCookie {
+			req.AddCookie(cookies[0])
+		}
+		return req
+	}
+	assert.True(t, CheckLoginCSRF(login(token, "", true)))
+	assert.True(t, CheckLoginCSRF(login(token, "https://autoscaler.example.com", true)))
+	assert.False(t, CheckLoginCSRF(login(token, "https://evil.example.com", true)))
+	assert.False(t, CheckLoginCSRF(login(token, "null", true)))
+	assert.False(t, CheckLoginCSRF(login(token, "", false)))
+	assert.False(t, CheckLoginCSRF(login("forged", "", true)))
+	assert.False(t, CheckLoginCSRF(login("", "", true)))
+}
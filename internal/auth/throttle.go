@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+)
+
+// loginFreeFailures is how many failed logins a client or user name may have
+// before each further one locks it out
+const loginFreeFailures = 5
+
+// Lockouts start at minLoginLockout and double with every further failure up
+// to maxLoginLockout. Failures are forgotten after loginFailureMemory without
+// any.
+const (
+	minLoginLockout    = time.Second
+	maxLoginLockout    = 15 * time.Minute
+	loginFailureMemory = time.Hour
+)
+
+// LoginThrottle slows down password guessing by locking out the clients and
+// user names with repeated failed logins, for longer with every failure
+type LoginThrottle struct {
+	clock clock.Clock
+
+	mu       sync.Mutex
+	failures map[string]*loginFailures
+}
+
+// loginFailures are the recent failed logins of a client or user name
+type loginFailures struct {
+	count       int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+// NewLoginThrottle creates a throttle without any failed logins
+func NewLoginThrottle(clk clock.Clock) *LoginThrottle {
+	return &LoginThrottle{clock: clk, failures: map[string]*loginFailures{}}
+}
+
+// Wait returns how long a login for all of the keys has to wait, or zero when
+// it may be tried now
+func (t *LoginThrottle) Wait(keys ...string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.clock.Now()
+	var wait time.Duration
+	for _, key := range keys {
+		if failures, ok := t.failures[key]; ok {
+			wait = max(wait, failures.lockedUntil.Sub(now))
+		}
+	}
+	return wait
+}
+
+// Failed records a failed login for each of the keys
+func (t *LoginThrottle) Failed(keys ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.clock.Now()
+	for key, failures := range t.failures {
+		if now.Sub(failures.last) >= loginFailureMemory {
+			delete(t.failures, key)
+		}
+	}
+	for _, key := range keys {
+		failures, ok := t.failures[key]
+		if !ok {
+			failures = &loginFailures{}
+			t.failures[key] = failures
+		}
+		failures.count++
+		failures.last = now
+		if failures.count > loginFreeFailures {
+			failures.lockedUntil = now.Add(loginLockout(failures.count - loginFreeFailures))
+		}
+	}
+}
+
+// Succeeded forgets the failed logins of the keys
+func (t *LoginThrottle) Succeeded(keys ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, key := range keys {
+		delete(t.failures, key)
+	}
+}
+
+// loginLockout is the lockout after the given number of failures past the
+// free ones
+func loginLockout(failures int) time.Duration {
+	lockout := minLoginLockout
+	for i := 1; i < failures && lockout < maxLoginLockout; i++ {
+		lockout *= 2
+	}
+	return min(lockout, maxLoginLockout)
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginThrottle(t *testing.T) {
+	clk := clock.NewSimulated(time.Unix(1700000000, 0))
+	throttle := NewLoginThrottle(clk)
+
+	// A few failures are free
+	for i := 0; i < loginFreeFailures; i++ {
+		throttle.Failed("client:10.0.0.1", "user:alice")
+	}
+	assert.Zero(t, throttle.Wait("client:10.0.0.1", "user:alice"))
+
+	// Every further one locks out the client and the user name for longer
+	throttle.Failed("client:10.0.0.1", "user:alice")
+	assert.Equal(t, minLoginLockout, throttle.Wait("client:10.0.0.1", "user:bob"))
+	assert.Equal(t, minLoginLockout, throttle.Wait("client:10.0.0.2", "user:alice"))
+	assert.Zero(t, throttle.Wait("client:10.0.0.2", "user:bob"))
+	throttle.Failed("client:10.0.0.1", "user:alice")
+	assert.Equal(t, 2*minLoginLockout, throttle.Wait("client:10.0.0.1"))
+
+	clk.Advance(2 * minLoginLockout)
+	assert.Zero(t, throttle.Wait("client:10.0.0.1", "user:alice"))
+
+	// A successful login forgives the user name only
+	throttle.Failed("client:10.0.0.1", "user:alice")
+	throttle.Succeeded("user:alice")
+	assert.Zero(t, throttle.Wait("user:alice"))
+	assert.Equal(t, 4*minLoginLockout, throttle.Wait("client:10.0.0.1"))
+
+	// Failures are forgotten after a quiet hour
+	clk.Advance(loginFailureMemory)
+	throttle.Failed("client:10.0.0.3")
+	assert.Zero(t, throttle.Wait("client:10.0.0.1"))
+	throttle.Failed("client:10.0.0.1")
+	assert.Zero(t, throttle.Wait("client:10.0.0.1"))
+
+	assert.Equal(t, maxLoginLockout, loginLockout(100))
+}
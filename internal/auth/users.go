@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Users checks the passwords of dashboard users against bcrypt hashes
+type Users struct {
+	users map[string]user
+	// dummyHash is compared against for unknown users, so that they take as
+	// long to reject as wrong passwords
+	dummyHash []byte
+}
+
+type user struct {
+	hash []byte
+	role Role
+}
+
+// LoadUsers reads a users file. Every line names a user, gives the bcrypt hash
+// of the password and optionally the role of the user, e.g.
+// "alice $2a$10$... admin"; users without a role are viewers. Empty lines and
+// lines starting with # are ignored.
+func LoadUsers(path string) (*Users, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open users file: %w", err)
+	}
+	defer file.Close()
+
+	users := &Users{users: map[string]user{}}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("%s:%d: expected a name, a bcrypt hash and an optional role", path, lineNumber)
+		}
+		if _, err := bcrypt.Cost([]byte(fields[1])); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid bcrypt hash of user %s: %w", path, lineNumber, fields[0], err)
+		}
+		role := RoleViewer
+		if len(fields) == 3 {
+			if role, err = ParseRole(fields[2]); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+			}
+		}
+		if _, ok := users.users[fields[0]]; ok {
+			return nil, fmt.Errorf("%s:%d: duplicate user %s", path, lineNumber, fields[0])
+		}
+		users.users[fields[0]] = user{hash: []byte(fields[1]), role: role}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read users file: %w", err)
+	}
+	if len(users.users) == 0 {
+		return nil, fmt.Errorf("users file %s has no users", path)
+	}
+
+	users.dummyHash, err = bcrypt.GenerateFromPassword([]byte("unknown user"), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	return users, nil
+}
+
+// HashPassword returns the bcrypt hash of a password as written in the users file
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// Verify returns the user with the name when the password is theirs
+func (u *Users) Verify(name, password string) (*Principal, error) {
+	candidate, ok := u.users[name]
+	hash := candidate.hash
+	if !ok {
+		hash = u.dummyHash
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || !ok {
+		return nil, invalid("wrong user name or password")
+	}
+	return &Principal{Name: name, Method: MethodPassword, Role: candidate.role}, nil
+}
@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsers(t *testing.T) {
+	aliceHash, err := HashPassword("correct horse")
+	require.NoError(t, err)
+	bobHash, err := HashPassword("battery staple")
+	require.NoError(t, err)
+	users, err := LoadUsers(writeFile(t, "users", "# Dashboard users\nalice "+aliceHash+" admin\nbob "+bobHash+"\n"))
+	require.NoError(t, err)
+
+	principal, err := users.Verify("alice", "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, &Principal{Name: "alice", Method: MethodPassword, Role: RoleAdmin}, principal)
+	principal, err = users.Verify("bob", "battery staple")
+	require.NoError(t, err)
+	assert.Equal(t, RoleViewer, principal.Role)
+
+	_, err = users.Verify("alice", "battery staple")
+	assert.ErrorContains(t, err, "wrong user name or password")
+	_, err = users.Verify("carol", "correct horse")
+	assert.ErrorContains(t, err, "wrong user name or password")
+}
+
+func TestLoadUsers_RejectsPlaintextPasswords(t *testing.T) {
+	_, err := LoadUsers(writeFile(t, "users", "alice hunter2\n"))
+	assert.ErrorContains(t, err, "users:1: invalid bcrypt hash of user alice")
+
+	hash, err := HashPassword("hunter2")
+	require.NoError(t, err)
+	_, err = LoadUsers(writeFile(t, "users", "alice "+hash+"\nalice "+hash+"\n"))
+	assert.ErrorContains(t, err, "users:2: duplicate user alice")
+}
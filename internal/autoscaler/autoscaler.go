@@ -2,22 +2,64 @@ package autoscaler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
+	"os"
 	"sync"
 	"time"
 
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/audit"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
 	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
 	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/metrics"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/notify"
 	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/policy"
+	"golang.org/x/sync/singleflight"
 )
 
+// ErrScalingInProgress is returned when a scaling operation is requested while
+// another one is still running
+var ErrScalingInProgress = errors.New("scaling operation already in progress")
+
+// ErrStaleObservation is returned when the sidecar last observed the capacity
+// of the resource longer ago than the configured maximum observation age
+var ErrStaleObservation = errors.New("capacity observation is stale")
+
+// maxScaleConflicts bounds how often a scaling step is retried after the
+// sidecar reports that the resource is not in a state to change capacity
+const maxScaleConflicts = 3
+
 type Autoscaler struct {
 	config            *config.Config
+	configFile        string
+	configMu          sync.RWMutex
+	reloadMu          sync.Mutex
+	lastReload        *ReloadResult
+	overrides         *config.OverrideStore
+	overrideMu        sync.Mutex
+	audit             *audit.Log
 	client            omnistrate_api.Client
+	clock             clock.Clock
+	sources           []metrics.Source
+	policy            policy.Policy
+	shadows           []*shadow
+	lifecycle         *lifecycle
+	notifier          notify.Notifier
+	breaker           breaker
 	lastActionTime    time.Time
 	scalingInProgress bool
+	backgroundScaling bool
+	backgroundScales  sync.WaitGroup
 	targetCapacity    int
+	lastDecision      *Decision
+	decisions         []Decision
+	recommendation    *Recommendation
+	observed          *omnistrate_api.ResourceInstanceCapacity
+	observedAt        time.Time
+	refresh           singleflight.Group
 	mu                sync.RWMutex
 }
 
@@ -33,30 +75,216 @@ type ScalingStatus struct {
 	InstanceID        string
 	ResourceID        string
 	ResourceAlias     string
+	LastDecision      *Decision
+	Throttle          *omnistrate_api.ThrottleStatus
+	LastObservedTime  time.Time
+	ObservationAge    time.Duration
+	ObservationStale  bool
+	StatePolicy       string
+	StateSince        time.Time
+	Recovery          RecoveryStatus
+	LastReload        *ReloadResult
 }
 
-// NewAutoscaler creates a new autoscaler instance with configuration from environment variables
+// NewAutoscaler creates a new autoscaler instance with configuration from
+// environment variables and the runtime overrides persisted by earlier runs
 func NewAutoscaler(ctx context.Context) (*Autoscaler, error) {
-	config, err := config.NewConfigFromEnv()
+	cfg, err := config.NewConfigFromEnv()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load configuration: %w", err)
 	}
 
-	client := omnistrate_api.NewClient(config)
+	overrides, err := config.OpenOverrideStore(cfg.OverridesFile)
+	if err != nil {
+		return nil, err
+	}
+	auditLog, err := audit.Open(cfg.AuditFile)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.OverridesFile == "" || cfg.AuditFile == "" {
+		logger.Warn().Msg("AUTOSCALER_OVERRIDES_FILE or AUTOSCALER_AUDIT_FILE is not set, runtime configuration changes or their audit trail will be lost on restart")
+	}
+	if len(overrides.List()) > 0 {
+		withOverrides, err := config.Load(cfg.ConfigFile, config.WithOverrides(overrides.List(), os.LookupEnv))
+		if err != nil {
+			// Start without the overrides rather than not at all; they stay
+			// persisted and can be reverted through the API
+			logger.Error().Err(err).Msg("Persisted configuration overrides are invalid, ignoring them")
+		} else {
+			cfg = withOverrides
+		}
+	}
+
+	client, err := omnistrate_api.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sidecar client: %w", err)
+	}
+
+	a, err := NewWithClient(cfg, client, clock.Real{})
+	if err != nil {
+		return nil, err
+	}
+	a.overrides = overrides
+	a.audit = auditLog
+	// Overrides that ran out while the controller was down are reverted now
+	a.ExpireOverrides()
+	return a, nil
+}
+
+// NewWithClient creates an autoscaler from an existing configuration, scaling
+// through the given client and telling time with the given clock
+func NewWithClient(config *config.Config, client omnistrate_api.Client, clk clock.Clock) (*Autoscaler, error) {
+	var sources []metrics.Source
+	for _, sourceConfig := range config.MetricSources {
+		source, err := metrics.NewPrometheusScraper(sourceConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create metric source: %w", err)
+		}
+		sources = append(sources, source)
+	}
+
+	scalingPolicy, err := policy.FromConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scaling policy: %w", err)
+	}
+
+	shadows, err := newShadows(config.ShadowPolicies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shadow policy: %w", err)
+	}
+
+	lifecycle, err := newLifecycle(config.StatePolicies)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create state policies: %w", err)
+	}
+
+	if err := validateRecoveryPolicy(config.Recovery.Policy); err != nil {
+		return nil, err
+	}
 
 	return &Autoscaler{
-		config: config,
-		client: client,
+		config:     config,
+		configFile: config.ConfigFile,
+		client:     client,
+		clock:      clk,
+		sources:    sources,
+		policy:     scalingPolicy,
+		shadows:    shadows,
+		lifecycle:  lifecycle,
+		notifier:   newNotifier(config.Recovery),
 	}, nil
 }
 
 // ScaleToTarget scales the resource to match the target capacity
 func (a *Autoscaler) ScaleToTarget(ctx context.Context, targetCapacity int) error {
+	return a.scaleToTarget(ctx, targetCapacity, false)
+}
+
+// RequestScale scales the resource to the target capacity on behalf of the
+// principal of the context, recording the request and its outcome as a
+// decision. With bypassCooldown the operation does not wait for the cooldown
+// period.
+func (a *Autoscaler) RequestScale(ctx context.Context, targetCapacity int, bypassCooldown bool) error {
+	capacity, err := a.getCurrentCapacity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current capacity: %w", err)
+	}
+
+	decision := Decision{
+		Time:            a.now(),
+		Principal:       principalOf(ctx),
+		CurrentCapacity: capacity.CurrentCapacity,
+		DesiredCapacity: targetCapacity,
+		Action:          ActionScale,
+		Reason:          "scaling requested",
+	}
+	if bypassCooldown {
+		decision.Reason += "; cooldown period overridden"
+	}
+
+	logger.Info().
+		Str("principal", decision.Principal).
+		Int("targetCapacity", targetCapacity).
+		Bool("bypassCooldown", bypassCooldown).
+		Msg("Scaling requested")
+	err = a.scaleToTarget(ctx, targetCapacity, bypassCooldown)
+	if err != nil {
+		decision.Error = err.Error()
+	}
+	a.recordDecision(decision)
+	return err
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context naming who requested the operations
+// performed with it, such as the authenticated caller of an API request
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// principalOf returns who requested the operations performed with the
+// context, or the autoscaler itself when nobody did
+func principalOf(ctx context.Context) string {
+	if principal, ok := ctx.Value(principalKey{}).(string); ok && principal != "" {
+		return principal
+	}
+	return PrincipalAutoscaler
+}
+
+// Wake scales the resource up from zero without waiting for the cooldown
+// period. It does nothing when the resource already has capacity.
+func (a *Autoscaler) Wake(ctx context.Context, reason string) error {
+	capacity, err := a.getCurrentCapacity(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get current capacity: %w", err)
+	}
+	if capacity.CurrentCapacity > 0 {
+		return nil
+	}
+
+	cfg := a.settings()
+	wakeCapacity := clamp(cfg, max(cfg.Idle.WakeCapacity, 1))
+	decision := Decision{
+		Time:            a.now(),
+		Principal:       principalOf(ctx),
+		Policy:          policy.TypeIdle,
+		CurrentCapacity: capacity.CurrentCapacity,
+		DesiredCapacity: wakeCapacity,
+		Action:          ActionWake,
+		Reason:          reason,
+	}
+	if cfg.RecommendOnly {
+		decision.Action = ActionRecommend
+		a.recordDecision(decision)
+		logger.Info().Int("targetCapacity", wakeCapacity).Str("reason", reason).Msg("Wake requested, not acting in recommend-only mode")
+		return nil
+	}
+
+	logger.Info().Int("targetCapacity", wakeCapacity).Str("reason", reason).Msg("Waking resource from zero")
+	err = a.scaleToTarget(ctx, wakeCapacity, true)
+	if err != nil {
+		decision.Error = err.Error()
+	}
+	a.recordDecision(decision)
+	return err
+}
+
+// scaleToTarget performs the scaling loop, optionally skipping the cooldown
+// period for priority operations such as waking from zero. The operation keeps
+// the configuration in effect when it started, even if it is reloaded meanwhile.
+func (a *Autoscaler) scaleToTarget(ctx context.Context, targetCapacity int, bypassCooldown bool) error {
+	cfg := a.settings()
+	if err := a.checkCircuit(); err != nil {
+		return err
+	}
+
 	// Check if scaling is already in progress
 	a.mu.Lock()
 	if a.scalingInProgress {
 		a.mu.Unlock()
-		return fmt.Errorf("scaling operation already in progress to target capacity: %d", a.targetCapacity)
+		return fmt.Errorf("%w to target capacity: %d", ErrScalingInProgress, a.targetCapacity)
 	}
 	a.scalingInProgress = true
 	a.targetCapacity = targetCapacity
@@ -72,20 +300,33 @@ func (a *Autoscaler) ScaleToTarget(ctx context.Context, targetCapacity int) erro
 
 	logger.Info().Int("targetCapacity", targetCapacity).Msg("Scaling to target capacity")
 
+	conflicts := 0
+	recoveries := 0
 	for {
-		// Check if we're within cooldown period
+		// Check if we're within cooldown period, and whether a recovery
+		// rolled the target back
 		a.mu.RLock()
 		lastAction := a.lastActionTime
+		targetCapacity = a.targetCapacity
 		a.mu.RUnlock()
 
-		if !lastAction.IsZero() && time.Since(lastAction) < a.config.CooldownDuration {
-			waitTime := a.config.CooldownDuration - time.Since(lastAction)
+		if !bypassCooldown && !lastAction.IsZero() && a.since(lastAction) < cfg.CooldownDuration {
+			waitTime := cfg.CooldownDuration - a.since(lastAction)
 			logger.Info().Dur("waitTime", waitTime).Msg("Within cooldown period, waiting before scaling")
-			time.Sleep(waitTime)
+			if err := a.getClock().Sleep(ctx, waitTime); err != nil {
+				return fmt.Errorf("interrupted while waiting for cooldown: %w", err)
+			}
 		}
 
 		// Wait for instance to be in ACTIVE state
-		currentCapacity, err := a.waitForActiveState(ctx)
+		currentCapacity, err := a.waitForActiveState(ctx, cfg)
+		if errors.Is(err, ErrInstanceFailed) {
+			err = a.recoverFromFailure(ctx, cfg, err, recoveries)
+			if err == nil {
+				recoveries++
+				continue
+			}
+		}
 		if err != nil {
 			return fmt.Errorf("failed to wait for active state: %w", err)
 		}
@@ -102,73 +343,144 @@ func (a *Autoscaler) ScaleToTarget(ctx context.Context, targetCapacity int) erro
 
 		// Perform scaling operation
 		if currentCapacity.CurrentCapacity < targetCapacity {
-			err = a.scaleUp(ctx, currentCapacity.CurrentCapacity)
+			err = a.scaleUp(ctx, cfg, currentCapacity.CurrentCapacity)
 		} else {
-			err = a.scaleDown(ctx, currentCapacity.CurrentCapacity)
+			err = a.scaleDown(ctx, cfg, currentCapacity.CurrentCapacity)
 		}
 
+		// The resource can leave ACTIVE between the status check and the
+		// request, in which case wait for it to settle and try again
+		if errors.Is(err, omnistrate_api.ErrConflict) && conflicts < maxScaleConflicts {
+			conflicts++
+			logger.Warn().Err(err).Int("attempt", conflicts).Msg("Resource changed state before scaling, retrying")
+			continue
+		}
 		if err != nil {
 			return fmt.Errorf("failed to scale: %w", err)
 		}
 
 		// Update last action time
 		a.mu.Lock()
-		a.lastActionTime = time.Now()
+		a.lastActionTime = a.now()
 		a.mu.Unlock()
 	}
 
+	a.recordSuccess()
 	return nil
 }
 
 // getCurrentCapacity gets the current capacity of the resource
 func (a *Autoscaler) getCurrentCapacity(ctx context.Context) (*omnistrate_api.ResourceInstanceCapacity, error) {
-	capacity, err := a.client.GetCurrentCapacity(ctx, a.config.TargetResource)
+	capacity, err := a.client.GetCurrentCapacity(ctx, a.settings().TargetResource)
 	if err != nil {
 		return nil, err
 	}
+
+	a.mu.Lock()
+	a.observed = &capacity
+	a.observedAt = a.now()
+	a.mu.Unlock()
+	a.getLifecycle().observe(capacity.Status, a.now())
+
 	return &capacity, nil
 }
 
+// ObservedCapacity returns the most recent capacity observation, querying the
+// sidecar only when the cached observation is older than maxAge. Concurrent
+// callers share a single query so that a busy caller such as the proxy does
+// not use up the sidecar rate limit.
+func (a *Autoscaler) ObservedCapacity(ctx context.Context, maxAge time.Duration) (omnistrate_api.ResourceInstanceCapacity, error) {
+	a.mu.RLock()
+	observed, observedAt := a.observed, a.observedAt
+	a.mu.RUnlock()
+	if observed != nil && a.since(observedAt) <= maxAge {
+		return *observed, nil
+	}
+
+	result := a.refresh.DoChan("capacity", func() (interface{}, error) {
+		// The query is shared, so it must not be cancelled along with the
+		// caller that happened to start it
+		return a.getCurrentCapacity(context.WithoutCancel(ctx))
+	})
+	select {
+	case res := <-result:
+		if res.Err != nil {
+			return omnistrate_api.ResourceInstanceCapacity{}, res.Err
+		}
+		return *res.Val.(*omnistrate_api.ResourceInstanceCapacity), nil
+	case <-ctx.Done():
+		return omnistrate_api.ResourceInstanceCapacity{}, ctx.Err()
+	}
+}
+
+// AddSource registers an additional metric source. It must be called before Run.
+func (a *Autoscaler) AddSource(source metrics.Source) {
+	a.sources = append(a.sources, source)
+}
+
 // waitForActiveState waits for the instance to be in ACTIVE state
-func (a *Autoscaler) waitForActiveState(ctx context.Context) (*omnistrate_api.ResourceInstanceCapacity, error) {
-	maxWaitTime := a.config.WaitForActiveTimeout
-	checkInterval := a.config.WaitForActiveCheckInterval
-	timeout := time.After(maxWaitTime)
-	ticker := time.NewTicker(checkInterval)
-	defer ticker.Stop()
+func (a *Autoscaler) waitForActiveState(ctx context.Context, cfg *config.Config) (*omnistrate_api.ResourceInstanceCapacity, error) {
+	maxWaitTime := cfg.WaitForActiveTimeout
+	checkInterval := cfg.WaitForActiveCheckInterval
+	deadline := a.now().Add(maxWaitTime)
+	var staleErr error
 
 	for {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case <-timeout:
-			return nil, fmt.Errorf("timeout waiting for instance to become ACTIVE")
-		case <-ticker.C:
-			capacity, err := a.getCurrentCapacity(ctx)
-			if err != nil {
-				logger.Warn().Err(err).Msg("Error checking instance status")
-				continue
+		if err := a.getClock().Sleep(ctx, checkInterval); err != nil {
+			return nil, err
+		}
+		if !a.now().Before(deadline) {
+			if staleErr != nil {
+				return nil, fmt.Errorf("timeout waiting for a current observation of the instance: %w", staleErr)
 			}
+			return nil, fmt.Errorf("timeout waiting for instance to become ACTIVE")
+		}
 
-			logger.Debug().Str("status", string(capacity.Status)).Msg("Current instance status")
-			if capacity.Status == omnistrate_api.ACTIVE {
-				logger.Info().Msg("Instance is now ACTIVE")
-				return capacity, nil
+		capacity, err := a.getCurrentCapacity(ctx)
+		if err != nil {
+			// Errors such as an unknown resource alias will not go away by
+			// waiting, so give up instead of polling until the timeout
+			var apiErr *omnistrate_api.APIError
+			if errors.As(err, &apiErr) && !apiErr.Retryable {
+				return nil, fmt.Errorf("failed to check instance status: %w", err)
 			}
+			logger.Warn().Err(err).Msg("Error checking instance status")
+			continue
+		}
+
+		// An outdated observation says nothing reliable about the status or
+		// capacity, so keep polling until the sidecar catches up
+		if staleErr = a.checkObservation(cfg, capacity); staleErr != nil {
+			logger.Warn().Err(staleErr).Msg("Ignoring stale capacity observation")
+			continue
+		}
 
+		logger.Debug().Str("status", string(capacity.Status)).Msg("Current instance status")
+		switch a.getLifecycle().policy(capacity.Status) {
+		case StatePolicyReady:
+			logger.Info().Msg("Instance is now ACTIVE")
+			a.recordKnownGood(capacity.CurrentCapacity)
+			return capacity, nil
+		case StatePolicyZero:
+			logger.Info().Str("status", string(capacity.Status)).Msg("Treating instance as having zero capacity")
+			zero := *capacity
+			zero.CurrentCapacity = 0
+			return &zero, nil
+		case StatePolicyAbort:
 			if capacity.Status == omnistrate_api.FAILED {
-				return nil, fmt.Errorf("instance is in FAILED state")
+				return nil, fmt.Errorf("instance is in %s state: %w", capacity.Status, ErrInstanceFailed)
 			}
-
-			logger.Debug().Str("status", string(capacity.Status)).Msg("Instance status is not ACTIVE, waiting")
+			return nil, fmt.Errorf("instance is in %s state: %w", capacity.Status, ErrInstanceState)
 		}
+
+		logger.Debug().Str("status", string(capacity.Status)).Msg("Instance status is not ACTIVE, waiting")
 	}
 }
 
 // scaleUp adds capacity to the resource
-func (a *Autoscaler) scaleUp(ctx context.Context, currentCapacity int) error {
+func (a *Autoscaler) scaleUp(ctx context.Context, cfg *config.Config, currentCapacity int) error {
 	// Ensure we do not exceed target capacity
-	steps := uint(math.Min(float64(a.config.Steps), float64(a.targetCapacity-currentCapacity)))
+	steps := uint(math.Min(float64(cfg.Steps), float64(a.targetCapacity-currentCapacity)))
 	if steps <= 0 {
 		logger.Info().Msg("No scaling up needed")
 		return nil
@@ -177,7 +489,7 @@ func (a *Autoscaler) scaleUp(ctx context.Context, currentCapacity int) error {
 		Int("currentCapacity", currentCapacity).
 		Uint("increaseBy", steps).
 		Msg("Scaling up instances")
-	_, err := a.client.AddCapacity(ctx, a.config.TargetResource, steps)
+	_, err := a.client.AddCapacity(ctx, cfg.TargetResource, steps)
 	if err != nil {
 		return fmt.Errorf("failed to add capacity: %w", err)
 	}
@@ -187,8 +499,8 @@ func (a *Autoscaler) scaleUp(ctx context.Context, currentCapacity int) error {
 }
 
 // scaleDown removes capacity from the resource
-func (a *Autoscaler) scaleDown(ctx context.Context, currentCapacity int) error {
-	steps := uint(math.Min(float64(a.config.Steps), float64(currentCapacity-a.targetCapacity)))
+func (a *Autoscaler) scaleDown(ctx context.Context, cfg *config.Config, currentCapacity int) error {
+	steps := uint(math.Min(float64(cfg.Steps), float64(currentCapacity-a.targetCapacity)))
 	if steps <= 0 {
 		logger.Info().Msg("No scaling down needed")
 		return nil
@@ -198,7 +510,7 @@ func (a *Autoscaler) scaleDown(ctx context.Context, currentCapacity int) error {
 		Int("currentCapacity", currentCapacity).
 		Uint("decreaseBy", steps).
 		Msg("Scaling down instances")
-	_, err := a.client.RemoveCapacity(ctx, a.config.TargetResource, steps)
+	_, err := a.client.RemoveCapacity(ctx, cfg.TargetResource, steps)
 	if err != nil {
 		return fmt.Errorf("failed to remove capacity: %w", err)
 	}
@@ -212,6 +524,9 @@ func (a *Autoscaler) GetStatus(ctx context.Context) (*ScalingStatus, error) {
 	if err != nil {
 		return nil, err
 	}
+	_, stateSince, statePolicy := a.getLifecycle().state()
+	cfg := a.settings()
+	lastReload := a.LastReload()
 
 	a.mu.RLock()
 	defer a.mu.RUnlock()
@@ -225,21 +540,104 @@ func (a *Autoscaler) GetStatus(ctx context.Context) (*ScalingStatus, error) {
 		InstanceID:        capacity.InstanceID,
 		ResourceID:        capacity.ResourceID,
 		ResourceAlias:     capacity.ResourceAlias,
+		LastDecision:      a.lastDecision,
+		Throttle:          a.ThrottleStatus(),
+		LastObservedTime:  time.Time(capacity.LastObservedTimestamp),
+		ObservationStale:  a.checkObservation(cfg, capacity) != nil,
+		LastReload:        lastReload,
 	}
+	status.ObservationAge, _ = a.observationAge(capacity)
+	status.StateSince, status.StatePolicy = stateSince, statePolicy
+	status.Recovery = a.recoveryStatus()
 
 	// Calculate cooldown information
 	if !a.lastActionTime.IsZero() {
-		timeSinceLastAction := time.Since(a.lastActionTime)
-		if timeSinceLastAction < a.config.CooldownDuration {
+		timeSinceLastAction := a.since(a.lastActionTime)
+		if timeSinceLastAction < cfg.CooldownDuration {
 			status.InCooldownPeriod = true
-			status.CooldownRemaining = a.config.CooldownDuration - timeSinceLastAction
+			status.CooldownRemaining = cfg.CooldownDuration - timeSinceLastAction
 		}
 	}
 
 	return status, nil
 }
 
-// GetConfig returns the current configuration
+// observationAge returns how long ago the sidecar observed the capacity, and
+// false when the observation carries no timestamp
+func (a *Autoscaler) observationAge(capacity *omnistrate_api.ResourceInstanceCapacity) (time.Duration, bool) {
+	observed := time.Time(capacity.LastObservedTimestamp)
+	if observed.IsZero() {
+		return 0, false
+	}
+	// Clock skew between the sidecar and the controller can put the
+	// observation slightly in the future
+	return max(a.since(observed), 0), true
+}
+
+// checkObservation returns an ErrStaleObservation when the capacity was
+// observed longer ago than the configured maximum age. Observations without a
+// timestamp are accepted, as are all observations when no maximum is set.
+func (a *Autoscaler) checkObservation(cfg *config.Config, capacity *omnistrate_api.ResourceInstanceCapacity) error {
+	age, ok := a.observationAge(capacity)
+	if !ok || cfg.MaxObservationAge <= 0 || age <= cfg.MaxObservationAge {
+		return nil
+	}
+	return fmt.Errorf("%w: observed %s ago, maximum age is %s",
+		ErrStaleObservation, age.Round(time.Second), cfg.MaxObservationAge)
+}
+
+// ThrottleStatus reports how sidecar requests are being throttled, or nil when
+// the client does not throttle them
+func (a *Autoscaler) ThrottleStatus() *omnistrate_api.ThrottleStatus {
+	reporter, ok := a.client.(omnistrate_api.ThrottleReporter)
+	if !ok {
+		return nil
+	}
+	status := reporter.ThrottleStatus()
+	return &status
+}
+
+// Transitions returns the recorded changes of the instance status, oldest first
+func (a *Autoscaler) Transitions() []StateTransition {
+	return a.getLifecycle().history()
+}
+
+// getLifecycle returns the lifecycle tracking the instance status, creating
+// one with the default state policies when the autoscaler was built without it
+func (a *Autoscaler) getLifecycle() *lifecycle {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.lifecycle == nil {
+		a.lifecycle, _ = newLifecycle(nil)
+	}
+	return a.lifecycle
+}
+
+// getClock returns the clock the autoscaler tells time with, defaulting to the wall clock
+func (a *Autoscaler) getClock() clock.Clock {
+	if a.clock == nil {
+		return clock.Real{}
+	}
+	return a.clock
+}
+
+func (a *Autoscaler) now() time.Time {
+	return a.getClock().Now()
+}
+
+func (a *Autoscaler) since(t time.Time) time.Duration {
+	return a.now().Sub(t)
+}
+
+// GetConfig returns a copy of the configuration in effect
 func (a *Autoscaler) GetConfig() *config.Config {
+	return a.settings().Clone()
+}
+
+// settings returns the configuration in effect. A configuration is never
+// changed once in effect, reloads replace it, so callers can keep using it.
+func (a *Autoscaler) settings() *config.Config {
+	a.configMu.RLock()
+	defer a.configMu.RUnlock()
 	return a.config
 }
@@ -3,13 +3,16 @@ package autoscaler
 import (
 	"context"
 	"errors"
+	"net/http"
 	"testing"
 	"time"
 
 	"github.com/go-jose/go-jose/v4/testutils/require"
+	"github.com/go-openapi/strfmt"
 	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
 	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 // Helper function to create a test autoscaler with mocked client
@@ -792,3 +795,176 @@ func TestScaleDown_LimitedByTargetCapacity(t *testing.T) {
 	assert.NoError(t, err)
 	mockClient.AssertExpectations(t)
 }
+
+func TestScaleToTarget_ErrScalingInProgress(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createFastTestAutoscaler(t, mockClient)
+	autoscaler.scalingInProgress = true
+	autoscaler.targetCapacity = 5
+
+	err := autoscaler.ScaleToTarget(context.Background(), 3)
+
+	assert.ErrorIs(t, err, ErrScalingInProgress)
+	assert.EqualError(t, err, "scaling operation already in progress to target capacity: 5")
+}
+
+func TestScaleToTarget_UnknownResourceFailsFast(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createFastTestAutoscaler(t, mockClient)
+	ctx := context.Background()
+
+	notFound := &omnistrate_api.APIError{
+		Operation:     "get current capacity",
+		ResourceAlias: "test-resource",
+		StatusCode:    http.StatusNotFound,
+		Message:       "resource not found",
+	}
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(omnistrate_api.ResourceInstanceCapacity{}, notFound).Once()
+
+	err := autoscaler.ScaleToTarget(ctx, 3)
+
+	// A non-retryable error is returned on the first check instead of polling until the timeout
+	assert.ErrorIs(t, err, omnistrate_api.ErrNotFound)
+	var apiErr *omnistrate_api.APIError
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	}
+	mockClient.AssertExpectations(t)
+}
+
+func TestScaleToTarget_RetriesConflict(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createFastTestAutoscaler(t, mockClient)
+	ctx := context.Background()
+
+	conflict := &omnistrate_api.APIError{
+		Operation:     "add capacity",
+		ResourceAlias: "test-resource",
+		StatusCode:    http.StatusConflict,
+		Message:       "instance is STARTING",
+	}
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(2), nil).Twice()
+	mockClient.On("AddCapacity", ctx, "test-resource", uint(1)).Return(omnistrate_api.ResourceInstance{}, conflict).Once()
+	mockClient.On("AddCapacity", ctx, "test-resource", uint(1)).Return(omnistrate_api.ResourceInstance{}, nil).Once()
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(3), nil).Once()
+
+	err := autoscaler.ScaleToTarget(ctx, 3)
+
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
+
+func observedCapacity(capacity int, age time.Duration) omnistrate_api.ResourceInstanceCapacity {
+	observed := activeCapacity(capacity)
+	observed.LastObservedTimestamp = strfmt.DateTime(time.Now().Add(-age))
+	return observed
+}
+
+func TestScaleToTarget_IgnoresStaleObservation(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createFastTestAutoscaler(t, mockClient)
+	autoscaler.config.MaxObservationAge = time.Minute
+	ctx := context.Background()
+
+	// The outdated observation of 5 replicas must not lead to removing capacity
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(observedCapacity(5, 10*time.Minute), nil).Once()
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(observedCapacity(2, time.Second), nil).Once()
+	mockClient.On("AddCapacity", ctx, "test-resource", uint(1)).Return(omnistrate_api.ResourceInstance{}, nil).Once()
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(observedCapacity(3, 0), nil).Once()
+
+	err := autoscaler.ScaleToTarget(ctx, 3)
+
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestScaleToTarget_StaleObservationTimesOut(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createFastTestAutoscaler(t, mockClient)
+	autoscaler.config.MaxObservationAge = time.Minute
+	autoscaler.config.WaitForActiveTimeout = 20 * time.Millisecond
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(observedCapacity(2, 10*time.Minute), nil)
+
+	err := autoscaler.ScaleToTarget(ctx, 3)
+
+	assert.ErrorIs(t, err, ErrStaleObservation)
+	mockClient.AssertNotCalled(t, "AddCapacity", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetStatus_ObservationAge(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createTestAutoscaler(t, mockClient)
+	autoscaler.config.MaxObservationAge = time.Minute
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(observedCapacity(2, 30*time.Second), nil).Once()
+	status, err := autoscaler.GetStatus(ctx)
+	require.NoError(t, err)
+	assert.InDelta(t, 30*time.Second, status.ObservationAge, float64(time.Second))
+	assert.False(t, status.ObservationStale)
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(observedCapacity(2, 2*time.Minute), nil).Once()
+	status, err = autoscaler.GetStatus(ctx)
+	require.NoError(t, err)
+	assert.True(t, status.ObservationStale)
+
+	// Observations without a timestamp are never stale
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(2), nil).Once()
+	status, err = autoscaler.GetStatus(ctx)
+	require.NoError(t, err)
+	assert.False(t, status.ObservationStale)
+	assert.True(t, status.LastObservedTime.IsZero())
+}
+
+func TestRequestScale_RecordsPrincipal(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createTestAutoscaler(t, mockClient)
+	autoscaler.config.CooldownDuration = time.Hour
+	autoscaler.lastActionTime = time.Now()
+	ctx := WithPrincipal(context.Background(), "alice")
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(omnistrate_api.ResourceInstanceCapacity{
+		Status:          omnistrate_api.ACTIVE,
+		ResourceAlias:   "test-resource",
+		CurrentCapacity: 3,
+	}, nil).Twice()
+
+	// The cooldown period is not waited for when it is overridden
+	err := autoscaler.RequestScale(ctx, 3, true)
+
+	assert.NoError(t, err)
+	decisions := autoscaler.Decisions()
+	assert.Len(t, decisions, 1)
+	assert.Equal(t, "alice", decisions[0].Principal)
+	assert.Equal(t, ActionScale, decisions[0].Action)
+	assert.Equal(t, 3, decisions[0].CurrentCapacity)
+	assert.Equal(t, 3, decisions[0].DesiredCapacity)
+	assert.Contains(t, decisions[0].Reason, "cooldown period overridden")
+	mockClient.AssertExpectations(t)
+
+	// Decisions of the autoscaler itself name it as the principal
+	decision := autoscaler.Evaluate(context.Background())
+	assert.Equal(t, PrincipalAutoscaler, decision.Principal)
+}
+
+func TestRequestScale_RecordsError(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createTestAutoscaler(t, mockClient)
+	ctx := WithPrincipal(context.Background(), "alice")
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(2), nil).Twice()
+	mockClient.On("AddCapacity", ctx, "test-resource", uint(1)).Return(omnistrate_api.ResourceInstance{}, errors.New("sidecar unavailable")).Once()
+
+	err := autoscaler.RequestScale(ctx, 3, false)
+
+	// The decision records how the requested operation ended
+	assert.ErrorContains(t, err, "sidecar unavailable")
+	decisions := autoscaler.Decisions()
+	if assert.Len(t, decisions, 1) {
+		assert.Equal(t, ActionScale, decisions[0].Action)
+		assert.Equal(t, err.Error(), decisions[0].Error)
+	}
+	mockClient.AssertExpectations(t)
+}
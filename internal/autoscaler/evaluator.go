@@ -0,0 +1,402 @@
+package autoscaler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/metrics"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/policy"
+)
+
+// Decision actions
+const (
+	ActionScale     = "scale"
+	ActionHold      = "hold"
+	ActionSkip      = "skip"
+	ActionWake      = "wake"
+	ActionRecommend = "recommend"
+)
+
+// decisionHistorySize bounds how many decisions are kept for inspection
+const decisionHistorySize = 100
+
+// PrincipalAutoscaler is the principal of the decisions the autoscaler makes
+// on its own, such as policy evaluations and wakes by the proxy
+const PrincipalAutoscaler = "autoscaler"
+
+// Decision records the outcome of a single policy evaluation together with the
+// reasoning of the policy that produced it. Principal names who the decision
+// was made for: the caller of a scaling request, or the autoscaler itself.
+type Decision struct {
+	Time            time.Time          `json:"time"`
+	Principal       string             `json:"principal"`
+	Policy          string             `json:"policy,omitempty"`
+	CurrentCapacity int                `json:"currentCapacity"`
+	DesiredCapacity int                `json:"desiredCapacity"`
+	Metrics         map[string]float64 `json:"metrics,omitempty"`
+	Action          string             `json:"action"`
+	Reason          string             `json:"reason"`
+	Details         map[string]float64 `json:"details,omitempty"`
+	Error           string             `json:"error,omitempty"`
+}
+
+// Run evaluates the configured policy on every evaluation interval until the
+// context is cancelled. While no policy is configured, scaling is driven by
+// API requests only.
+func (a *Autoscaler) Run(ctx context.Context) {
+	interval := a.settings().EvaluationInterval
+	if scalingPolicy := a.scalingPolicy(); scalingPolicy != nil {
+		logger.Info().
+			Str("policy", scalingPolicy.Name()).
+			Dur("interval", interval).
+			Msg("Starting policy evaluation loop")
+	} else {
+		logger.Info().Msg("No scaling policy configured, scaling is driven by API requests only")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Let a scaling operation started by an evaluation see the
+			// cancellation and finish
+			a.backgroundScales.Wait()
+			return
+		case <-ticker.C:
+			// Pick up an interval changed by a configuration reload
+			if reloaded := a.settings().EvaluationInterval; reloaded != interval {
+				interval = reloaded
+				ticker.Reset(interval)
+				logger.Info().Dur("interval", interval).Msg("Changed policy evaluation interval")
+			}
+			// A policy configured by a reload is picked up on the next tick
+			if a.scalingPolicy() == nil {
+				continue
+			}
+			decision := a.evaluate(ctx, a.scaleInBackground)
+			if decision.Error != "" {
+				logger.Warn().Str("error", decision.Error).Str("action", decision.Action).Msg("Policy evaluation failed")
+			}
+		}
+	}
+}
+
+// scaleFunc carries out the scaling operation an evaluation decided on,
+// recording its outcome in the decision. An operation that outlives the
+// evaluation is returned as a function to run once the decision is recorded,
+// so that its outcome can be added to the recorded decision.
+type scaleFunc func(ctx context.Context, decision *Decision, wake bool) func()
+
+// Evaluate reads the metric sources, asks the policy for a desired capacity and
+// scales towards it when the autoscaler is idle and outside the cooldown period.
+// The scaling operation completes before Evaluate returns.
+func (a *Autoscaler) Evaluate(ctx context.Context) Decision {
+	return a.evaluate(ctx, a.scaleNow)
+}
+
+// evaluate runs a policy evaluation, handing a decision to scale to scale
+func (a *Autoscaler) evaluate(ctx context.Context, scale scaleFunc) Decision {
+	cfg := a.settings()
+	scalingPolicy := a.scalingPolicy()
+	decision := Decision{Time: a.now(), Principal: principalOf(ctx), Action: ActionSkip}
+	var input *RecommendationInput
+	var shadowResults []shadowResult
+	var background func()
+	defer func() {
+		a.recordDecision(decision)
+		if input != nil {
+			a.recordRecommendation(decision, *input)
+		}
+		if shadowResults != nil {
+			a.recordShadows(decision, shadowResults)
+		}
+		if background != nil {
+			background()
+		}
+	}()
+
+	if scalingPolicy == nil {
+		decision.Reason = "no scaling policy configured"
+		return decision
+	}
+	decision.Policy = scalingPolicy.Name()
+
+	if err := a.checkCircuit(); err != nil {
+		decision.Reason = "circuit breaker is open"
+		decision.Error = err.Error()
+		return decision
+	}
+
+	capacity, err := a.getCurrentCapacity(ctx)
+	if err != nil {
+		decision.Reason = "failed to get current capacity"
+		if errors.Is(err, omnistrate_api.ErrNotFound) {
+			decision.Reason = fmt.Sprintf("sidecar does not know resource %s", cfg.TargetResource)
+		}
+		decision.Error = err.Error()
+		return decision
+	}
+	decision.CurrentCapacity = capacity.CurrentCapacity
+	decision.DesiredCapacity = capacity.CurrentCapacity
+	if err := a.checkObservation(cfg, capacity); err != nil {
+		decision.Reason = "capacity observation is too old to scale from"
+		decision.Error = err.Error()
+		return decision
+	}
+	switch a.getLifecycle().policy(capacity.Status) {
+	case StatePolicyReady:
+	case StatePolicyZero:
+		zero := *capacity
+		zero.CurrentCapacity = 0
+		capacity = &zero
+		decision.CurrentCapacity = 0
+		decision.DesiredCapacity = 0
+	default:
+		decision.Reason = fmt.Sprintf("instance is %s", capacity.Status)
+		return decision
+	}
+
+	a.mu.RLock()
+	lastAction := a.lastActionTime
+	recentMetrics := a.recentMetrics()
+	a.mu.RUnlock()
+	var cooldownRemaining time.Duration
+	if !lastAction.IsZero() && a.since(lastAction) < cfg.CooldownDuration {
+		cooldownRemaining = cfg.CooldownDuration - a.since(lastAction)
+	}
+
+	decision.Metrics = a.readMetrics(ctx)
+	policyInput := policy.Input{
+		Now:               decision.Time,
+		CurrentCapacity:   capacity.CurrentCapacity,
+		MinCapacity:       cfg.MinCapacity,
+		MaxCapacity:       cfg.MaxCapacity,
+		Metrics:           decision.Metrics,
+		Capacity:          *capacity,
+		LastActionTime:    lastAction,
+		CooldownRemaining: cooldownRemaining,
+		RecentMetrics:     recentMetrics,
+	}
+	recommendation, err := scalingPolicy.Evaluate(ctx, policyInput)
+	if err != nil {
+		decision.Reason = "policy evaluation failed"
+		decision.Error = err.Error()
+		return decision
+	}
+
+	if recommendation.Policy != "" {
+		decision.Policy = recommendation.Policy
+	}
+	decision.DesiredCapacity = clamp(cfg, recommendation.DesiredCapacity)
+	decision.Reason = recommendation.Reason
+	if len(a.shadows) > 0 {
+		shadowResults = a.evaluateShadows(ctx, cfg, policyInput)
+	}
+	decision.Details = recommendation.Details
+	if decision.DesiredCapacity != recommendation.DesiredCapacity {
+		decision.Reason += fmt.Sprintf("; bounded from %d to %d", recommendation.DesiredCapacity, decision.DesiredCapacity)
+	}
+	input = &RecommendationInput{
+		Status:            capacity.Status,
+		MinCapacity:       cfg.MinCapacity,
+		MaxCapacity:       cfg.MaxCapacity,
+		CooldownRemaining: cooldownRemaining,
+		Metrics:           decision.Metrics,
+		Details:           decision.Details,
+	}
+
+	if decision.DesiredCapacity == capacity.CurrentCapacity {
+		decision.Action = ActionHold
+		return decision
+	}
+
+	if cfg.RecommendOnly {
+		logger.Info().
+			Str("policy", decision.Policy).
+			Int("currentCapacity", decision.CurrentCapacity).
+			Int("desiredCapacity", decision.DesiredCapacity).
+			Str("reason", decision.Reason).
+			Msg("Policy recommends scaling, not acting in recommend-only mode")
+		decision.Action = ActionRecommend
+		return decision
+	}
+
+	// The decision is still recorded while a scaling operation runs, but no
+	// other one is started
+	a.mu.RLock()
+	inProgress := a.scalingInProgress
+	a.mu.RUnlock()
+	if inProgress {
+		decision.Action = ActionSkip
+		decision.Reason += "; scaling operation already in progress"
+		return decision
+	}
+
+	if recommendation.Wake {
+		logger.Info().
+			Int("desiredCapacity", decision.DesiredCapacity).
+			Str("reason", decision.Reason).
+			Msg("Policy requested wake from zero")
+		decision.Action = ActionWake
+		background = scale(ctx, &decision, true)
+		return decision
+	}
+
+	if cooldownRemaining > 0 {
+		decision.Action = ActionHold
+		decision.Reason += "; within cooldown period"
+		return decision
+	}
+
+	logger.Info().
+		Str("policy", decision.Policy).
+		Int("currentCapacity", decision.CurrentCapacity).
+		Int("desiredCapacity", decision.DesiredCapacity).
+		Str("reason", decision.Reason).
+		Msg("Policy requested scaling")
+	decision.Action = ActionScale
+	background = scale(ctx, &decision, false)
+	return decision
+}
+
+// scaleNow scales to the desired capacity of the decision and records the
+// error, if any, in the decision
+func (a *Autoscaler) scaleNow(ctx context.Context, decision *Decision, wake bool) func() {
+	if err := a.scaleToTarget(ctx, decision.DesiredCapacity, wake); err != nil {
+		decision.Error = err.Error()
+	}
+	return nil
+}
+
+// scaleInBackground scales to the desired capacity of the decision without
+// waiting for the operation, so that the evaluation loop keeps its schedule
+// while the instance scales. Only one such operation runs at a time; when it
+// fails, the error is added to the recorded decision and recommendation.
+func (a *Autoscaler) scaleInBackground(ctx context.Context, decision *Decision, wake bool) func() {
+	a.mu.Lock()
+	if a.backgroundScaling {
+		a.mu.Unlock()
+		decision.Action = ActionSkip
+		decision.Reason += "; scaling operation already in progress"
+		return nil
+	}
+	a.backgroundScaling = true
+	a.mu.Unlock()
+
+	started := *decision
+	a.backgroundScales.Add(1)
+	return func() {
+		go func() {
+			defer a.backgroundScales.Done()
+			defer func() {
+				a.mu.Lock()
+				a.backgroundScaling = false
+				a.mu.Unlock()
+			}()
+			if err := a.scaleToTarget(ctx, started.DesiredCapacity, wake); err != nil {
+				logger.Warn().Err(err).Int("targetCapacity", started.DesiredCapacity).Msg("Scaling requested by policy failed")
+				a.recordScaleError(started, err)
+			}
+		}()
+	}
+}
+
+// recordScaleError adds the error of a scaling operation that completed after
+// its decision was recorded to the decision and the recommendation behind it
+func (a *Autoscaler) recordScaleError(decision Decision, err error) {
+	decision.Error = err.Error()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i := len(a.decisions) - 1; i >= 0; i-- {
+		if a.decisions[i].Time.Equal(decision.Time) && a.decisions[i].Principal == decision.Principal {
+			a.decisions[i].Error = decision.Error
+			break
+		}
+	}
+	// Readers hold on to the latest decision and recommendation, so they are
+	// replaced rather than changed
+	if a.lastDecision != nil && a.lastDecision.Time.Equal(decision.Time) {
+		last := *a.lastDecision
+		last.Error = decision.Error
+		a.lastDecision = &last
+	}
+	if a.recommendation != nil && a.recommendation.Time.Equal(decision.Time) {
+		recommendation := *a.recommendation
+		recommendation.Explanation = explain(decision, recommendation.Inputs)
+		a.recommendation = &recommendation
+	}
+}
+
+// Decisions returns the most recent decisions, oldest first
+func (a *Autoscaler) Decisions() []Decision {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return append([]Decision(nil), a.decisions...)
+}
+
+// recordDecision stores a decision as the latest one and appends it to the
+// bounded history
+func (a *Autoscaler) recordDecision(decision Decision) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastDecision = &decision
+	a.decisions = append(a.decisions, decision)
+	if len(a.decisions) > decisionHistorySize {
+		a.decisions = a.decisions[len(a.decisions)-decisionHistorySize:]
+	}
+}
+
+// recentMetrics returns the readings of previous evaluations, oldest first.
+// The caller must hold the lock.
+func (a *Autoscaler) recentMetrics() []policy.MetricSample {
+	var samples []policy.MetricSample
+	for _, decision := range a.decisions {
+		if len(decision.Metrics) > 0 {
+			samples = append(samples, policy.MetricSample{Time: decision.Time, Values: decision.Metrics})
+		}
+	}
+	return samples
+}
+
+// readMetrics collects the current value of every source that has data
+func (a *Autoscaler) readMetrics(ctx context.Context) map[string]float64 {
+	values := make(map[string]float64, len(a.sources))
+	for _, source := range a.sources {
+		value, err := source.Read(ctx)
+		if err != nil {
+			if errors.Is(err, metrics.ErrNoData) {
+				logger.Debug().Str("source", source.Name()).Msg("Metric source has no data yet")
+			} else {
+				logger.Warn().Err(err).Str("source", source.Name()).Msg("Failed to read metric source")
+			}
+			continue
+		}
+		values[source.Name()] = value
+	}
+	return values
+}
+
+// scalingPolicy returns the policy in effect, which a reload can replace
+func (a *Autoscaler) scalingPolicy() policy.Policy {
+	a.configMu.RLock()
+	defer a.configMu.RUnlock()
+	return a.policy
+}
+
+// clamp keeps a desired capacity within the configured bounds
+func clamp(cfg *config.Config, capacity int) int {
+	if capacity < cfg.MinCapacity {
+		capacity = cfg.MinCapacity
+	}
+	if cfg.MaxCapacity > 0 && capacity > cfg.MaxCapacity {
+		capacity = cfg.MaxCapacity
+	}
+	return capacity
+}
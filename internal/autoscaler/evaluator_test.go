@@ -0,0 +1,471 @@
+package autoscaler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/metrics"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// staticSource is a metric source that always reports the same reading
+type staticSource struct {
+	name  string
+	value float64
+	err   error
+}
+
+func (s *staticSource) Name() string {
+	return s.name
+}
+
+func (s *staticSource) Read(ctx context.Context) (float64, error) {
+	return s.value, s.err
+}
+
+func createTestEvaluator(t *testing.T, client omnistrate_api.Client, utilization float64) *Autoscaler {
+	autoscaler := createFastTestAutoscaler(t, client)
+	targetTracking, err := policy.NewTargetTracking(config.PolicyConfig{Metric: "cpu", Target: 0.5})
+	require.NoError(t, err)
+	autoscaler.policy = targetTracking
+	autoscaler.sources = []metrics.Source{&staticSource{name: "cpu", value: utilization}}
+	return autoscaler
+}
+
+// createFastTestAutoscaler polls for the ACTIVE state every millisecond to keep tests short
+func createFastTestAutoscaler(t *testing.T, client omnistrate_api.Client) *Autoscaler {
+	autoscaler := createTestAutoscaler(t, client)
+	autoscaler.config.WaitForActiveCheckInterval = time.Millisecond
+	return autoscaler
+}
+
+func activeCapacity(capacity int) omnistrate_api.ResourceInstanceCapacity {
+	return omnistrate_api.ResourceInstanceCapacity{
+		InstanceID:      "test-instance",
+		Status:          omnistrate_api.ACTIVE,
+		ResourceID:      "test-resource-id",
+		ResourceAlias:   "test-resource",
+		CurrentCapacity: capacity,
+	}
+}
+
+func TestEvaluate_ScalesTowardsPolicy(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createTestEvaluator(t, mockClient, 1.0)
+	ctx := context.Background()
+
+	// Evaluation observes 2 replicas at twice the target utilization
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(2), nil).Once()
+	// ScaleToTarget then walks from 2 to 4
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(2), nil).Once()
+	mockClient.On("AddCapacity", ctx, "test-resource", uint(1)).Return(omnistrate_api.ResourceInstance{}, nil).Twice()
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(3), nil).Once()
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(4), nil).Once()
+
+	decision := autoscaler.Evaluate(ctx)
+
+	assert.Equal(t, ActionScale, decision.Action)
+	assert.Equal(t, 2, decision.CurrentCapacity)
+	assert.Equal(t, 4, decision.DesiredCapacity)
+	assert.Equal(t, map[string]float64{"cpu": 1.0}, decision.Metrics)
+	assert.Empty(t, decision.Error)
+	mockClient.AssertExpectations(t)
+}
+
+func TestEvaluate_HoldsAtTarget(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createTestEvaluator(t, mockClient, 0.5)
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(3), nil).Once()
+
+	decision := autoscaler.Evaluate(ctx)
+
+	assert.Equal(t, ActionHold, decision.Action)
+	assert.Equal(t, 3, decision.DesiredCapacity)
+	mockClient.AssertExpectations(t)
+}
+
+func TestEvaluate_RespectsBounds(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createTestEvaluator(t, mockClient, 5.0)
+	autoscaler.config.MaxCapacity = 3
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(3), nil).Once()
+
+	decision := autoscaler.Evaluate(ctx)
+
+	assert.Equal(t, ActionHold, decision.Action)
+	assert.Equal(t, 3, decision.DesiredCapacity)
+	assert.Contains(t, decision.Reason, "bounded from 30 to 3")
+	mockClient.AssertExpectations(t)
+}
+
+func TestEvaluate_HoldsDuringCooldown(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createTestEvaluator(t, mockClient, 1.0)
+	autoscaler.config.CooldownDuration = time.Minute
+	autoscaler.lastActionTime = time.Now()
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(2), nil).Once()
+
+	decision := autoscaler.Evaluate(ctx)
+
+	assert.Equal(t, ActionHold, decision.Action)
+	assert.Equal(t, 4, decision.DesiredCapacity)
+	assert.Contains(t, decision.Reason, "cooldown")
+	mockClient.AssertExpectations(t)
+}
+
+func TestEvaluate_SkipsStaleObservation(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createTestEvaluator(t, mockClient, 1.0)
+	autoscaler.config.MaxObservationAge = time.Minute
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(observedCapacity(2, 5*time.Minute), nil).Once()
+
+	decision := autoscaler.Evaluate(ctx)
+
+	assert.Equal(t, ActionSkip, decision.Action)
+	assert.Equal(t, 2, decision.DesiredCapacity)
+	assert.Contains(t, decision.Error, ErrStaleObservation.Error())
+	mockClient.AssertExpectations(t)
+}
+
+func TestEvaluate_SkipsWhenNotActive(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createTestEvaluator(t, mockClient, 1.0)
+	ctx := context.Background()
+
+	starting := activeCapacity(2)
+	starting.Status = omnistrate_api.STARTING
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(starting, nil).Once()
+
+	decision := autoscaler.Evaluate(ctx)
+
+	assert.Equal(t, ActionSkip, decision.Action)
+	assert.Contains(t, decision.Reason, "STARTING")
+	mockClient.AssertExpectations(t)
+}
+
+func TestEvaluate_MissingMetric(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createTestEvaluator(t, mockClient, 1.0)
+	autoscaler.sources = []metrics.Source{&staticSource{name: "cpu", err: metrics.ErrNoData}}
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(2), nil).Once()
+
+	decision := autoscaler.Evaluate(ctx)
+
+	assert.Equal(t, ActionSkip, decision.Action)
+	assert.Contains(t, decision.Error, "metric cpu is not available")
+	mockClient.AssertExpectations(t)
+}
+
+func TestGetStatus_IncludesLastDecision(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createTestEvaluator(t, mockClient, 0.5)
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(3), nil)
+
+	autoscaler.Evaluate(ctx)
+	status, err := autoscaler.GetStatus(ctx)
+
+	require.NoError(t, err)
+	require.NotNil(t, status.LastDecision)
+	assert.Equal(t, ActionHold, status.LastDecision.Action)
+}
+
+func TestWake_BypassesCooldown(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createFastTestAutoscaler(t, mockClient)
+	autoscaler.config.CooldownDuration = time.Hour
+	autoscaler.lastActionTime = time.Now()
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(0), nil).Twice()
+	mockClient.On("AddCapacity", ctx, "test-resource", uint(1)).Return(omnistrate_api.ResourceInstance{}, nil).Once()
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(1), nil).Once()
+
+	err := autoscaler.Wake(ctx, "test")
+
+	assert.NoError(t, err)
+	require.NotNil(t, autoscaler.lastDecision)
+	assert.Equal(t, ActionWake, autoscaler.lastDecision.Action)
+	mockClient.AssertExpectations(t)
+}
+
+func TestWake_NoopWithCapacity(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createFastTestAutoscaler(t, mockClient)
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(2), nil).Once()
+
+	err := autoscaler.Wake(ctx, "test")
+
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestEvaluate_IdlePolicyWakesDuringCooldown(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createFastTestAutoscaler(t, mockClient)
+	idle, err := policy.NewIdle(nil, config.IdleConfig{Metric: "rps", Period: time.Minute, WakeCapacity: 1})
+	require.NoError(t, err)
+	autoscaler.policy = idle
+	autoscaler.sources = []metrics.Source{&staticSource{name: "rps", value: 5}}
+	autoscaler.config.CooldownDuration = time.Hour
+	autoscaler.lastActionTime = time.Now()
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(0), nil).Twice()
+	mockClient.On("AddCapacity", ctx, "test-resource", uint(1)).Return(omnistrate_api.ResourceInstance{}, nil).Once()
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(1), nil).Once()
+
+	decision := autoscaler.Evaluate(ctx)
+
+	assert.Equal(t, ActionWake, decision.Action)
+	assert.Equal(t, policy.TypeIdle, decision.Policy)
+	assert.Equal(t, 1, decision.DesiredCapacity)
+	assert.Empty(t, decision.Error)
+	mockClient.AssertExpectations(t)
+}
+
+func TestObservedCapacity_UsesCachedObservation(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createFastTestAutoscaler(t, mockClient)
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", mock.Anything, "test-resource").Return(activeCapacity(2), nil).Once()
+
+	first, err := autoscaler.ObservedCapacity(ctx, time.Minute)
+	require.NoError(t, err)
+	second, err := autoscaler.ObservedCapacity(ctx, time.Minute)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, first.CurrentCapacity)
+	assert.Equal(t, first, second)
+	mockClient.AssertExpectations(t)
+}
+
+func TestObservedCapacity_SharesConcurrentRefresh(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createFastTestAutoscaler(t, mockClient)
+	ctx := context.Background()
+
+	// Concurrent callers with an expired observation query the sidecar once
+	mockClient.On("GetCurrentCapacity", mock.Anything, "test-resource").
+		Return(activeCapacity(2), nil).After(50 * time.Millisecond).Once()
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			capacity, err := autoscaler.ObservedCapacity(ctx, time.Second)
+			assert.NoError(t, err)
+			assert.Equal(t, 2, capacity.CurrentCapacity)
+		}()
+	}
+	wg.Wait()
+
+	mockClient.AssertExpectations(t)
+}
+
+func TestEvaluate_RecordsPolicyReasoning(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createFastTestAutoscaler(t, mockClient)
+	slo, err := policy.NewSLO(config.PolicyConfig{Metric: "slow-requests", SLO: config.SLOConfig{
+		Objective: 0.95, ShortWindow: 5 * time.Minute, LongWindow: time.Hour,
+		ShortBurnRate: 2, LongBurnRate: 1, ScaleDownBurnRate: 0.25, Step: 1,
+	}})
+	require.NoError(t, err)
+	autoscaler.policy = slo
+	autoscaler.sources = []metrics.Source{&staticSource{name: "slow-requests", value: 0.05}}
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(2), nil).Twice()
+
+	first := autoscaler.Evaluate(ctx)
+	second := autoscaler.Evaluate(ctx)
+
+	assert.Equal(t, ActionHold, second.Action)
+	assert.InDelta(t, 1.0, second.Details["shortBurnRate"], 0.0001)
+	assert.Equal(t, []Decision{first, second}, autoscaler.Decisions())
+	mockClient.AssertExpectations(t)
+}
+
+func TestEvaluate_RecommendOnlyNeverScales(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createTestEvaluator(t, mockClient, 1.0)
+	autoscaler.config.RecommendOnly = true
+	ctx := context.Background()
+
+	// Only the observation is expected, AddCapacity must not be called
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(2), nil).Once()
+
+	decision := autoscaler.Evaluate(ctx)
+
+	assert.Equal(t, ActionRecommend, decision.Action)
+	assert.Equal(t, 4, decision.DesiredCapacity)
+	mockClient.AssertExpectations(t)
+	mockClient.AssertNotCalled(t, "AddCapacity", mock.Anything, mock.Anything, mock.Anything)
+
+	recommendation := autoscaler.LatestRecommendation()
+	require.NotNil(t, recommendation)
+	assert.True(t, recommendation.RecommendOnly)
+	assert.Equal(t, 4, recommendation.DesiredCapacity)
+	assert.Equal(t, map[string]float64{"cpu": 1.0}, recommendation.Inputs.Metrics)
+	assert.Equal(t, omnistrate_api.ACTIVE, recommendation.Inputs.Status)
+	assert.Contains(t, recommendation.Explanation, "recommends 4 replicas (currently 2)")
+	assert.Contains(t, recommendation.Explanation, "did not add 2 replicas")
+}
+
+func TestWake_RecommendOnlyDoesNotScale(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createFastTestAutoscaler(t, mockClient)
+	autoscaler.config.RecommendOnly = true
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(0), nil).Once()
+
+	err := autoscaler.Wake(ctx, "test")
+
+	require.NoError(t, err)
+	assert.Equal(t, ActionRecommend, autoscaler.lastDecision.Action)
+	mockClient.AssertExpectations(t)
+}
+
+func TestEvaluate_ExplainsCooldownHold(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createTestEvaluator(t, mockClient, 0.25)
+	autoscaler.config.CooldownDuration = time.Minute
+	autoscaler.lastActionTime = time.Now()
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(4), nil).Once()
+
+	decision := autoscaler.Evaluate(ctx)
+
+	assert.Equal(t, ActionHold, decision.Action)
+	recommendation := autoscaler.LatestRecommendation()
+	require.NotNil(t, recommendation)
+	assert.False(t, recommendation.RecommendOnly)
+	assert.Contains(t, recommendation.Explanation, "will remove 2 replicas once the cooldown period ends")
+}
+
+// countingSource is a metric source that counts how often it is read
+type countingSource struct {
+	staticSource
+	reads atomic.Int32
+}
+
+func (s *countingSource) Read(ctx context.Context) (float64, error) {
+	s.reads.Add(1)
+	return s.staticSource.Read(ctx)
+}
+
+func TestEvaluate_ReadsMetricsWhileScalingInProgress(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createTestEvaluator(t, mockClient, 1.0)
+	autoscaler.scalingInProgress = true
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(2), nil).Once()
+
+	decision := autoscaler.Evaluate(ctx)
+
+	assert.Equal(t, ActionSkip, decision.Action)
+	assert.Equal(t, 4, decision.DesiredCapacity)
+	assert.Contains(t, decision.Reason, "scaling operation already in progress")
+	assert.Equal(t, map[string]float64{"cpu": 1.0}, decision.Metrics)
+	mockClient.AssertExpectations(t)
+}
+
+func TestRun_EvaluatesWhileScaling(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createTestEvaluator(t, mockClient, 1.0)
+	autoscaler.config.EvaluationInterval = 10 * time.Millisecond
+	autoscaler.config.WaitForActiveCheckInterval = time.Millisecond
+	source := &countingSource{staticSource: staticSource{name: "cpu", value: 1.0}}
+	autoscaler.sources = []metrics.Source{source}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Adding capacity blocks until released, as if the instance took long to scale
+	release := make(chan time.Time)
+	mockClient.On("GetCurrentCapacity", mock.Anything, "test-resource").Return(activeCapacity(2), nil)
+	mockClient.On("AddCapacity", mock.Anything, "test-resource", uint(1)).
+		Return(omnistrate_api.ResourceInstance{}, nil).WaitUntil(release)
+
+	done := make(chan struct{})
+	go func() {
+		autoscaler.Run(ctx)
+		close(done)
+	}()
+
+	// Evaluations keep running on schedule while the operation is blocked,
+	// without starting another one
+	assert.Eventually(t, func() bool {
+		return source.reads.Load() >= 5
+	}, 5*time.Second, 10*time.Millisecond)
+	mockClient.AssertNumberOfCalls(t, "AddCapacity", 1)
+
+	cancel()
+	close(release)
+	<-done
+}
+
+func TestRun_RecordsBackgroundScaleError(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createTestEvaluator(t, mockClient, 1.0)
+	autoscaler.config.EvaluationInterval = 10 * time.Millisecond
+	autoscaler.config.CooldownDuration = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mockClient.On("GetCurrentCapacity", mock.Anything, "test-resource").Return(activeCapacity(2), nil)
+	mockClient.On("AddCapacity", mock.Anything, "test-resource", uint(1)).
+		Return(omnistrate_api.ResourceInstance{}, errors.New("sidecar unavailable"))
+
+	done := make(chan struct{})
+	go func() {
+		autoscaler.Run(ctx)
+		close(done)
+	}()
+
+	// The failure of the operation started by the decision is added to it
+	// once the operation completes
+	var scaled Decision
+	assert.Eventually(t, func() bool {
+		for _, decision := range autoscaler.Decisions() {
+			if decision.Action == ActionScale && decision.Error != "" {
+				scaled = decision
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Contains(t, scaled.Error, "sidecar unavailable")
+
+	cancel()
+	<-done
+	for _, decision := range autoscaler.Decisions() {
+		if decision.Time.Equal(scaled.Time) {
+			assert.Equal(t, scaled.Error, decision.Error)
+		}
+	}
+}
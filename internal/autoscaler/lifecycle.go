@@ -0,0 +1,192 @@
+package autoscaler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
+)
+
+// ErrInstanceState is returned when the instance is in a state whose policy is
+// to abort scaling
+var ErrInstanceState = errors.New("instance cannot be scaled in its current state")
+
+// State policies decide how the autoscaler reacts to an instance status
+const (
+	// StatePolicyReady scales from the observed capacity. It only applies to ACTIVE.
+	StatePolicyReady = "ready"
+	// StatePolicyWait keeps polling until the instance leaves the state
+	StatePolicyWait = "wait"
+	// StatePolicyAbort fails scaling operations immediately
+	StatePolicyAbort = "abort"
+	// StatePolicyAlert keeps polling like wait, and logs an error when the
+	// instance enters the state
+	StatePolicyAlert = "alert"
+	// StatePolicyZero treats the instance as running with zero capacity, so
+	// scaling proceeds from zero
+	StatePolicyZero = "zero"
+)
+
+// otherStates is the state policy key that applies to statuses the autoscaler
+// does not know, such as ones introduced by newer platform versions
+const otherStates = "OTHER"
+
+// transitionHistorySize bounds how many state transitions are kept for inspection
+const transitionHistorySize = 100
+
+// defaultStatePolicies is how each instance status is handled unless configured
+// otherwise. A PAUSED instance does not resume on its own, so waiting for it
+// would only run into the timeout.
+var defaultStatePolicies = map[string]string{
+	string(omnistrate_api.STARTING): StatePolicyWait,
+	string(omnistrate_api.PAUSED):   StatePolicyAbort,
+	string(omnistrate_api.FAILED):   StatePolicyAbort,
+	string(omnistrate_api.UNKNOWN):  StatePolicyWait,
+	otherStates:                     StatePolicyAlert,
+}
+
+// StateTransition records a change of the observed instance status
+type StateTransition struct {
+	Time   time.Time             `json:"time"`
+	From   omnistrate_api.Status `json:"from,omitempty"`
+	To     omnistrate_api.Status `json:"to"`
+	Policy string                `json:"policy"`
+	Known  bool                  `json:"known"`
+}
+
+// lifecycle tracks the observed status of the instance and decides how each
+// status is handled
+type lifecycle struct {
+	mu          sync.Mutex
+	policies    map[string]string
+	current     omnistrate_api.Status
+	since       time.Time
+	transitions []StateTransition
+}
+
+// newLifecycle builds a lifecycle from the configured state policies, which
+// override the defaults per status
+func newLifecycle(configured map[string]string) (*lifecycle, error) {
+	policies, err := statePolicies(configured)
+	if err != nil {
+		return nil, err
+	}
+	return &lifecycle{policies: policies}, nil
+}
+
+// statePolicies merges the configured state policies into the defaults
+func statePolicies(configured map[string]string) (map[string]string, error) {
+	policies := make(map[string]string, len(defaultStatePolicies))
+	for state, statePolicy := range defaultStatePolicies {
+		policies[state] = statePolicy
+	}
+	for state, statePolicy := range configured {
+		state = strings.ToUpper(state)
+		if state == string(omnistrate_api.ACTIVE) {
+			return nil, fmt.Errorf("the policy of the ACTIVE state cannot be changed")
+		}
+		switch statePolicy {
+		case StatePolicyWait, StatePolicyAbort, StatePolicyAlert, StatePolicyZero:
+		default:
+			return nil, fmt.Errorf("unknown state policy %q for state %s, must be one of wait, abort, alert or zero", statePolicy, state)
+		}
+		policies[state] = statePolicy
+	}
+	return policies, nil
+}
+
+// setPolicies replaces the state policies, which apply from the next
+// observed status on
+func (l *lifecycle) setPolicies(configured map[string]string) error {
+	policies, err := statePolicies(configured)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.policies = policies
+	return nil
+}
+
+// isKnown reports whether a status is one the autoscaler models
+func isKnown(status omnistrate_api.Status) bool {
+	switch status {
+	case omnistrate_api.ACTIVE, omnistrate_api.STARTING, omnistrate_api.PAUSED, omnistrate_api.FAILED, omnistrate_api.UNKNOWN:
+		return true
+	}
+	return false
+}
+
+// policy returns how a status is handled. Statuses without a policy of their
+// own fall back to the policy for other states.
+func (l *lifecycle) policy(status omnistrate_api.Status) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lookup(status)
+}
+
+// lookup returns how a status is handled. The caller must hold the lock.
+func (l *lifecycle) lookup(status omnistrate_api.Status) string {
+	if status == omnistrate_api.ACTIVE {
+		return StatePolicyReady
+	}
+	if statePolicy, ok := l.policies[string(status)]; ok {
+		return statePolicy
+	}
+	return l.policies[otherStates]
+}
+
+// observe records a status observed at the given time, returning the
+// transition when the status changed
+func (l *lifecycle) observe(status omnistrate_api.Status, now time.Time) *StateTransition {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if status == l.current && !l.since.IsZero() {
+		return nil
+	}
+
+	transition := StateTransition{
+		Time:   now,
+		From:   l.current,
+		To:     status,
+		Policy: l.lookup(status),
+		Known:  isKnown(status),
+	}
+	l.current = status
+	l.since = now
+	l.transitions = append(l.transitions, transition)
+	if len(l.transitions) > transitionHistorySize {
+		l.transitions = l.transitions[len(l.transitions)-transitionHistorySize:]
+	}
+
+	event := logger.Info()
+	if transition.Policy == StatePolicyAlert {
+		event = logger.Error()
+	}
+	event.
+		Str("from", string(transition.From)).
+		Str("to", string(transition.To)).
+		Str("policy", transition.Policy).
+		Bool("known", transition.Known).
+		Msg("Instance status changed")
+	return &transition
+}
+
+// state returns the current status, since when it has been observed and how
+// it is handled
+func (l *lifecycle) state() (omnistrate_api.Status, time.Time, string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.current, l.since, l.lookup(l.current)
+}
+
+// history returns the recorded transitions, oldest first
+func (l *lifecycle) history() []StateTransition {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]StateTransition(nil), l.transitions...)
+}
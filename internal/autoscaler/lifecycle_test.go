@@ -0,0 +1,110 @@
+package autoscaler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func capacityWithStatus(capacity int, status omnistrate_api.Status) omnistrate_api.ResourceInstanceCapacity {
+	observed := activeCapacity(capacity)
+	observed.Status = status
+	return observed
+}
+
+func TestNewLifecycle(t *testing.T) {
+	l, err := newLifecycle(map[string]string{"paused": StatePolicyZero, "OTHER": StatePolicyWait})
+	require.NoError(t, err)
+
+	assert.Equal(t, StatePolicyReady, l.policy(omnistrate_api.ACTIVE))
+	assert.Equal(t, StatePolicyWait, l.policy(omnistrate_api.STARTING))
+	assert.Equal(t, StatePolicyZero, l.policy(omnistrate_api.PAUSED))
+	assert.Equal(t, StatePolicyAbort, l.policy(omnistrate_api.FAILED))
+	assert.Equal(t, StatePolicyWait, l.policy("HIBERNATING"))
+
+	_, err = newLifecycle(map[string]string{"PAUSED": "ignore"})
+	assert.ErrorContains(t, err, "unknown state policy")
+	_, err = newLifecycle(map[string]string{"ACTIVE": StatePolicyWait})
+	assert.Error(t, err)
+}
+
+func TestLifecycle_RecordsTransitions(t *testing.T) {
+	l, err := newLifecycle(nil)
+	require.NoError(t, err)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.NotNil(t, l.observe(omnistrate_api.ACTIVE, start))
+	assert.Nil(t, l.observe(omnistrate_api.ACTIVE, start.Add(time.Minute)))
+	assert.NotNil(t, l.observe(omnistrate_api.STARTING, start.Add(2*time.Minute)))
+	assert.NotNil(t, l.observe("HIBERNATING", start.Add(3*time.Minute)))
+
+	transitions := l.history()
+	require.Len(t, transitions, 3)
+	assert.Equal(t, omnistrate_api.Status(""), transitions[0].From)
+	assert.Equal(t, omnistrate_api.ACTIVE, transitions[1].From)
+	assert.Equal(t, omnistrate_api.STARTING, transitions[1].To)
+	assert.Equal(t, StatePolicyWait, transitions[1].Policy)
+	assert.False(t, transitions[2].Known)
+	assert.Equal(t, StatePolicyAlert, transitions[2].Policy)
+
+	status, since, statePolicy := l.state()
+	assert.Equal(t, omnistrate_api.Status("HIBERNATING"), status)
+	assert.Equal(t, start.Add(3*time.Minute), since)
+	assert.Equal(t, StatePolicyAlert, statePolicy)
+}
+
+func TestScaleToTarget_PausedAbortsImmediately(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createFastTestAutoscaler(t, mockClient)
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(capacityWithStatus(2, omnistrate_api.PAUSED), nil).Once()
+
+	err := autoscaler.ScaleToTarget(ctx, 3)
+
+	assert.ErrorIs(t, err, ErrInstanceState)
+	assert.Contains(t, err.Error(), "instance is in PAUSED state")
+	mockClient.AssertExpectations(t)
+}
+
+func TestScaleToTarget_PausedAsZeroCapacity(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createFastTestAutoscaler(t, mockClient)
+	autoscaler.lifecycle, _ = newLifecycle(map[string]string{"PAUSED": StatePolicyZero})
+	ctx := context.Background()
+
+	// The paused instance reports its previous capacity, which is not serving
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(capacityWithStatus(2, omnistrate_api.PAUSED), nil).Once()
+	mockClient.On("AddCapacity", ctx, "test-resource", uint(1)).Return(omnistrate_api.ResourceInstance{}, nil).Once()
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(1), nil).Once()
+
+	err := autoscaler.ScaleToTarget(ctx, 1)
+
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestScaleToTarget_WaitsThroughUnknownStatus(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createFastTestAutoscaler(t, mockClient)
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(capacityWithStatus(2, "HIBERNATING"), nil).Once()
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(2), nil).Once()
+	mockClient.On("AddCapacity", ctx, "test-resource", uint(1)).Return(omnistrate_api.ResourceInstance{}, nil).Once()
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(3), nil).Once()
+
+	err := autoscaler.ScaleToTarget(ctx, 3)
+
+	assert.NoError(t, err)
+	mockClient.AssertNotCalled(t, "RemoveCapacity", mock.Anything, mock.Anything, mock.Anything)
+	transitions := autoscaler.Transitions()
+	require.Len(t, transitions, 2)
+	assert.Equal(t, omnistrate_api.Status("HIBERNATING"), transitions[0].To)
+	assert.Equal(t, omnistrate_api.ACTIVE, transitions[1].To)
+}
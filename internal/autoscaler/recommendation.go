@@ -0,0 +1,97 @@
+package autoscaler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
+)
+
+// Recommendation is the latest capacity recommended by the policy together
+// with the inputs it was computed from and an explanation of the outcome
+type Recommendation struct {
+	Time            time.Time           `json:"time"`
+	Policy          string              `json:"policy"`
+	RecommendOnly   bool                `json:"recommendOnly"`
+	CurrentCapacity int                 `json:"currentCapacity"`
+	DesiredCapacity int                 `json:"desiredCapacity"`
+	Action          string              `json:"action"`
+	Inputs          RecommendationInput `json:"inputs"`
+	Explanation     string              `json:"explanation"`
+}
+
+// RecommendationInput holds the values a recommendation was computed from
+type RecommendationInput struct {
+	Status            omnistrate_api.Status `json:"status"`
+	MinCapacity       int                   `json:"minCapacity"`
+	MaxCapacity       int                   `json:"maxCapacity"`
+	CooldownRemaining time.Duration         `json:"cooldownRemaining"`
+	Metrics           map[string]float64    `json:"metrics"`
+	Details           map[string]float64    `json:"details,omitempty"`
+}
+
+// LatestRecommendation returns the most recent policy recommendation, or nil
+// when the policy has not produced one yet
+func (a *Autoscaler) LatestRecommendation() *Recommendation {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.recommendation
+}
+
+// recordRecommendation stores the recommendation behind a decision
+func (a *Autoscaler) recordRecommendation(decision Decision, input RecommendationInput) {
+	recommendation := &Recommendation{
+		Time:            decision.Time,
+		Policy:          decision.Policy,
+		RecommendOnly:   a.settings().RecommendOnly,
+		CurrentCapacity: decision.CurrentCapacity,
+		DesiredCapacity: decision.DesiredCapacity,
+		Action:          decision.Action,
+		Inputs:          input,
+		Explanation:     explain(decision, input),
+	}
+	if recommendation.Inputs.Metrics == nil {
+		recommendation.Inputs.Metrics = map[string]float64{}
+	}
+	a.mu.Lock()
+	a.recommendation = recommendation
+	a.mu.Unlock()
+}
+
+// explain describes a decision in plain language
+func explain(decision Decision, input RecommendationInput) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "The %s policy recommends %s (currently %d): %s.",
+		decision.Policy, replicas(decision.DesiredCapacity), decision.CurrentCapacity, decision.Reason)
+
+	difference := decision.DesiredCapacity - decision.CurrentCapacity
+	change := fmt.Sprintf("add %s", replicas(difference))
+	if difference < 0 {
+		change = fmt.Sprintf("remove %s", replicas(-difference))
+	}
+
+	switch decision.Action {
+	case ActionRecommend:
+		fmt.Fprintf(&b, " Recommend-only mode is enabled, so the controller did not %s.", change)
+	case ActionHold:
+		if difference == 0 {
+			b.WriteString(" Capacity already matches, so no change is needed.")
+		} else {
+			fmt.Fprintf(&b, " The controller will %s once the cooldown period ends in %s.", change, input.CooldownRemaining.Round(time.Second))
+		}
+	case ActionScale, ActionWake:
+		fmt.Fprintf(&b, " The controller started to %s.", change)
+	}
+	if decision.Error != "" {
+		fmt.Fprintf(&b, " Scaling failed: %s.", decision.Error)
+	}
+	return b.String()
+}
+
+func replicas(n int) string {
+	if n == 1 {
+		return "1 replica"
+	}
+	return fmt.Sprintf("%d replicas", n)
+}
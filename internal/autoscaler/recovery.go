@@ -0,0 +1,241 @@
+package autoscaler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/audit"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/notify"
+)
+
+// ErrInstanceFailed is returned when the instance enters FAILED during a
+// scaling operation
+var ErrInstanceFailed = fmt.Errorf("instance failed: %w", ErrInstanceState)
+
+// ErrCircuitOpen is returned while the circuit breaker blocks scaling
+var ErrCircuitOpen = errors.New("scaling is blocked until the circuit breaker is reset")
+
+// Recovery policies decide how a scaling operation reacts to a FAILED instance
+const (
+	// RecoveryAbort fails the scaling operation
+	RecoveryAbort = "abort"
+	// RecoveryRetry waits for a backoff and retries the step
+	RecoveryRetry = "retry"
+	// RecoveryRollback waits for a backoff and scales back to the last
+	// capacity the instance was ACTIVE with
+	RecoveryRollback = "rollback"
+	// RecoveryFreeze opens the circuit breaker and notifies operators
+	RecoveryFreeze = "freeze"
+)
+
+// maxRecoveryBackoff bounds the wait before a recovery attempt, which doubles
+// with every attempt
+const maxRecoveryBackoff = time.Hour
+
+// RecoveryStatus reports the recovery policy and the state of the circuit breaker
+type RecoveryStatus struct {
+	Policy              string     `json:"policy"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	BreakerThreshold    int        `json:"breakerThreshold"`
+	CircuitOpen         bool       `json:"circuitOpen"`
+	OpenedAt            *time.Time `json:"openedAt,omitempty"`
+	Reason              string     `json:"reason,omitempty"`
+	LastKnownGood       *int       `json:"lastKnownGood,omitempty"`
+}
+
+// breaker counts consecutive failures of the instance and blocks scaling once
+// there are too many. The caller must hold the autoscaler lock.
+type breaker struct {
+	failures      int
+	openedAt      time.Time
+	reason        string
+	lastKnownGood *int
+}
+
+func (b *breaker) open() bool {
+	return !b.openedAt.IsZero()
+}
+
+// validateRecoveryPolicy checks that a recovery policy is known. No policy
+// means abort.
+func validateRecoveryPolicy(recoveryPolicy string) error {
+	switch recoveryPolicy {
+	case "", RecoveryAbort, RecoveryRetry, RecoveryRollback, RecoveryFreeze:
+		return nil
+	}
+	return fmt.Errorf("unknown recovery policy %q, must be one of abort, retry, rollback or freeze", recoveryPolicy)
+}
+
+// recoverFromFailure reacts to the instance entering FAILED during a scaling
+// operation according to the recovery policy. It returns nil when the
+// operation should go on, after waiting for the backoff and possibly rolling
+// the target capacity back, and otherwise the error to fail the operation with.
+func (a *Autoscaler) recoverFromFailure(ctx context.Context, cfg *config.Config, cause error, attempt int) error {
+	recovery := cfg.Recovery
+
+	a.mu.Lock()
+	a.breaker.failures++
+	failures := a.breaker.failures
+	tripped := recovery.BreakerThreshold > 0 && failures >= recovery.BreakerThreshold
+	lastKnownGood := a.breaker.lastKnownGood
+	a.mu.Unlock()
+
+	a.notify(ctx, notify.EventInstanceFailed, fmt.Sprintf("Instance of %s entered FAILED while scaling (failure %d): %v", cfg.TargetResource, failures, cause))
+
+	if tripped {
+		a.openCircuit(ctx, notify.EventCircuitOpen, fmt.Sprintf("%d consecutive failures", failures))
+		return fmt.Errorf("%w: %w", ErrCircuitOpen, cause)
+	}
+
+	switch recovery.Policy {
+	case RecoveryFreeze:
+		a.openCircuit(ctx, notify.EventFrozen, "automation frozen after the instance failed")
+		return fmt.Errorf("%w: %w", ErrCircuitOpen, cause)
+	case RecoveryRetry, RecoveryRollback:
+		if attempt >= recovery.MaxRetries {
+			return fmt.Errorf("giving up after %d recovery attempts: %w", attempt, cause)
+		}
+		if recovery.Policy == RecoveryRollback && lastKnownGood != nil {
+			a.mu.Lock()
+			a.targetCapacity = *lastKnownGood
+			a.mu.Unlock()
+			logger.Warn().Int("targetCapacity", *lastKnownGood).Msg("Rolling back to the last known-good capacity")
+		}
+		backoff := recoveryBackoff(recovery.Backoff, attempt)
+		logger.Warn().Dur("backoff", backoff).Int("attempt", attempt+1).Str("policy", recovery.Policy).Msg("Instance failed, recovering after backoff")
+		if err := a.getClock().Sleep(ctx, backoff); err != nil {
+			return fmt.Errorf("interrupted while recovering: %w", err)
+		}
+		return nil
+	default:
+		return cause
+	}
+}
+
+// recoveryBackoff is the wait before the recovery attempt after the given
+// number of earlier ones, doubled without overflowing past maxRecoveryBackoff
+func recoveryBackoff(initial time.Duration, attempt int) time.Duration {
+	backoff := initial
+	for i := 0; i < attempt && backoff < maxRecoveryBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, maxRecoveryBackoff)
+}
+
+// openCircuit blocks further scaling and notifies operators
+func (a *Autoscaler) openCircuit(ctx context.Context, eventType, reason string) {
+	a.mu.Lock()
+	if a.breaker.open() {
+		a.mu.Unlock()
+		return
+	}
+	a.breaker.openedAt = a.now()
+	a.breaker.reason = reason
+	a.mu.Unlock()
+
+	a.notify(ctx, eventType, fmt.Sprintf("Scaling of %s is blocked until the circuit breaker is reset: %s", a.settings().TargetResource, reason))
+}
+
+// checkCircuit returns ErrCircuitOpen while the circuit breaker blocks scaling
+func (a *Autoscaler) checkCircuit() error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.breaker.open() {
+		return fmt.Errorf("%w: %s", ErrCircuitOpen, a.breaker.reason)
+	}
+	return nil
+}
+
+// recordSuccess clears the failure count after a successful scaling operation
+func (a *Autoscaler) recordSuccess() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.breaker.failures = 0
+}
+
+// recordKnownGood remembers a capacity the instance was ACTIVE with
+func (a *Autoscaler) recordKnownGood(capacity int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.breaker.lastKnownGood = &capacity
+}
+
+// ResetCircuitBreaker unblocks scaling and clears the failure count. It
+// reports whether the circuit breaker was open, and records the reset by the
+// principal of the context in the audit trail.
+func (a *Autoscaler) ResetCircuitBreaker(ctx context.Context) bool {
+	a.mu.Lock()
+	wasOpen := a.breaker.open()
+	reason := a.breaker.reason
+	a.breaker.failures = 0
+	a.breaker.openedAt = time.Time{}
+	a.breaker.reason = ""
+	a.mu.Unlock()
+
+	if wasOpen {
+		a.recordAudit(audit.Entry{
+			Time:   a.now(),
+			Actor:  principalOf(ctx),
+			Action: audit.ActionCircuitReset,
+			Reason: reason,
+		})
+		a.notify(ctx, notify.EventCircuitReset, fmt.Sprintf("Scaling of %s was unblocked", a.settings().TargetResource))
+	}
+	return wasOpen
+}
+
+// RecoveryStatus reports the recovery policy and the state of the circuit breaker
+func (a *Autoscaler) RecoveryStatus() RecoveryStatus {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.recoveryStatus()
+}
+
+// recoveryStatus builds the recovery status. The caller must hold the lock.
+func (a *Autoscaler) recoveryStatus() RecoveryStatus {
+	recovery := a.settings().Recovery
+	status := RecoveryStatus{
+		Policy:              recovery.Policy,
+		ConsecutiveFailures: a.breaker.failures,
+		BreakerThreshold:    recovery.BreakerThreshold,
+		CircuitOpen:         a.breaker.open(),
+		Reason:              a.breaker.reason,
+		LastKnownGood:       a.breaker.lastKnownGood,
+	}
+	if status.CircuitOpen {
+		openedAt := a.breaker.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// newNotifier builds the notifier delivering recovery events to operators
+func newNotifier(recovery config.RecoveryConfig) notify.Notifier {
+	if recovery.NotifyURL != "" {
+		return notify.NewWebhook(recovery.NotifyURL, 10*time.Second)
+	}
+	return notify.Log{}
+}
+
+// notify delivers an event to operators, logging it when no notifier is set
+func (a *Autoscaler) notify(ctx context.Context, eventType, message string) {
+	a.configMu.RLock()
+	notifier := a.notifier
+	a.configMu.RUnlock()
+	if notifier == nil {
+		notifier = notify.Log{}
+	}
+	event := notify.Event{
+		Time:     a.now(),
+		Type:     eventType,
+		Resource: a.settings().TargetResource,
+		Message:  message,
+	}
+	if err := notifier.Notify(ctx, event); err != nil {
+		logger.Warn().Err(err).Str("type", eventType).Msg("Failed to send notification")
+	}
+}
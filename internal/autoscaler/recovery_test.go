@@ -0,0 +1,173 @@
+package autoscaler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/audit"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/notify"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingNotifier keeps the events it was asked to deliver
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []notify.Event
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, event notify.Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+	return nil
+}
+
+func (n *recordingNotifier) types() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var types []string
+	for _, event := range n.events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func createRecoveryAutoscaler(t *testing.T, client omnistrate_api.Client, recoveryPolicy string) (*Autoscaler, *recordingNotifier) {
+	autoscaler := createFastTestAutoscaler(t, client)
+	autoscaler.config.Recovery.Policy = recoveryPolicy
+	autoscaler.config.Recovery.Backoff = time.Millisecond
+	autoscaler.config.Recovery.MaxRetries = 3
+	autoscaler.config.Recovery.BreakerThreshold = 3
+	notifier := &recordingNotifier{}
+	autoscaler.notifier = notifier
+	return autoscaler, notifier
+}
+
+func failedCapacity(capacity int) omnistrate_api.ResourceInstanceCapacity {
+	return capacityWithStatus(capacity, omnistrate_api.FAILED)
+}
+
+func TestRecovery_RetryAfterBackoff(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler, notifier := createRecoveryAutoscaler(t, mockClient, RecoveryRetry)
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(failedCapacity(2), nil).Once()
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(2), nil).Once()
+	mockClient.On("AddCapacity", ctx, "test-resource", uint(1)).Return(omnistrate_api.ResourceInstance{}, nil).Once()
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(3), nil).Once()
+
+	err := autoscaler.ScaleToTarget(ctx, 3)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{notify.EventInstanceFailed}, notifier.types())
+	// A successful operation clears the failure count
+	assert.Zero(t, autoscaler.RecoveryStatus().ConsecutiveFailures)
+	mockClient.AssertExpectations(t)
+}
+
+func TestRecovery_RollbackToLastKnownGood(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler, _ := createRecoveryAutoscaler(t, mockClient, RecoveryRollback)
+	ctx := context.Background()
+
+	// The instance fails after the first step from the known-good 2 replicas
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(2), nil).Once()
+	mockClient.On("AddCapacity", ctx, "test-resource", uint(1)).Return(omnistrate_api.ResourceInstance{}, nil).Once()
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(failedCapacity(3), nil).Once()
+	// Once it recovers, the step is undone instead of scaling on to 4
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(3), nil).Once()
+	mockClient.On("RemoveCapacity", ctx, "test-resource", uint(1)).Return(omnistrate_api.ResourceInstance{}, nil).Once()
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(2), nil).Once()
+
+	err := autoscaler.ScaleToTarget(ctx, 4)
+
+	assert.NoError(t, err)
+	status := autoscaler.RecoveryStatus()
+	require.NotNil(t, status.LastKnownGood)
+	assert.Equal(t, 2, *status.LastKnownGood)
+	mockClient.AssertExpectations(t)
+}
+
+func TestRecovery_FreezeUntilReset(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler, notifier := createRecoveryAutoscaler(t, mockClient, RecoveryFreeze)
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(failedCapacity(2), nil).Once()
+
+	err := autoscaler.ScaleToTarget(ctx, 3)
+
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.ErrorIs(t, err, ErrInstanceFailed)
+	assert.Equal(t, []string{notify.EventInstanceFailed, notify.EventFrozen}, notifier.types())
+	assert.True(t, autoscaler.RecoveryStatus().CircuitOpen)
+
+	// Scaling is refused without asking the sidecar until the breaker is reset
+	err = autoscaler.ScaleToTarget(ctx, 3)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	decision := autoscaler.Evaluate(ctx)
+	assert.Equal(t, ActionSkip, decision.Action)
+	mockClient.AssertExpectations(t)
+
+	assert.True(t, autoscaler.ResetCircuitBreaker(WithPrincipal(ctx, "alice")))
+	assert.False(t, autoscaler.RecoveryStatus().CircuitOpen)
+	assert.Contains(t, notifier.types(), notify.EventCircuitReset)
+	entries := autoscaler.AuditTrail()
+	require.Len(t, entries, 1)
+	assert.Equal(t, audit.ActionCircuitReset, entries[0].Action)
+	assert.Equal(t, "alice", entries[0].Actor)
+	assert.False(t, autoscaler.ResetCircuitBreaker(ctx))
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(3), nil).Once()
+	assert.NoError(t, autoscaler.ScaleToTarget(ctx, 3))
+}
+
+func TestRecovery_ConsecutiveFailuresTripBreaker(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler, notifier := createRecoveryAutoscaler(t, mockClient, RecoveryRetry)
+	autoscaler.config.Recovery.MaxRetries = 10
+	autoscaler.config.Recovery.BreakerThreshold = 2
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(failedCapacity(2), nil).Twice()
+
+	err := autoscaler.ScaleToTarget(ctx, 3)
+
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, []string{notify.EventInstanceFailed, notify.EventInstanceFailed, notify.EventCircuitOpen}, notifier.types())
+	status := autoscaler.RecoveryStatus()
+	assert.True(t, status.CircuitOpen)
+	assert.Equal(t, 2, status.ConsecutiveFailures)
+	assert.Contains(t, status.Reason, "2 consecutive failures")
+	mockClient.AssertNotCalled(t, "AddCapacity", mock.Anything, mock.Anything, mock.Anything)
+	mockClient.AssertExpectations(t)
+}
+
+func TestRecovery_AbortByDefault(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler, _ := createRecoveryAutoscaler(t, mockClient, RecoveryAbort)
+	ctx := context.Background()
+
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(failedCapacity(2), nil).Once()
+
+	err := autoscaler.ScaleToTarget(ctx, 3)
+
+	assert.ErrorIs(t, err, ErrInstanceFailed)
+	assert.NotErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 1, autoscaler.RecoveryStatus().ConsecutiveFailures)
+	mockClient.AssertExpectations(t)
+}
+
+func TestRecoveryBackoff(t *testing.T) {
+	assert.Equal(t, time.Minute, recoveryBackoff(time.Minute, 0))
+	assert.Equal(t, 8*time.Minute, recoveryBackoff(time.Minute, 3))
+	// Many retries wait at most an hour instead of overflowing
+	assert.Equal(t, maxRecoveryBackoff, recoveryBackoff(time.Minute, 100))
+	assert.Equal(t, maxRecoveryBackoff, recoveryBackoff(2*time.Hour, 0))
+}
@@ -0,0 +1,190 @@
+package autoscaler
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/policy"
+)
+
+// restartSettings are the settings a reload cannot change, because the sidecar
+// client, the metric sources, the shadow policies, the file watch, the API
+// authentication and the API's TLS are set up from them at startup
+var restartSettings = []string{
+	"TargetResource",
+	"DryRun",
+	"Simulation",
+	"Sidecar",
+	"Proxy",
+	"MetricSources",
+	"ShadowPolicies",
+	"ConfigFile",
+	"ConfigWatchInterval",
+	"OverridesFile",
+	"AuditFile",
+	"Auth",
+	"TLS",
+}
+
+// policySettings are the settings the scaling policy is built from. A reload
+// changing any of them replaces the policy.
+var policySettings = []string{
+	"Policy",
+	"Policies",
+	"PolicyStrategy",
+	"Idle",
+}
+
+// ReloadResult reports the outcome of a configuration reload. Changed lists
+// the settings that took effect, and RestartRequired the changed settings that
+// keep their running values until the controller is restarted.
+type ReloadResult struct {
+	Time            time.Time `json:"time"`
+	Trigger         string    `json:"trigger"`
+	Success         bool      `json:"success"`
+	Error           string    `json:"error,omitempty"`
+	Changed         []string  `json:"changed,omitempty"`
+	RestartRequired []string  `json:"restartRequired,omitempty"`
+}
+
+// ReloadConfig loads the configuration again from the configuration file, the
+// environment and the runtime overrides and puts it into effect. The trigger,
+// such as a signal or a change of the file, is recorded in the result.
+func (a *Autoscaler) ReloadConfig(trigger string) ReloadResult {
+	// Hold the overrides while reloading, so a runtime change made meanwhile
+	// is not put out of effect by a configuration loaded without it
+	a.overrideMu.Lock()
+	defer a.overrideMu.Unlock()
+	cfg, err := a.loadConfig(a.getOverrides().List())
+	if err != nil {
+		return a.recordReload(ReloadResult{
+			Time:    a.now(),
+			Trigger: trigger,
+			Error:   fmt.Sprintf("failed to load configuration: %v", err),
+		})
+	}
+	return a.Reload(cfg, trigger)
+}
+
+// Reload validates a new configuration and atomically puts it into effect.
+// Scaling operations in progress finish with the configuration they started
+// with. When the configuration is invalid, the current one stays in effect.
+func (a *Autoscaler) Reload(cfg *config.Config, trigger string) ReloadResult {
+	result := ReloadResult{Time: a.now(), Trigger: trigger}
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	if err := validateRecoveryPolicy(cfg.Recovery.Policy); err != nil {
+		result.Error = err.Error()
+		return a.recordReload(result)
+	}
+	if _, err := statePolicies(cfg.StatePolicies); err != nil {
+		result.Error = fmt.Sprintf("invalid state policies: %v", err)
+		return a.recordReload(result)
+	}
+
+	current := a.settings()
+	next := cfg.Clone()
+	result.Changed, result.RestartRequired = diffSettings(current, next)
+	// Settings that need a restart keep their running values, so the
+	// configuration in effect describes what the controller actually does
+	for _, name := range result.RestartRequired {
+		field := reflect.ValueOf(next).Elem().FieldByName(name)
+		field.Set(reflect.ValueOf(current).Elem().FieldByName(name))
+	}
+
+	var scalingPolicy policy.Policy
+	rebuild := changesPolicy(result.Changed)
+	if rebuild {
+		var err error
+		scalingPolicy, err = policy.FromConfig(next)
+		if err != nil {
+			result.Error = fmt.Sprintf("invalid scaling policy: %v", err)
+			result.Changed, result.RestartRequired = nil, nil
+			return a.recordReload(result)
+		}
+	}
+
+	a.configMu.Lock()
+	a.config = next
+	if rebuild {
+		a.policy = scalingPolicy
+	}
+	if next.Recovery.NotifyURL != current.Recovery.NotifyURL {
+		a.notifier = newNotifier(next.Recovery)
+	}
+	a.configMu.Unlock()
+
+	// The state policies were validated above, so this cannot fail
+	_ = a.getLifecycle().setPolicies(next.StatePolicies)
+
+	result.Success = true
+	return a.recordReload(result)
+}
+
+// LastReload returns the result of the most recent reload, or nil when the
+// configuration was never reloaded
+func (a *Autoscaler) LastReload() *ReloadResult {
+	a.configMu.RLock()
+	defer a.configMu.RUnlock()
+	return a.lastReload
+}
+
+// recordReload logs the result of a reload and keeps it for inspection
+func (a *Autoscaler) recordReload(result ReloadResult) ReloadResult {
+	a.configMu.Lock()
+	a.lastReload = &result
+	a.configMu.Unlock()
+
+	if !result.Success {
+		logger.Error().Str("trigger", result.Trigger).Str("error", result.Error).Msg("Configuration reload failed, keeping the current configuration")
+		return result
+	}
+	logger.Info().
+		Str("trigger", result.Trigger).
+		Strs("changed", result.Changed).
+		Msg("Configuration reloaded")
+	if len(result.RestartRequired) > 0 {
+		logger.Warn().Strs("settings", result.RestartRequired).Msg("Changed settings take effect after a restart")
+	}
+	return result
+}
+
+// changesPolicy reports whether any of the changed settings describes the
+// scaling policy
+func changesPolicy(changed []string) bool {
+	for _, name := range changed {
+		if slices.Contains(policySettings, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// diffSettings returns the top-level settings that differ between two
+// configurations, split into those a reload applies and those it cannot
+func diffSettings(current, next *config.Config) (changed []string, restartRequired []string) {
+	needsRestart := make(map[string]bool, len(restartSettings))
+	for _, name := range restartSettings {
+		needsRestart[name] = true
+	}
+
+	currentValue := reflect.ValueOf(current).Elem()
+	nextValue := reflect.ValueOf(next).Elem()
+	for i := 0; i < currentValue.NumField(); i++ {
+		name := currentValue.Type().Field(i).Name
+		if reflect.DeepEqual(currentValue.Field(i).Interface(), nextValue.Field(i).Interface()) {
+			continue
+		}
+		if needsRestart[name] {
+			restartRequired = append(restartRequired, name)
+		} else {
+			changed = append(changed, name)
+		}
+	}
+	return changed, restartRequired
+}
@@ -0,0 +1,137 @@
+package autoscaler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload_AppliesSettings(t *testing.T) {
+	autoscaler := createFastTestAutoscaler(t, new(MockClient))
+
+	cfg := autoscaler.GetConfig()
+	cfg.CooldownDuration = time.Minute
+	cfg.Steps = 3
+	result := autoscaler.Reload(cfg, "test")
+
+	assert.True(t, result.Success)
+	assert.Equal(t, []string{"CooldownDuration", "Steps"}, result.Changed)
+	assert.Empty(t, result.RestartRequired)
+	assert.Equal(t, time.Minute, autoscaler.GetConfig().CooldownDuration)
+	assert.Equal(t, uint(3), autoscaler.GetConfig().Steps)
+	require.NotNil(t, autoscaler.LastReload())
+	assert.Equal(t, "test", autoscaler.LastReload().Trigger)
+}
+
+func TestReload_RestartRequiredKeepsRunningValues(t *testing.T) {
+	autoscaler := createFastTestAutoscaler(t, new(MockClient))
+
+	cfg := autoscaler.GetConfig()
+	cfg.TargetResource = "other-resource"
+	cfg.Sidecar.Timeout = time.Second
+	cfg.MaxCapacity = 10
+	result := autoscaler.Reload(cfg, "test")
+
+	assert.True(t, result.Success)
+	assert.Equal(t, []string{"MaxCapacity"}, result.Changed)
+	assert.Equal(t, []string{"TargetResource", "Sidecar"}, result.RestartRequired)
+	assert.Equal(t, "test-resource", autoscaler.GetConfig().TargetResource)
+	assert.Equal(t, 10, autoscaler.GetConfig().MaxCapacity)
+}
+
+func TestReload_InvalidConfigKeepsCurrent(t *testing.T) {
+	autoscaler := createFastTestAutoscaler(t, new(MockClient))
+
+	cfg := autoscaler.GetConfig()
+	cfg.Steps = 4
+	cfg.StatePolicies = map[string]string{"PAUSED": "ignore"}
+	result := autoscaler.Reload(cfg, "test")
+
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "unknown state policy")
+	assert.Equal(t, uint(1), autoscaler.GetConfig().Steps)
+	assert.False(t, autoscaler.LastReload().Success)
+}
+
+func TestReload_StatePolicies(t *testing.T) {
+	autoscaler := createFastTestAutoscaler(t, new(MockClient))
+
+	cfg := autoscaler.GetConfig()
+	cfg.StatePolicies = map[string]string{"PAUSED": StatePolicyZero}
+	require.True(t, autoscaler.Reload(cfg, "test").Success)
+
+	assert.Equal(t, StatePolicyZero, autoscaler.getLifecycle().policy(omnistrate_api.PAUSED))
+}
+
+func TestReload_InFlightOperationKeepsSettings(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createFastTestAutoscaler(t, mockClient)
+	ctx := context.Background()
+
+	// The configuration is reloaded with larger steps after the first step
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(1), nil).Once()
+	mockClient.On("AddCapacity", ctx, "test-resource", uint(1)).Return(omnistrate_api.ResourceInstance{}, nil).Once().
+		Run(func(mock.Arguments) {
+			cfg := autoscaler.GetConfig()
+			cfg.Steps = 5
+			autoscaler.Reload(cfg, "test")
+		})
+	// The operation goes on in steps of one
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(2), nil).Once()
+	mockClient.On("AddCapacity", ctx, "test-resource", uint(1)).Return(omnistrate_api.ResourceInstance{}, nil).Once()
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(3), nil).Once()
+
+	err := autoscaler.ScaleToTarget(ctx, 3)
+
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+	// The next operation uses the reloaded steps
+	assert.Equal(t, uint(5), autoscaler.GetConfig().Steps)
+}
+
+func TestGetConfig_ReturnsCopy(t *testing.T) {
+	autoscaler := createFastTestAutoscaler(t, new(MockClient))
+
+	cfg := autoscaler.GetConfig()
+	cfg.Steps = 10
+	cfg.StatePolicies["PAUSED"] = StatePolicyZero
+
+	assert.Equal(t, uint(1), autoscaler.GetConfig().Steps)
+	assert.NotContains(t, autoscaler.GetConfig().StatePolicies, "PAUSED")
+}
+
+func TestReload_ReplacesPolicy(t *testing.T) {
+	autoscaler := createFastTestAutoscaler(t, new(MockClient))
+	require.Nil(t, autoscaler.scalingPolicy())
+
+	cfg := autoscaler.GetConfig()
+	cfg.Policy.Type = policy.TypeTargetTracking
+	cfg.Policy.Metric = "cpu"
+	cfg.Policy.Target = 0.5
+	result := autoscaler.Reload(cfg, "test")
+
+	assert.True(t, result.Success)
+	assert.Equal(t, []string{"Policy"}, result.Changed)
+	require.NotNil(t, autoscaler.scalingPolicy())
+	assert.Equal(t, "target-tracking", autoscaler.scalingPolicy().Name())
+}
+
+func TestReload_InvalidPolicyKeepsCurrent(t *testing.T) {
+	autoscaler := createFastTestAutoscaler(t, new(MockClient))
+
+	cfg := autoscaler.GetConfig()
+	cfg.Steps = 2
+	cfg.Policy.Type = policy.TypeTargetTracking
+	result := autoscaler.Reload(cfg, "test")
+
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "invalid scaling policy")
+	assert.Nil(t, autoscaler.scalingPolicy())
+	assert.Equal(t, uint(1), autoscaler.GetConfig().Steps)
+}
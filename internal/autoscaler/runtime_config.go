@@ -0,0 +1,224 @@
+package autoscaler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/audit"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+)
+
+// ErrInvalidConfig is returned when a runtime change would put an invalid
+// configuration into effect
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// ErrRestartRequired is returned when a runtime change touches settings that
+// only take effect when the controller is restarted
+var ErrRestartRequired = errors.New("settings can only be changed by restarting the controller")
+
+// overrideExpiryInterval is how often expired overrides are looked for
+const overrideExpiryInterval = 30 * time.Second
+
+// ConfigChange is a change of settings requested at runtime. Settings are
+// keyed by environment variable, as returned by config.ParsePatch. A positive
+// TTL reverts the change once it has passed.
+type ConfigChange struct {
+	Settings map[string]string
+	TTL      time.Duration
+	Actor    string
+	Reason   string
+}
+
+// UpdateConfig validates a runtime change and puts it into effect. The change
+// is persisted as an override, so it survives restarts until it is reverted
+// or expires, and is recorded in the audit trail.
+func (a *Autoscaler) UpdateConfig(change ConfigChange) (config.Override, error) {
+	override := config.Override{
+		ID:       newOverrideID(),
+		Time:     a.now(),
+		Actor:    change.Actor,
+		Reason:   change.Reason,
+		Settings: change.Settings,
+	}
+	if change.TTL > 0 {
+		expiresAt := override.Time.Add(change.TTL)
+		override.ExpiresAt = &expiresAt
+	}
+
+	a.overrideMu.Lock()
+	defer a.overrideMu.Unlock()
+	store := a.getOverrides()
+	current, err := a.loadConfig(store.List())
+	if err != nil {
+		return config.Override{}, err
+	}
+	cfg, err := a.loadConfig(append(store.List(), override))
+	if err != nil {
+		return config.Override{}, err
+	}
+	if _, restartRequired := diffSettings(current, cfg); len(restartRequired) > 0 {
+		return config.Override{}, fmt.Errorf("%w: %s", ErrRestartRequired, strings.Join(restartRequired, ", "))
+	}
+
+	if err := store.Add(override); err != nil {
+		return config.Override{}, err
+	}
+	if result := a.Reload(cfg, "config API"); !result.Success {
+		if _, err := store.Remove(override.ID); err != nil {
+			logger.Error().Err(err).Str("override", override.ID).Msg("Failed to remove rejected override")
+		}
+		return config.Override{}, fmt.Errorf("%w: %s", ErrInvalidConfig, result.Error)
+	}
+
+	a.recordAudit(audit.Entry{
+		Time:     override.Time,
+		Actor:    override.Actor,
+		Action:   audit.ActionConfigUpdate,
+		Override: override.ID,
+		Reason:   override.Reason,
+		Settings: override.Settings,
+	})
+	return override, nil
+}
+
+// RevertOverride removes a runtime change before it expires and puts the
+// configuration without it into effect
+func (a *Autoscaler) RevertOverride(id, actor string) (config.Override, error) {
+	a.overrideMu.Lock()
+	defer a.overrideMu.Unlock()
+	store := a.getOverrides()
+	override, err := store.Remove(id)
+	if err != nil {
+		return config.Override{}, err
+	}
+
+	cfg, err := a.loadConfig(store.List())
+	if err == nil {
+		if result := a.Reload(cfg, "config API"); !result.Success {
+			err = fmt.Errorf("%w: %s", ErrInvalidConfig, result.Error)
+		}
+	}
+	if err != nil {
+		// Keep the override, since the configuration in effect still has it
+		if addErr := store.Add(override); addErr != nil {
+			logger.Error().Err(addErr).Str("override", id).Msg("Failed to restore override")
+		}
+		return config.Override{}, err
+	}
+
+	a.recordAudit(audit.Entry{
+		Time:     a.now(),
+		Actor:    actor,
+		Action:   audit.ActionConfigRevert,
+		Override: override.ID,
+		Settings: override.Settings,
+	})
+	return override, nil
+}
+
+// ExpireOverrides reverts the runtime changes whose TTL has passed
+func (a *Autoscaler) ExpireOverrides() {
+	a.overrideMu.Lock()
+	defer a.overrideMu.Unlock()
+	store := a.getOverrides()
+	expired, err := store.Expire(a.now())
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to expire overrides")
+		return
+	}
+	if len(expired) == 0 {
+		return
+	}
+
+	var reloadError string
+	cfg, err := a.loadConfig(store.List())
+	if err != nil {
+		reloadError = err.Error()
+	} else if result := a.Reload(cfg, "override expired"); !result.Success {
+		reloadError = result.Error
+	}
+	for _, override := range expired {
+		a.recordAudit(audit.Entry{
+			Time:     a.now(),
+			Action:   audit.ActionConfigExpire,
+			Override: override.ID,
+			Reason:   override.Reason,
+			Settings: override.Settings,
+			Error:    reloadError,
+		})
+	}
+}
+
+// RunOverrideExpiry reverts expired runtime changes until the context is
+// cancelled
+func (a *Autoscaler) RunOverrideExpiry(ctx context.Context) {
+	ticker := time.NewTicker(overrideExpiryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.ExpireOverrides()
+		}
+	}
+}
+
+// Overrides returns the runtime changes in effect, oldest first
+func (a *Autoscaler) Overrides() []config.Override {
+	return a.getOverrides().List()
+}
+
+// AuditTrail returns the most recent changes made by operators, oldest first
+func (a *Autoscaler) AuditTrail() []audit.Entry {
+	return a.getAudit().Entries()
+}
+
+// loadConfig loads the configuration file the autoscaler was started with and
+// the environment with the given overrides on top
+func (a *Autoscaler) loadConfig(overrides []config.Override) (*config.Config, error) {
+	return config.Load(a.configFile, config.WithOverrides(overrides, os.LookupEnv))
+}
+
+// recordAudit adds an entry to the audit trail
+func (a *Autoscaler) recordAudit(entry audit.Entry) {
+	if err := a.getAudit().Record(entry); err != nil {
+		logger.Error().Err(err).Str("action", entry.Action).Msg("Failed to write audit trail")
+	}
+}
+
+// getOverrides returns the store of runtime changes, creating one kept in
+// memory when the autoscaler was built without it
+func (a *Autoscaler) getOverrides() *config.OverrideStore {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.overrides == nil {
+		a.overrides, _ = config.OpenOverrideStore("")
+	}
+	return a.overrides
+}
+
+// getAudit returns the audit trail, creating one kept in memory when the
+// autoscaler was built without it
+func (a *Autoscaler) getAudit() *audit.Log {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.audit == nil {
+		a.audit, _ = audit.Open("")
+	}
+	return a.audit
+}
+
+// newOverrideID returns a random identifier for an override
+func newOverrideID() string {
+	id := make([]byte, 6)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
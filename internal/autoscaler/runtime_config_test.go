@@ -0,0 +1,180 @@
+package autoscaler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/audit"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateConfig_AppliesAndAudits(t *testing.T) {
+	autoscaler := createFastTestAutoscaler(t, new(MockClient))
+
+	override, err := autoscaler.UpdateConfig(ConfigChange{
+		Settings: map[string]string{"AUTOSCALER_COOLDOWN": "60", "AUTOSCALER_MAX_CAPACITY": "8"},
+		Actor:    "alice",
+		Reason:   "incident",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Minute, autoscaler.GetConfig().CooldownDuration)
+	assert.Equal(t, 8, autoscaler.GetConfig().MaxCapacity)
+	assert.Nil(t, override.ExpiresAt)
+	require.Len(t, autoscaler.Overrides(), 1)
+	assert.Equal(t, override.ID, autoscaler.Overrides()[0].ID)
+
+	entries := autoscaler.AuditTrail()
+	require.Len(t, entries, 1)
+	assert.Equal(t, audit.ActionConfigUpdate, entries[0].Action)
+	assert.Equal(t, "alice", entries[0].Actor)
+	assert.Equal(t, "incident", entries[0].Reason)
+	assert.Equal(t, override.ID, entries[0].Override)
+	assert.Equal(t, "60", entries[0].Settings["AUTOSCALER_COOLDOWN"])
+}
+
+func TestUpdateConfig_InvalidIsRejected(t *testing.T) {
+	autoscaler := createFastTestAutoscaler(t, new(MockClient))
+
+	_, err := autoscaler.UpdateConfig(ConfigChange{
+		Settings: map[string]string{"AUTOSCALER_MIN_CAPACITY": "5", "AUTOSCALER_MAX_CAPACITY": "2"},
+	})
+	var validationErr *config.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	// A policy that cannot be built is rejected by the reload
+	_, err = autoscaler.UpdateConfig(ConfigChange{
+		Settings: map[string]string{"AUTOSCALER_POLICY_TYPE": "target-tracking"},
+	})
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	assert.Empty(t, autoscaler.Overrides())
+	assert.Empty(t, autoscaler.AuditTrail())
+	assert.Equal(t, 0, autoscaler.GetConfig().MaxCapacity)
+}
+
+func TestUpdateConfig_RestartOnlySettingsAreRejected(t *testing.T) {
+	autoscaler := createFastTestAutoscaler(t, new(MockClient))
+
+	_, err := autoscaler.UpdateConfig(ConfigChange{
+		Settings: map[string]string{"AUTOSCALER_TARGET_RESOURCE": "other-resource"},
+	})
+	assert.ErrorIs(t, err, ErrRestartRequired)
+	assert.Contains(t, err.Error(), "TargetResource")
+	assert.Empty(t, autoscaler.Overrides())
+}
+
+func TestUpdateConfig_ChangesPolicyParameters(t *testing.T) {
+	t.Setenv("AUTOSCALER_POLICY_TYPE", "target-tracking")
+	t.Setenv("AUTOSCALER_POLICY_METRIC", "cpu")
+	t.Setenv("AUTOSCALER_POLICY_TARGET", "0.5")
+	autoscaler := createFastTestAutoscaler(t, new(MockClient))
+
+	_, err := autoscaler.UpdateConfig(ConfigChange{
+		Settings: map[string]string{"AUTOSCALER_POLICY_TARGET": "0.8"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 0.8, autoscaler.GetConfig().Policy.Target)
+	require.NotNil(t, autoscaler.scalingPolicy())
+}
+
+func TestExpireOverrides_RevertsAfterTTL(t *testing.T) {
+	autoscaler := createFastTestAutoscaler(t, new(MockClient))
+	clk := clock.NewSimulated(time.Unix(1700000000, 0))
+	autoscaler.clock = clk
+
+	override, err := autoscaler.UpdateConfig(ConfigChange{
+		Settings: map[string]string{"AUTOSCALER_STEPS": "3"},
+		TTL:      2 * time.Hour,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, override.ExpiresAt)
+	assert.Equal(t, clk.Now().Add(2*time.Hour), *override.ExpiresAt)
+
+	// Nothing happens before the TTL has passed
+	clk.Advance(time.Hour)
+	autoscaler.ExpireOverrides()
+	assert.Equal(t, uint(3), autoscaler.GetConfig().Steps)
+
+	clk.Advance(time.Hour)
+	autoscaler.ExpireOverrides()
+	assert.Equal(t, uint(1), autoscaler.GetConfig().Steps)
+	assert.Empty(t, autoscaler.Overrides())
+
+	entries := autoscaler.AuditTrail()
+	require.Len(t, entries, 2)
+	assert.Equal(t, audit.ActionConfigExpire, entries[1].Action)
+	assert.Equal(t, override.ID, entries[1].Override)
+}
+
+func TestRevertOverride(t *testing.T) {
+	autoscaler := createFastTestAutoscaler(t, new(MockClient))
+
+	first, err := autoscaler.UpdateConfig(ConfigChange{Settings: map[string]string{"AUTOSCALER_STEPS": "2"}})
+	require.NoError(t, err)
+	_, err = autoscaler.UpdateConfig(ConfigChange{Settings: map[string]string{"AUTOSCALER_STEPS": "4"}})
+	require.NoError(t, err)
+	assert.Equal(t, uint(4), autoscaler.GetConfig().Steps)
+
+	// Reverting the older override keeps the newer one in effect
+	_, err = autoscaler.RevertOverride(first.ID, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, uint(4), autoscaler.GetConfig().Steps)
+	require.Len(t, autoscaler.Overrides(), 1)
+
+	_, err = autoscaler.RevertOverride(first.ID, "bob")
+	assert.ErrorIs(t, err, config.ErrOverrideNotFound)
+
+	entries := autoscaler.AuditTrail()
+	require.Len(t, entries, 3)
+	assert.Equal(t, audit.ActionConfigRevert, entries[2].Action)
+	assert.Equal(t, "bob", entries[2].Actor)
+}
+
+func TestUpdateConfig_UsesStartupConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "autoscaler.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("targetResource: test-resource\nmaxCapacity: 6\n"), 0o600))
+	cfg, err := config.Load(path, os.LookupEnv)
+	require.NoError(t, err)
+	autoscaler, err := NewWithClient(cfg, new(MockClient), clock.Real{})
+	require.NoError(t, err)
+
+	// Changes apply on top of the file even if the environment does not name it
+	t.Setenv("AUTOSCALER_CONFIG_FILE", "")
+	_, err = autoscaler.UpdateConfig(ConfigChange{Settings: map[string]string{"AUTOSCALER_COOLDOWN": "60"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Minute, autoscaler.GetConfig().CooldownDuration)
+	assert.Equal(t, 6, autoscaler.GetConfig().MaxCapacity)
+	assert.Equal(t, path, autoscaler.GetConfig().ConfigFile)
+}
+
+func TestUpdateConfig_Persists(t *testing.T) {
+	dir := t.TempDir()
+	autoscaler := createFastTestAutoscaler(t, new(MockClient))
+	store, err := config.OpenOverrideStore(filepath.Join(dir, "overrides.json"))
+	require.NoError(t, err)
+	auditLog, err := audit.Open(filepath.Join(dir, "audit.log"))
+	require.NoError(t, err)
+	autoscaler.overrides = store
+	autoscaler.audit = auditLog
+
+	override, err := autoscaler.UpdateConfig(ConfigChange{Settings: map[string]string{"AUTOSCALER_COOLDOWN": "30"}})
+	require.NoError(t, err)
+
+	// Both survive a restart
+	reopened, err := config.OpenOverrideStore(filepath.Join(dir, "overrides.json"))
+	require.NoError(t, err)
+	require.Len(t, reopened.List(), 1)
+	assert.Equal(t, override.ID, reopened.List()[0].ID)
+	reopenedAudit, err := audit.Open(filepath.Join(dir, "audit.log"))
+	require.NoError(t, err)
+	require.Len(t, reopenedAudit.Entries(), 1)
+	assert.Equal(t, override.ID, reopenedAudit.Entries()[0].Override)
+}
@@ -0,0 +1,147 @@
+package autoscaler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/policy"
+)
+
+// ShadowStats compares what a shadow policy would have done with the active
+// policy. Capacity-hours assume every shadow action takes effect immediately.
+type ShadowStats struct {
+	Name                    string    `json:"name"`
+	Policy                  string    `json:"policy"`
+	Evaluations             int       `json:"evaluations"`
+	Disagreements           int       `json:"disagreements"`
+	DisagreementRate        float64   `json:"disagreementRate"`
+	Errors                  int       `json:"errors"`
+	ShadowActions           int       `json:"shadowActions"`
+	ActiveActions           int       `json:"activeActions"`
+	ShadowCapacityHours     float64   `json:"shadowCapacityHours"`
+	ActiveCapacityHours     float64   `json:"activeCapacityHours"`
+	CapacityHoursDifference float64   `json:"capacityHoursDifference"`
+	SimulatedCapacity       int       `json:"simulatedCapacity"`
+	LastDesiredCapacity     int       `json:"lastDesiredCapacity"`
+	LastReason              string    `json:"lastReason,omitempty"`
+	LastError               string    `json:"lastError,omitempty"`
+	LastEvaluation          time.Time `json:"lastEvaluation,omitempty"`
+}
+
+// shadow is a policy evaluated on the same inputs as the active policy. It
+// only ever sees policy inputs and never the sidecar client, so it cannot
+// change the capacity of the resource.
+type shadow struct {
+	policy policy.Policy
+
+	mu             sync.Mutex
+	stats          ShadowStats
+	activeCapacity int
+	lastAction     time.Time
+}
+
+// newShadows builds the configured shadow policies
+func newShadows(configs []config.PolicyConfig) ([]*shadow, error) {
+	var shadows []*shadow
+	for _, cfg := range configs {
+		p, err := policy.New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		shadows = append(shadows, &shadow{
+			policy: p,
+			stats:  ShadowStats{Name: cfg.Name, Policy: p.Name()},
+		})
+	}
+	return shadows, nil
+}
+
+// ShadowStats returns the divergence statistics of every shadow policy
+func (a *Autoscaler) ShadowStats() []ShadowStats {
+	stats := make([]ShadowStats, 0, len(a.shadows))
+	for _, s := range a.shadows {
+		s.mu.Lock()
+		stats = append(stats, s.stats)
+		s.mu.Unlock()
+	}
+	return stats
+}
+
+// evaluateShadows runs every shadow policy on the input of the active policy
+// and returns their bounded desired capacities in the order of the shadows
+func (a *Autoscaler) evaluateShadows(ctx context.Context, cfg *config.Config, in policy.Input) []shadowResult {
+	results := make([]shadowResult, len(a.shadows))
+	for i, s := range a.shadows {
+		rec, err := s.policy.Evaluate(ctx, in)
+		if err != nil {
+			logger.Debug().Err(err).Str("shadow", s.stats.Name).Msg("Shadow policy evaluation failed")
+			results[i] = shadowResult{err: err}
+			continue
+		}
+		results[i] = shadowResult{desired: clamp(cfg, rec.DesiredCapacity), reason: rec.Reason}
+	}
+	return results
+}
+
+type shadowResult struct {
+	desired int
+	reason  string
+	err     error
+}
+
+// recordShadows compares the shadow results with the final decision of the
+// active policy and updates the divergence statistics
+func (a *Autoscaler) recordShadows(decision Decision, results []shadowResult) {
+	activeActed := decision.Action == ActionScale || decision.Action == ActionWake
+	activeCapacity := decision.CurrentCapacity
+	cooldown := a.settings().CooldownDuration
+	if activeActed && decision.Error == "" {
+		activeCapacity = decision.DesiredCapacity
+	}
+
+	for i, s := range a.shadows {
+		result := results[i]
+		s.mu.Lock()
+		stats := &s.stats
+		if !stats.LastEvaluation.IsZero() {
+			hours := decision.Time.Sub(stats.LastEvaluation).Hours()
+			stats.ShadowCapacityHours += float64(stats.SimulatedCapacity) * hours
+			stats.ActiveCapacityHours += float64(s.activeCapacity) * hours
+			stats.CapacityHoursDifference = stats.ShadowCapacityHours - stats.ActiveCapacityHours
+		} else {
+			stats.SimulatedCapacity = decision.CurrentCapacity
+		}
+		stats.LastEvaluation = decision.Time
+		s.activeCapacity = activeCapacity
+		if activeActed {
+			stats.ActiveActions++
+		}
+
+		if result.err != nil {
+			stats.Errors++
+			stats.LastError = result.err.Error()
+			s.mu.Unlock()
+			continue
+		}
+		stats.Evaluations++
+		stats.LastDesiredCapacity = result.desired
+		stats.LastReason = result.reason
+		stats.LastError = ""
+		if result.desired != decision.DesiredCapacity {
+			stats.Disagreements++
+		}
+		stats.DisagreementRate = float64(stats.Disagreements) / float64(stats.Evaluations)
+
+		// The shadow acts on its own simulated capacity, honoring the cooldown
+		inCooldown := !s.lastAction.IsZero() && decision.Time.Sub(s.lastAction) < cooldown
+		if result.desired != stats.SimulatedCapacity && !inCooldown {
+			stats.ShadowActions++
+			stats.SimulatedCapacity = result.desired
+			s.lastAction = decision.Time
+		}
+		s.mu.Unlock()
+	}
+}
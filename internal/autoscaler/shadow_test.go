@@ -0,0 +1,79 @@
+package autoscaler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluate_ShadowPolicyNeverScales(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createTestEvaluator(t, mockClient, 0.5)
+	shadows, err := newShadows([]config.PolicyConfig{
+		{Name: "aggressive", Type: policy.TypeTargetTracking, Metric: "cpu", Target: 0.25},
+	})
+	require.NoError(t, err)
+	autoscaler.shadows = shadows
+	ctx := context.Background()
+
+	// The active policy holds at 2 while the shadow would scale to 4. Only the
+	// observations are expected; the shadow must not add capacity.
+	mockClient.On("GetCurrentCapacity", ctx, "test-resource").Return(activeCapacity(2), nil).Twice()
+
+	first := autoscaler.Evaluate(ctx)
+	assert.Equal(t, ActionHold, first.Action)
+	second := autoscaler.Evaluate(ctx)
+	assert.Equal(t, ActionHold, second.Action)
+	mockClient.AssertExpectations(t)
+
+	stats := autoscaler.ShadowStats()
+	require.Len(t, stats, 1)
+	assert.Equal(t, "aggressive", stats[0].Name)
+	assert.Equal(t, 2, stats[0].Evaluations)
+	assert.Equal(t, 2, stats[0].Disagreements)
+	assert.Equal(t, 1.0, stats[0].DisagreementRate)
+	assert.Equal(t, 1, stats[0].ShadowActions)
+	assert.Equal(t, 0, stats[0].ActiveActions)
+	assert.Equal(t, 4, stats[0].SimulatedCapacity)
+	assert.Equal(t, 4, stats[0].LastDesiredCapacity)
+}
+
+func TestRecordShadows_CapacityHours(t *testing.T) {
+	mockClient := new(MockClient)
+	autoscaler := createFastTestAutoscaler(t, mockClient)
+	autoscaler.config.CooldownDuration = 0
+	shadows, err := newShadows([]config.PolicyConfig{
+		{Name: "lean", Type: policy.TypeTargetTracking, Metric: "cpu", Target: 0.5},
+	})
+	require.NoError(t, err)
+	autoscaler.shadows = shadows
+	start := time.Unix(1700000000, 0)
+
+	// At the start both run 4 replicas; the shadow wants 2 while the active holds
+	autoscaler.recordShadows(Decision{Time: start, CurrentCapacity: 4, DesiredCapacity: 4, Action: ActionHold},
+		[]shadowResult{{desired: 2}})
+	// An hour later the active scales to 3 and the shadow agrees
+	autoscaler.recordShadows(Decision{Time: start.Add(time.Hour), CurrentCapacity: 4, DesiredCapacity: 3, Action: ActionScale},
+		[]shadowResult{{desired: 3}})
+	// Another hour passes with both at 3
+	autoscaler.recordShadows(Decision{Time: start.Add(2 * time.Hour), CurrentCapacity: 3, DesiredCapacity: 3, Action: ActionHold},
+		[]shadowResult{{desired: 3}})
+
+	stats := autoscaler.ShadowStats()[0]
+	assert.InDelta(t, 5.0, stats.ShadowCapacityHours, 0.0001) // 2 + 3
+	assert.InDelta(t, 7.0, stats.ActiveCapacityHours, 0.0001) // 4 + 3
+	assert.InDelta(t, -2.0, stats.CapacityHoursDifference, 0.0001)
+	assert.Equal(t, 2, stats.ShadowActions)
+	assert.Equal(t, 1, stats.ActiveActions)
+	assert.Equal(t, 1, stats.Disagreements)
+}
+
+func TestNewShadows_InvalidPolicy(t *testing.T) {
+	_, err := newShadows([]config.PolicyConfig{{Name: "broken", Type: policy.TypeTargetTracking}})
+	assert.Error(t, err)
+}
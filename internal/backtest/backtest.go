@@ -0,0 +1,254 @@
+// Package backtest replays a recorded metric trace through the autoscaler's
+// decision logic on a simulated clock, so a policy configuration can be
+// evaluated before it is deployed.
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/autoscaler"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/metrics"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
+)
+
+// Options control how the simulated resource behaves and how the SLO is judged
+type Options struct {
+	// InitialCapacity is the capacity of the resource when the trace starts
+	InitialCapacity int
+	// ScaleUpDelay and ScaleDownDelay model how long the resource stays
+	// STARTING after capacity is added or removed
+	ScaleUpDelay   time.Duration
+	ScaleDownDelay time.Duration
+	// PerReplica lists trace metrics that record total demand and are
+	// divided by the simulated capacity before policies see them
+	PerReplica []string
+	// SLOMetric is violated at every trace sample where its value exceeds
+	// SLOThreshold, or where there is demand and no capacity to serve it
+	SLOMetric    string
+	SLOThreshold float64
+}
+
+// Point is one evaluation of the policy. Capacity and Status are observed once
+// the evaluation, including any scaling operation it started, has completed.
+type Point struct {
+	Time            time.Time          `json:"time"`
+	Capacity        int                `json:"capacity"`
+	Status          string             `json:"status"`
+	DesiredCapacity int                `json:"desiredCapacity"`
+	Action          string             `json:"action"`
+	Reason          string             `json:"reason"`
+	Metrics         map[string]float64 `json:"metrics,omitempty"`
+	Violation       bool               `json:"violation,omitempty"`
+	Error           string             `json:"error,omitempty"`
+}
+
+// Summary aggregates the outcome of a backtest
+type Summary struct {
+	Start               time.Time `json:"start"`
+	End                 time.Time `json:"end"`
+	Evaluations         int       `json:"evaluations"`
+	Actions             int       `json:"actions"`
+	CapacityRequests    int       `json:"capacityRequests"`
+	CapacityHours       float64   `json:"capacityHours"`
+	PeakCapacity        int       `json:"peakCapacity"`
+	FinalCapacity       int       `json:"finalCapacity"`
+	SLOViolations       int       `json:"sloViolations"`
+	SLOViolationMinutes float64   `json:"sloViolationMinutes"`
+}
+
+// Result is the capacity timeline produced by a backtest and its summary
+type Result struct {
+	Summary  Summary                        `json:"summary"`
+	Timeline []Point                        `json:"timeline"`
+	Events   []omnistrate_api.CapacityEvent `json:"events"`
+}
+
+// Run replays the trace through an autoscaler built from cfg. Metric sources,
+// the proxy and the dry-run and recommend-only modes are switched off in cfg:
+// policies read the trace instead, and every metric in the trace is made
+// available to them as an input. The policy is evaluated on every evaluation
+// interval from the first sample to the last.
+func Run(ctx context.Context, cfg *config.Config, trace *Trace, opts Options) (*Result, error) {
+	if cfg.EvaluationInterval <= 0 {
+		return nil, fmt.Errorf("evaluation interval must be positive")
+	}
+	if opts.InitialCapacity < 0 {
+		return nil, fmt.Errorf("initial capacity must not be negative")
+	}
+	for _, name := range append(slices.Clone(opts.PerReplica), opts.SLOMetric) {
+		if name != "" && !slices.Contains(trace.Metrics, name) {
+			return nil, fmt.Errorf("metric %s is not in the trace", name)
+		}
+	}
+
+	cfg.MetricSources = nil
+	cfg.Proxy = config.ProxyConfig{}
+	cfg.DryRun = false
+	cfg.RecommendOnly = false
+	cfg.SetMetricInputs(trace.Metrics)
+
+	clk := clock.NewSimulated(trace.Start())
+	simulator := omnistrate_api.NewSimulator(clk, config.SimulationConfig{
+		InitialCapacity: opts.InitialCapacity,
+		ScaleUpDelay:    opts.ScaleUpDelay,
+		ScaleDownDelay:  opts.ScaleDownDelay,
+	})
+	scaler, err := autoscaler.NewWithClient(cfg, simulator, clk)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &replay{trace: trace, clock: clk, simulator: simulator, perReplica: opts.PerReplica}
+	for _, name := range trace.Metrics {
+		scaler.AddSource(&traceSource{name: name, replay: r})
+	}
+
+	result := &Result{Summary: Summary{Start: trace.Start(), End: trace.End()}}
+	for tick := trace.Start(); !tick.After(trace.End()); tick = tick.Add(cfg.EvaluationInterval) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// A scaling operation advances the clock while it waits for the
+		// resource, so evaluations that fall within it are skipped. The
+		// controller keeps evaluating while it scales in the background, but
+		// starts no other operation until this one is done.
+		if clk.Now().After(tick) {
+			continue
+		}
+		clk.AdvanceTo(tick)
+
+		decision := scaler.Evaluate(ctx)
+		capacity, err := simulator.GetCurrentCapacity(ctx, cfg.TargetResource)
+		if err != nil {
+			return nil, err
+		}
+		result.Timeline = append(result.Timeline, Point{
+			Time:            decision.Time,
+			Capacity:        capacity.CurrentCapacity,
+			Status:          string(capacity.Status),
+			DesiredCapacity: decision.DesiredCapacity,
+			Action:          decision.Action,
+			Reason:          decision.Reason,
+			Metrics:         decision.Metrics,
+			Error:           decision.Error,
+		})
+		result.Summary.Evaluations++
+		if decision.Action == autoscaler.ActionScale || decision.Action == autoscaler.ActionWake {
+			result.Summary.Actions++
+		}
+	}
+
+	result.Events = simulator.Events()
+	result.Summary.CapacityRequests = simulator.Requests()
+	result.Summary.FinalCapacity = simulator.Capacity()
+	result.Summary.CapacityHours, result.Summary.PeakCapacity = capacityHours(result.Events, trace.Start(), trace.End())
+	if opts.SLOMetric != "" {
+		r.markViolations(result, opts)
+	}
+	return result, nil
+}
+
+// replay serves trace values to the autoscaler at the simulated time
+type replay struct {
+	trace      *Trace
+	clock      *clock.Simulated
+	simulator  *omnistrate_api.Simulator
+	perReplica []string
+}
+
+// value returns a metric as a policy sees it, dividing per-replica metrics by
+// the given capacity. With no capacity the total demand is returned.
+func (r *replay) value(name string, at time.Time, capacity int) (float64, bool) {
+	value, ok := r.trace.At(name, at)
+	if !ok {
+		return 0, false
+	}
+	if capacity > 0 && slices.Contains(r.perReplica, name) {
+		value /= float64(capacity)
+	}
+	return value, true
+}
+
+// markViolations checks the SLO at every trace sample against the capacity
+// serving at the time, counting the time until the next sample as spent in
+// violation, and flags the evaluations that saw a violation
+func (r *replay) markViolations(result *Result, opts Options) {
+	violated := func(at time.Time) bool {
+		capacity := capacityAt(result.Events, at)
+		value, ok := r.value(opts.SLOMetric, at, capacity)
+		return ok && (value > opts.SLOThreshold || (capacity == 0 && value > 0))
+	}
+
+	samples := r.trace.Samples
+	for i, sample := range samples {
+		if !violated(sample.Time) {
+			continue
+		}
+		result.Summary.SLOViolations++
+		if i+1 < len(samples) {
+			result.Summary.SLOViolationMinutes += samples[i+1].Time.Sub(sample.Time).Minutes()
+		}
+	}
+	for i := range result.Timeline {
+		result.Timeline[i].Violation = violated(result.Timeline[i].Time)
+	}
+}
+
+// traceSource is a metric source reading one metric from the trace
+type traceSource struct {
+	name   string
+	replay *replay
+}
+
+func (s *traceSource) Name() string {
+	return s.name
+}
+
+func (s *traceSource) Read(ctx context.Context) (float64, error) {
+	value, ok := s.replay.value(s.name, s.replay.clock.Now(), s.replay.simulator.Capacity())
+	if !ok {
+		return 0, metrics.ErrNoData
+	}
+	return value, nil
+}
+
+// capacityAt returns the capacity serving at the given time
+func capacityAt(events []omnistrate_api.CapacityEvent, at time.Time) int {
+	capacity := 0
+	for _, event := range events {
+		if event.Time.After(at) {
+			break
+		}
+		capacity = event.Capacity
+	}
+	return capacity
+}
+
+// capacityHours integrates the capacity of the resource over [start, end] and
+// returns it together with the peak capacity in that range
+func capacityHours(events []omnistrate_api.CapacityEvent, start, end time.Time) (float64, int) {
+	var hours float64
+	peak := 0
+	for i, event := range events {
+		from := event.Time
+		if from.Before(start) {
+			from = start
+		}
+		to := end
+		if i+1 < len(events) && events[i+1].Time.Before(end) {
+			to = events[i+1].Time
+		}
+		if to.After(from) {
+			hours += float64(event.Capacity) * to.Sub(from).Hours()
+		}
+		if !event.Time.After(end) {
+			peak = max(peak, event.Capacity)
+		}
+	}
+	return hours, peak
+}
@@ -0,0 +1,125 @@
+package backtest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/autoscaler"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTrace_CSV(t *testing.T) {
+	trace, err := ReadTrace(strings.NewReader(`timestamp,rps,latency
+2025-01-01T00:01:00Z,200,
+1735689600,100,0.2
+`), FormatCSV)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"latency", "rps"}, trace.Metrics)
+	require.Len(t, trace.Samples, 2)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), trace.Start())
+
+	value, ok := trace.At("rps", trace.Start().Add(30*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, 100.0, value)
+	value, ok = trace.At("latency", trace.End())
+	assert.True(t, ok)
+	assert.Equal(t, 0.2, value, "a missing value holds the previous sample")
+	_, ok = trace.At("rps", trace.Start().Add(-time.Second))
+	assert.False(t, ok)
+}
+
+func TestReadTrace_JSONL(t *testing.T) {
+	trace, err := ReadTrace(strings.NewReader(`{"time": "2025-01-01T00:00:00Z", "rps": 100}
+{"time": 1735689660, "metrics": {"rps": 150, "queue-depth": 3}}
+`), FormatJSONL)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"queue-depth", "rps"}, trace.Metrics)
+	value, ok := trace.At("rps", trace.End())
+	assert.True(t, ok)
+	assert.Equal(t, 150.0, value)
+}
+
+func TestReadTrace_Invalid(t *testing.T) {
+	_, err := ReadTrace(strings.NewReader("rps\n100\n"), FormatCSV)
+	assert.ErrorContains(t, err, "no time column")
+
+	_, err = ReadTrace(strings.NewReader("time,rps\n2025-01-01T00:00:00Z,fast\n"), FormatCSV)
+	assert.ErrorContains(t, err, "invalid rps value")
+
+	_, err = ReadTrace(strings.NewReader(`{"rps": 1}`), FormatJSONL)
+	assert.ErrorContains(t, err, "missing time")
+}
+
+func TestRun_FollowsDemandWithProvisioningDelay(t *testing.T) {
+	// Demand quadruples for 20 minutes and then drops back
+	var csv strings.Builder
+	csv.WriteString("time,rps\n")
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for minute := 0; minute <= 60; minute++ {
+		rps := "100"
+		if minute >= 20 && minute < 40 {
+			rps = "400"
+		}
+		csv.WriteString(start.Add(time.Duration(minute)*time.Minute).Format(time.RFC3339) + "," + rps + "\n")
+	}
+	trace, err := ReadTrace(strings.NewReader(csv.String()), FormatCSV)
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		TargetResource:             "backtest",
+		Steps:                      4,
+		CooldownDuration:           2 * time.Minute,
+		WaitForActiveTimeout:       10 * time.Minute,
+		WaitForActiveCheckInterval: 10 * time.Second,
+		EvaluationInterval:         time.Minute,
+		MinCapacity:                1,
+		MaxCapacity:                10,
+		Policy:                     config.PolicyConfig{Type: "target-tracking", Metric: "rps", Target: 100},
+	}
+	result, err := Run(context.Background(), cfg, trace, Options{
+		InitialCapacity: 1,
+		ScaleUpDelay:    3 * time.Minute,
+		PerReplica:      []string{"rps"},
+		SLOMetric:       "rps",
+		SLOThreshold:    150,
+	})
+	require.NoError(t, err)
+
+	summary := result.Summary
+	assert.Equal(t, 2, summary.Actions, "one scale up and one scale down")
+	assert.Equal(t, 4, summary.PeakCapacity)
+	assert.Equal(t, 1, summary.FinalCapacity)
+	// Capacity is requested after the first ACTIVE check at 00:20:10 and is
+	// ready three minutes later, so the samples from 00:20 to 00:23 violate
+	assert.Equal(t, 4, summary.SLOViolations)
+	assert.InDelta(t, 4.0, summary.SLOViolationMinutes, 0.001)
+	assert.Greater(t, summary.CapacityHours, 1.0)
+	assert.Less(t, summary.CapacityHours, 4.0)
+
+	var scaled []Point
+	for _, point := range result.Timeline {
+		if point.Action == autoscaler.ActionScale {
+			scaled = append(scaled, point)
+		}
+	}
+	require.Len(t, scaled, 2)
+	assert.Equal(t, start.Add(20*time.Minute), scaled[0].Time)
+	assert.Equal(t, 4, scaled[0].DesiredCapacity)
+	assert.True(t, scaled[0].Violation)
+	assert.Equal(t, start.Add(40*time.Minute), scaled[1].Time)
+	assert.Equal(t, 1, scaled[1].DesiredCapacity)
+}
+
+func TestRun_UnknownMetric(t *testing.T) {
+	trace, err := ReadTrace(strings.NewReader("time,rps\n0,1\n"), FormatCSV)
+	require.NoError(t, err)
+
+	_, err = Run(context.Background(), &config.Config{EvaluationInterval: time.Minute}, trace, Options{SLOMetric: "latency"})
+	assert.ErrorContains(t, err, "metric latency is not in the trace")
+}
@@ -0,0 +1,54 @@
+package backtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// WriteJSON writes the full result as indented JSON
+func WriteJSON(w io.Writer, result *Result) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// WriteText writes the capacity timeline as a table followed by the summary
+func WriteText(w io.Writer, result *Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tCAPACITY\tSTATUS\tDESIRED\tACTION\tSLO\tREASON")
+	for _, point := range result.Timeline {
+		slo := "ok"
+		if point.Violation {
+			slo = "VIOLATED"
+		}
+		reason := point.Reason
+		if point.Error != "" {
+			reason += " (error: " + point.Error + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%s\t%s\t%s\n",
+			point.Time.UTC().Format(time.RFC3339), point.Capacity, point.Status,
+			point.DesiredCapacity, point.Action, slo, reason)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	summary := result.Summary
+	_, err := fmt.Fprintf(w, `
+Period:             %s to %s (%s)
+Evaluations:        %d
+Scaling actions:    %d
+Capacity requests:  %d
+Capacity-hours:     %.2f
+Peak capacity:      %d
+Final capacity:     %d
+SLO violations:     %d (%.1f minutes)
+`,
+		summary.Start.UTC().Format(time.RFC3339), summary.End.UTC().Format(time.RFC3339), summary.End.Sub(summary.Start),
+		summary.Evaluations, summary.Actions, summary.CapacityRequests, summary.CapacityHours,
+		summary.PeakCapacity, summary.FinalCapacity, summary.SLOViolations, summary.SLOViolationMinutes)
+	return err
+}
@@ -0,0 +1,251 @@
+package backtest
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format identifies how a trace file is encoded
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+)
+
+// Sample is the value of every metric in a trace at a point in time
+type Sample struct {
+	Time    time.Time
+	Metrics map[string]float64
+}
+
+// Trace is a time-ordered series of metric samples to replay
+type Trace struct {
+	Metrics []string
+	Samples []Sample
+}
+
+// Start returns the time of the first sample
+func (t *Trace) Start() time.Time {
+	return t.Samples[0].Time
+}
+
+// End returns the time of the last sample
+func (t *Trace) End() time.Time {
+	return t.Samples[len(t.Samples)-1].Time
+}
+
+// At returns the value of a metric at the given time, holding each sample
+// until the next one. It reports false before the metric's first sample.
+func (t *Trace) At(metric string, at time.Time) (float64, bool) {
+	i := sort.Search(len(t.Samples), func(i int) bool {
+		return t.Samples[i].Time.After(at)
+	})
+	for i--; i >= 0; i-- {
+		if value, ok := t.Samples[i].Metrics[metric]; ok {
+			return value, true
+		}
+	}
+	return 0, false
+}
+
+// LoadTrace reads a trace file. When format is empty it is inferred from the
+// file extension.
+func LoadTrace(path string, format Format) (*Trace, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			format = FormatCSV
+		case ".jsonl", ".ndjson", ".json":
+			format = FormatJSONL
+		default:
+			return nil, fmt.Errorf("cannot infer trace format from %s, specify csv or jsonl", path)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace: %w", err)
+	}
+	defer f.Close()
+
+	return ReadTrace(f, format)
+}
+
+// ReadTrace parses a trace in the given format.
+//
+// CSV traces have a header row with a "time" or "timestamp" column and one
+// column per metric. JSONL traces have one object per line with a "time" or
+// "timestamp" field and either top-level metric fields or a "metrics" object.
+// Times are RFC 3339 or Unix seconds.
+func ReadTrace(r io.Reader, format Format) (*Trace, error) {
+	var samples []Sample
+	var err error
+	switch format {
+	case FormatCSV:
+		samples, err = readCSV(r)
+	case FormatJSONL:
+		samples, err = readJSONL(r)
+	default:
+		return nil, fmt.Errorf("invalid trace format: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("trace has no samples")
+	}
+
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Time.Before(samples[j].Time)
+	})
+
+	seen := map[string]bool{}
+	trace := &Trace{Samples: samples}
+	for _, sample := range samples {
+		for name := range sample.Metrics {
+			if !seen[name] {
+				seen[name] = true
+				trace.Metrics = append(trace.Metrics, name)
+			}
+		}
+	}
+	if len(trace.Metrics) == 0 {
+		return nil, fmt.Errorf("trace has no metrics")
+	}
+	sort.Strings(trace.Metrics)
+	return trace, nil
+}
+
+func readCSV(r io.Reader) ([]Sample, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trace header: %w", err)
+	}
+
+	timeColumn := -1
+	for i, name := range header {
+		header[i] = strings.TrimSpace(name)
+		if isTimeField(header[i]) {
+			timeColumn = i
+		}
+	}
+	if timeColumn < 0 {
+		return nil, fmt.Errorf("trace header has no time column")
+	}
+
+	var samples []Sample
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read trace line %d: %w", line, err)
+		}
+
+		at, err := parseTime(record[timeColumn])
+		if err != nil {
+			return nil, fmt.Errorf("trace line %d: %w", line, err)
+		}
+		sample := Sample{Time: at, Metrics: map[string]float64{}}
+		for i, field := range record {
+			field = strings.TrimSpace(field)
+			if i == timeColumn || field == "" {
+				continue
+			}
+			value, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, fmt.Errorf("trace line %d: invalid %s value: %s", line, header[i], field)
+			}
+			sample.Metrics[header[i]] = value
+		}
+		samples = append(samples, sample)
+	}
+	return samples, nil
+}
+
+func readJSONL(r io.Reader) ([]Sample, error) {
+	var samples []Sample
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(text), &fields); err != nil {
+			return nil, fmt.Errorf("trace line %d: %w", line, err)
+		}
+
+		sample := Sample{Metrics: map[string]float64{}}
+		hasTime := false
+		for name, raw := range fields {
+			switch {
+			case isTimeField(name):
+				var value any
+				if err := json.Unmarshal(raw, &value); err != nil {
+					return nil, fmt.Errorf("trace line %d: %w", line, err)
+				}
+				at, err := parseTime(fmt.Sprint(value))
+				if err != nil {
+					return nil, fmt.Errorf("trace line %d: %w", line, err)
+				}
+				sample.Time = at
+				hasTime = true
+			case name == "metrics":
+				var metrics map[string]float64
+				if err := json.Unmarshal(raw, &metrics); err != nil {
+					return nil, fmt.Errorf("trace line %d: invalid metrics: %w", line, err)
+				}
+				for metric, value := range metrics {
+					sample.Metrics[metric] = value
+				}
+			default:
+				var value float64
+				if err := json.Unmarshal(raw, &value); err != nil {
+					return nil, fmt.Errorf("trace line %d: invalid %s value: %s", line, name, raw)
+				}
+				sample.Metrics[name] = value
+			}
+		}
+		if !hasTime {
+			return nil, fmt.Errorf("trace line %d: missing time", line)
+		}
+		samples = append(samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read trace: %w", err)
+	}
+	return samples, nil
+}
+
+func isTimeField(name string) bool {
+	name = strings.ToLower(name)
+	return name == "time" || name == "timestamp"
+}
+
+func parseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if at, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return at, nil
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time value: %s", value)
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))).UTC(), nil
+}
@@ -2,8 +2,10 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -12,74 +14,922 @@ type Config struct {
 	TargetResource             string
 	Steps                      uint
 	DryRun                     bool
+	RecommendOnly              bool
 	WaitForActiveTimeout       time.Duration
 	WaitForActiveCheckInterval time.Duration
+	MaxObservationAge          time.Duration
+	StatePolicies              map[string]string
+	EvaluationInterval         time.Duration
+	MinCapacity                int
+	MaxCapacity                int
+	MetricSources              []MetricSourceConfig
+	Policy                     PolicyConfig
+	Policies                   []PolicyConfig
+	PolicyStrategy             string
+	ShadowPolicies             []PolicyConfig
+	Idle                       IdleConfig
+	Proxy                      ProxyConfig
+	Simulation                 SimulationConfig
+	Sidecar                    SidecarConfig
+	Recovery                   RecoveryConfig
+	Auth                       AuthConfig
+	TLS                        TLSConfig
+	ConfigFile                 string
+	ConfigWatchInterval        time.Duration
+	OverridesFile              string
+	AuditFile                  string
 }
 
-// NewConfigFromEnv loads configuration from environment variables
+// MetricSourceConfig describes a Prometheus exposition endpoint set to scrape.
+// Threshold applies to histogram families, which are reported as the fraction
+// of observations above it.
+type MetricSourceConfig struct {
+	Name        string
+	URLs        []string
+	Family      string
+	Labels      map[string]string
+	Aggregation string
+	Threshold   float64
+}
+
+// IdleConfig describes when capacity is removed entirely and how much is
+// restored once demand reappears
+type IdleConfig struct {
+	Metric       string
+	Threshold    float64
+	Period       time.Duration
+	WakeCapacity int
+}
+
+// Enabled reports whether scale-to-zero is configured
+func (c IdleConfig) Enabled() bool {
+	return c.Period > 0
+}
+
+// ProxyConfig describes the optional request-counting reverse proxy placed in
+// front of the scaled service
+type ProxyConfig struct {
+	Target      string
+	Port        string
+	HoldTimeout time.Duration
+	Window      time.Duration
+}
+
+// Enabled reports whether the reverse proxy should be started
+func (c ProxyConfig) Enabled() bool {
+	return c.Target != ""
+}
+
+// SimulationConfig describes the simulated resource that stands in for the
+// sidecar in dry-run mode. Each change of capacity keeps the resource STARTING
+// for the scale up or scale down delay, and ends in FAILED at the given rate.
+// A failed resource recovers after FailureDuration, or never when it is zero.
+type SimulationConfig struct {
+	InitialCapacity int
+	ScaleUpDelay    time.Duration
+	ScaleDownDelay  time.Duration
+	MaxReplicas     int
+	Latency         time.Duration
+	FailureRate     float64
+	FailureDuration time.Duration
+}
+
+// RecoveryConfig describes how the autoscaler reacts when the instance enters
+// FAILED during a scaling operation. Retries and rollbacks wait Backoff before
+// the first attempt, doubling it for every further one. BreakerThreshold
+// consecutive failures block scaling until the circuit breaker is reset, and
+// NotifyURL receives a webhook for every event an operator has to look at.
+type RecoveryConfig struct {
+	Policy           string
+	Backoff          time.Duration
+	MaxRetries       int
+	BreakerThreshold int
+	NotifyURL        string
+}
+
+// AuthConfig describes how callers of the controller API authenticate. Static
+// API keys are checked against the hashes in APIKeysFile, HMAC-signed tokens
+// against the secret in HMACSecretFile, and JWTs against the keys in JWKS, a
+// file or an http(s) URL fetched again every JWKSRefresh. The role of a JWT's
+// subject is read from the JWTRolesClaim claim.
+//
+// Users sign in to the dashboard with a password checked against the bcrypt
+// hashes in UsersFile, or through the OpenID Connect provider OIDCIssuer, which
+// redirects back to OIDCRedirectURL. They stay signed in for SessionTTL.
+// Authentication is disabled when none of these is configured.
+type AuthConfig struct {
+	APIKeysFile          string
+	HMACSecretFile       string
+	JWKS                 string
+	JWKSRefresh          time.Duration
+	JWTIssuer            string
+	JWTAudience          string
+	JWTRolesClaim        string
+	UsersFile            string
+	OIDCIssuer           string
+	OIDCClientID         string
+	OIDCClientSecretFile string
+	OIDCRedirectURL      string
+	SessionTTL           time.Duration
+}
+
+// Enabled reports whether callers have to authenticate
+func (c AuthConfig) Enabled() bool {
+	return c.APIEnabled() || c.LoginEnabled()
+}
+
+// APIEnabled reports whether API credentials are accepted
+func (c AuthConfig) APIEnabled() bool {
+	return c.APIKeysFile != "" || c.HMACSecretFile != "" || c.JWKS != ""
+}
+
+// LoginEnabled reports whether users sign in to the dashboard
+func (c AuthConfig) LoginEnabled() bool {
+	return c.UsersFile != "" || c.OIDCIssuer != ""
+}
+
+// TLSConfig describes the optional TLS of the controller's API. CertFile and
+// KeyFile are read again every ReloadInterval, so that renewed certificates
+// are served without a restart. With ClientCAFile, client certificates signed
+// by one of its CAs are verified and authenticate their holder; they are
+// required of every client with RequireClientCert.
+type TLSConfig struct {
+	CertFile          string
+	KeyFile           string
+	ClientCAFile      string
+	RequireClientCert bool
+	ReloadInterval    time.Duration
+}
+
+// Enabled reports whether the API is served over TLS
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// SidecarConfig describes how to reach the Omnistrate sidecar API. URL is an
+// http:// or https:// base URL, or unix:// followed by the path of a socket.
+// CAFile, CertFile and KeyFile configure TLS for https:// URLs. RateLimit
+// bounds the requests per second sent to the sidecar (0 = unlimited), and
+// MaxRetryAfter caps how long a Retry-After from the sidecar is honored and
+// must be shorter than Timeout.
+type SidecarConfig struct {
+	URL                string
+	Timeout            time.Duration
+	RetryMax           int
+	RetryWaitMin       time.Duration
+	RetryWaitMax       time.Duration
+	RateLimit          float64
+	Burst              int
+	MaxRetryAfter      time.Duration
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// PolicyConfig describes the policy that turns metric readings into a desired
+// capacity. Name and Weight are only used when several policies are composed.
+// Inputs lists the metric sources available to expression policies. URL,
+// Timeout and Fallback describe an external policy server and the policy used
+// while it cannot be reached.
+type PolicyConfig struct {
+	Name       string
+	Type       string
+	Metric     string
+	Target     float64
+	Weight     float64
+	Expression string
+	Timezone   string
+	Inputs     []string
+	URL        string
+	Timeout    time.Duration
+	Fallback   *PolicyConfig
+	SLO        SLOConfig
+}
+
+// SLOConfig describes an error budget and the burn rates that trigger scaling.
+// Objective is the fraction of events that must be good, e.g. 0.95 for p95.
+type SLOConfig struct {
+	Objective         float64
+	ShortWindow       time.Duration
+	LongWindow        time.Duration
+	ShortBurnRate     float64
+	LongBurnRate      float64
+	ScaleDownBurnRate float64
+	Step              int
+}
+
+// ValidationError lists every problem found while loading a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0]
+	}
+	return fmt.Sprintf("%d configuration problems:\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// NewConfigFromEnv loads configuration from environment variables, on top of
+// the configuration file named by AUTOSCALER_CONFIG_FILE when it is set
 func NewConfigFromEnv() (*Config, error) {
-	// Get cooldown duration
-	cooldownStr := os.Getenv("AUTOSCALER_COOLDOWN")
-	if cooldownStr == "" {
-		cooldownStr = "300" // Default 5 minutes
+	return Load(os.Getenv("AUTOSCALER_CONFIG_FILE"), os.LookupEnv)
+}
+
+// Load reads the configuration file at path, if any, and applies the
+// environment variables returned by lookupEnv on top of it. Every problem
+// found is reported at once in a *ValidationError.
+func Load(path string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	l := &loader{lookupEnv: lookupEnv}
+	if path != "" {
+		file, problems, err := loadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		values, valueProblems := file.values()
+		l.file = values
+		l.problems = append(problems, valueProblems...)
 	}
-	cooldownSeconds, err := strconv.Atoi(cooldownStr)
-	if err != nil {
-		return nil, fmt.Errorf("invalid AUTOSCALER_COOLDOWN value: %s", cooldownStr)
+
+	cfg := l.config()
+	if len(l.problems) > 0 {
+		return nil, &ValidationError{Problems: l.problems}
+	}
+	cfg.ConfigFile = path
+	return cfg, nil
+}
+
+// config reads every setting, recording problems instead of stopping at the first one
+func (l *loader) config() *Config {
+	// Get cooldown duration
+	cooldown := l.seconds("AUTOSCALER_COOLDOWN", 300) // Default 5 minutes
+	if cooldown < 0 {
+		l.errorf("%s must not be negative", l.name("AUTOSCALER_COOLDOWN"))
 	}
 
 	// Get target resource
-	targetResource := os.Getenv("AUTOSCALER_TARGET_RESOURCE")
+	targetResource := l.get("AUTOSCALER_TARGET_RESOURCE")
 	if targetResource == "" {
-		return nil, fmt.Errorf("AUTOSCALER_TARGET_RESOURCE environment variable is required")
+		l.errorf("AUTOSCALER_TARGET_RESOURCE environment variable is required")
 	}
 
 	// Get steps
-	stepsStr := os.Getenv("AUTOSCALER_STEPS")
-	if stepsStr == "" {
-		stepsStr = "1" // Default 1 step
+	steps := l.int("AUTOSCALER_STEPS", 1) // Default 1 step
+	if steps < 1 {
+		l.errorf("%s must be at least 1, got %d", l.name("AUTOSCALER_STEPS"), steps)
+		steps = 1
 	}
-	steps, err := strconv.Atoi(stepsStr)
-	if err != nil {
-		return nil, fmt.Errorf("invalid AUTOSCALER_STEPS value: %s", stepsStr)
+
+	// Get dry run and recommend-only flags
+	dryRun := l.bool("DRY_RUN", false)
+	recommendOnly := l.bool("AUTOSCALER_RECOMMEND_ONLY", false)
+
+	// Get wait for active timeout and check interval
+	waitForActiveTimeout := l.seconds("AUTOSCALER_WAIT_FOR_ACTIVE_TIMEOUT", 900)             // Default 15 minutes
+	waitForActiveCheckInterval := l.seconds("AUTOSCALER_WAIT_FOR_ACTIVE_CHECK_INTERVAL", 30) // Default 30 seconds
+	if waitForActiveTimeout < 0 {
+		l.errorf("%s must not be negative", l.name("AUTOSCALER_WAIT_FOR_ACTIVE_TIMEOUT"))
+	}
+	if waitForActiveCheckInterval <= 0 {
+		l.errorf("%s must be positive", l.name("AUTOSCALER_WAIT_FOR_ACTIVE_CHECK_INTERVAL"))
 	}
 
-	// Get dry run flag
-	dryRunStr := os.Getenv("DRY_RUN")
-	dryRun := false // Default to false
-	if dryRunStr != "" {
-		dryRun, err = strconv.ParseBool(dryRunStr)
-		if err != nil {
-			return nil, fmt.Errorf("invalid DRY_RUN value: %s", dryRunStr)
-		}
+	// Get the age after which capacity observations of the sidecar are stale.
+	// Staleness checks are off unless enabled.
+	maxObservationAge := l.seconds("AUTOSCALER_MAX_OBSERVATION_AGE", 0)
+	if maxObservationAge < 0 {
+		l.errorf("%s must not be negative", l.name("AUTOSCALER_MAX_OBSERVATION_AGE"))
 	}
 
-	// Get wait for active timeout
-	waitForActiveTimeoutStr := os.Getenv("AUTOSCALER_WAIT_FOR_ACTIVE_TIMEOUT")
-	if waitForActiveTimeoutStr == "" {
-		waitForActiveTimeoutStr = "900" // Default 15 minutes
+	// Get how instance statuses other than ACTIVE are handled
+	statePolicies := l.labels("AUTOSCALER_STATE_POLICIES")
+
+	// Get policy evaluation interval
+	evaluationInterval := l.seconds("AUTOSCALER_EVALUATION_INTERVAL", 30)
+	if evaluationInterval <= 0 {
+		l.errorf("%s must be positive", l.name("AUTOSCALER_EVALUATION_INTERVAL"))
 	}
-	waitForActiveTimeoutSeconds, err := strconv.Atoi(waitForActiveTimeoutStr)
-	if err != nil {
-		return nil, fmt.Errorf("invalid AUTOSCALER_WAIT_FOR_ACTIVE_TIMEOUT value: %s", waitForActiveTimeoutStr)
+
+	// Get capacity bounds
+	minCapacity := l.int("AUTOSCALER_MIN_CAPACITY", 0)
+	maxCapacity := l.int("AUTOSCALER_MAX_CAPACITY", 0)
+	if minCapacity < 0 || maxCapacity < 0 {
+		l.errorf("%s and %s must be non-negative", l.name("AUTOSCALER_MIN_CAPACITY"), l.name("AUTOSCALER_MAX_CAPACITY"))
+	} else if maxCapacity > 0 && minCapacity > maxCapacity {
+		l.errorf("%s (%d) exceeds %s (%d)", l.name("AUTOSCALER_MIN_CAPACITY"), minCapacity, l.name("AUTOSCALER_MAX_CAPACITY"), maxCapacity)
 	}
 
-	// Get wait for active check interval
-	waitForActiveCheckIntervalStr := os.Getenv("AUTOSCALER_WAIT_FOR_ACTIVE_CHECK_INTERVAL")
-	if waitForActiveCheckIntervalStr == "" {
-		waitForActiveCheckIntervalStr = "30" // Default 30 seconds
+	// Get metric sources
+	metricSources := l.metricSources()
+
+	// Get scaling policy
+	policy := l.policy("AUTOSCALER_POLICY_")
+
+	// Get composed policies
+	policies := l.policies()
+	if len(policies) > 0 && policy.Type != "" {
+		l.errorf("%s cannot be combined with %s", l.name("AUTOSCALER_POLICY_TYPE"), l.name("AUTOSCALER_POLICIES"))
 	}
-	waitForActiveCheckIntervalSeconds, err := strconv.Atoi(waitForActiveCheckIntervalStr)
-	if err != nil {
-		return nil, fmt.Errorf("invalid AUTOSCALER_WAIT_FOR_ACTIVE_CHECK_INTERVAL value: %s", waitForActiveCheckIntervalStr)
+	policyStrategy := l.get("AUTOSCALER_POLICY_STRATEGY")
+	if policyStrategy == "" {
+		policyStrategy = "max"
+	}
+
+	// Get shadow policies
+	shadowPolicies := l.shadowPolicies()
+
+	// Get how often the configuration file is checked for changes
+	configWatchInterval := l.seconds("AUTOSCALER_CONFIG_WATCH_INTERVAL", 10)
+	if configWatchInterval < 0 {
+		l.errorf("%s must not be negative", l.name("AUTOSCALER_CONFIG_WATCH_INTERVAL"))
+	}
+
+	// Get where runtime overrides and the audit trail are kept. They are only
+	// kept in memory unless a file is set.
+	overridesFile := l.get("AUTOSCALER_OVERRIDES_FILE")
+	auditFile := l.get("AUTOSCALER_AUDIT_FILE")
+
+	// Get scale-to-zero settings
+	idle := l.idle()
+	if idle.Enabled() && minCapacity > 0 {
+		l.errorf("%s requires %s to be 0", l.name("AUTOSCALER_IDLE_PERIOD"), l.name("AUTOSCALER_MIN_CAPACITY"))
 	}
 
-	return &Config{
-		CooldownDuration:           time.Duration(cooldownSeconds) * time.Second,
+	cfg := &Config{
+		CooldownDuration:           cooldown,
 		TargetResource:             targetResource,
 		Steps:                      uint(steps),
 		DryRun:                     dryRun,
-		WaitForActiveTimeout:       time.Duration(waitForActiveTimeoutSeconds) * time.Second,
-		WaitForActiveCheckInterval: time.Duration(waitForActiveCheckIntervalSeconds) * time.Second,
-	}, nil
+		RecommendOnly:              recommendOnly,
+		WaitForActiveTimeout:       waitForActiveTimeout,
+		WaitForActiveCheckInterval: waitForActiveCheckInterval,
+		MaxObservationAge:          maxObservationAge,
+		StatePolicies:              statePolicies,
+		EvaluationInterval:         evaluationInterval,
+		MinCapacity:                minCapacity,
+		MaxCapacity:                maxCapacity,
+		MetricSources:              metricSources,
+		Policy:                     policy,
+		Policies:                   policies,
+		PolicyStrategy:             policyStrategy,
+		ShadowPolicies:             shadowPolicies,
+		Idle:                       idle,
+		Proxy:                      l.proxy(),
+		Simulation:                 l.simulation(),
+		Sidecar:                    l.sidecar(),
+		Recovery:                   l.recovery(),
+		Auth:                       l.auth(),
+		TLS:                        l.tls(),
+		ConfigWatchInterval:        configWatchInterval,
+		OverridesFile:              overridesFile,
+		AuditFile:                  auditFile,
+	}
+
+	// Make every metric source available to expression policies
+	var inputs []string
+	for _, source := range metricSources {
+		inputs = append(inputs, source.Name)
+	}
+	if cfg.Proxy.Enabled() {
+		inputs = append(inputs, "concurrency", "rps")
+	}
+	cfg.SetMetricInputs(inputs)
+
+	return cfg
+}
+
+func (l *loader) proxy() ProxyConfig {
+	holdTimeout := l.seconds("AUTOSCALER_PROXY_HOLD_TIMEOUT", 60)
+	window := l.seconds("AUTOSCALER_PROXY_WINDOW", 60)
+	if window < time.Second {
+		l.errorf("%s must be at least 1 second", l.name("AUTOSCALER_PROXY_WINDOW"))
+	}
+	port := l.get("AUTOSCALER_PROXY_PORT")
+	if port == "" {
+		port = "8080"
+	}
+	return ProxyConfig{
+		Target:      l.get("AUTOSCALER_PROXY_TARGET"),
+		Port:        port,
+		HoldTimeout: holdTimeout,
+		Window:      window,
+	}
+}
+
+func (l *loader) sidecar() SidecarConfig {
+	sidecarURL := l.get("AUTOSCALER_SIDECAR_URL")
+	if sidecarURL == "" {
+		sidecarURL = "http://127.0.0.1:49750"
+	}
+	if parsed, err := url.Parse(sidecarURL); err != nil {
+		l.errorf("invalid %s value: %s", l.name("AUTOSCALER_SIDECAR_URL"), sidecarURL)
+	} else {
+		switch parsed.Scheme {
+		case "http", "https":
+			if parsed.Host == "" {
+				l.errorf("invalid %s value: %s", l.name("AUTOSCALER_SIDECAR_URL"), sidecarURL)
+			}
+		case "unix":
+			if parsed.Path == "" {
+				l.errorf("%s must name a socket path, e.g. unix:///var/run/sidecar.sock", l.name("AUTOSCALER_SIDECAR_URL"))
+			}
+		default:
+			l.errorf("%s must be an http, https or unix URL", l.name("AUTOSCALER_SIDECAR_URL"))
+		}
+	}
+
+	timeout := l.seconds("AUTOSCALER_SIDECAR_TIMEOUT", 60)
+	if timeout <= 0 {
+		l.errorf("%s must be positive", l.name("AUTOSCALER_SIDECAR_TIMEOUT"))
+	}
+	retryMax := l.int("AUTOSCALER_SIDECAR_RETRY_MAX", 3)
+	retryWaitMin := l.seconds("AUTOSCALER_SIDECAR_RETRY_WAIT_MIN", 1)
+	retryWaitMax := l.seconds("AUTOSCALER_SIDECAR_RETRY_WAIT_MAX", 30)
+	if retryMax < 0 || retryWaitMin < 0 || retryWaitMax < retryWaitMin {
+		l.errorf("%s must not be negative and %s must be at least %s",
+			l.name("AUTOSCALER_SIDECAR_RETRY_MAX"), l.name("AUTOSCALER_SIDECAR_RETRY_WAIT_MAX"), l.name("AUTOSCALER_SIDECAR_RETRY_WAIT_MIN"))
+	}
+
+	rateLimit := l.float("AUTOSCALER_SIDECAR_RATE_LIMIT", 5)
+	burst := l.int("AUTOSCALER_SIDECAR_BURST", 10)
+	// A Retry-After is capped below the request timeout, at 30 seconds unless
+	// the timeout is shorter. The default is kept to the millisecond, since half
+	// a timeout of a second in whole seconds is 0, which leaves it uncapped.
+	maxRetryAfter := min(30*time.Second, timeout/2)
+	if l.get("AUTOSCALER_SIDECAR_MAX_RETRY_AFTER") != "" {
+		maxRetryAfter = l.seconds("AUTOSCALER_SIDECAR_MAX_RETRY_AFTER", 0)
+	}
+	if rateLimit < 0 || burst < 1 || maxRetryAfter < 0 {
+		l.errorf("%s and %s must not be negative and %s must be at least 1",
+			l.name("AUTOSCALER_SIDECAR_RATE_LIMIT"), l.name("AUTOSCALER_SIDECAR_MAX_RETRY_AFTER"), l.name("AUTOSCALER_SIDECAR_BURST"))
+	}
+	if timeout > 0 && maxRetryAfter >= timeout {
+		l.errorf("%s must be less than %s", l.name("AUTOSCALER_SIDECAR_MAX_RETRY_AFTER"), l.name("AUTOSCALER_SIDECAR_TIMEOUT"))
+	}
+
+	certFile := l.get("AUTOSCALER_SIDECAR_CERT_FILE")
+	keyFile := l.get("AUTOSCALER_SIDECAR_KEY_FILE")
+	if (certFile == "") != (keyFile == "") {
+		l.errorf("%s and %s must be set together", l.name("AUTOSCALER_SIDECAR_CERT_FILE"), l.name("AUTOSCALER_SIDECAR_KEY_FILE"))
+	}
+
+	return SidecarConfig{
+		URL:                strings.TrimSuffix(sidecarURL, "/"),
+		Timeout:            timeout,
+		RetryMax:           retryMax,
+		RetryWaitMin:       retryWaitMin,
+		RetryWaitMax:       retryWaitMax,
+		RateLimit:          rateLimit,
+		Burst:              burst,
+		MaxRetryAfter:      maxRetryAfter,
+		CAFile:             l.get("AUTOSCALER_SIDECAR_CA_FILE"),
+		CertFile:           certFile,
+		KeyFile:            keyFile,
+		ServerName:         l.get("AUTOSCALER_SIDECAR_SERVER_NAME"),
+		InsecureSkipVerify: l.bool("AUTOSCALER_SIDECAR_INSECURE_SKIP_VERIFY", false),
+	}
+}
+
+func (l *loader) tls() TLSConfig {
+	certFile := l.get("AUTOSCALER_TLS_CERT_FILE")
+	keyFile := l.get("AUTOSCALER_TLS_KEY_FILE")
+	if (certFile == "") != (keyFile == "") {
+		l.errorf("%s and %s must be set together", l.name("AUTOSCALER_TLS_CERT_FILE"), l.name("AUTOSCALER_TLS_KEY_FILE"))
+	}
+	clientCAFile := l.get("AUTOSCALER_TLS_CLIENT_CA_FILE")
+	if clientCAFile != "" && certFile == "" {
+		l.errorf("%s requires %s", l.name("AUTOSCALER_TLS_CLIENT_CA_FILE"), l.name("AUTOSCALER_TLS_CERT_FILE"))
+	}
+	requireClientCert := l.bool("AUTOSCALER_TLS_REQUIRE_CLIENT_CERT", false)
+	if requireClientCert && clientCAFile == "" {
+		l.errorf("%s requires %s", l.name("AUTOSCALER_TLS_REQUIRE_CLIENT_CERT"), l.name("AUTOSCALER_TLS_CLIENT_CA_FILE"))
+	}
+	reloadInterval := l.seconds("AUTOSCALER_TLS_RELOAD_INTERVAL", 60)
+	if reloadInterval < 0 {
+		l.errorf("%s must not be negative", l.name("AUTOSCALER_TLS_RELOAD_INTERVAL"))
+	}
+	return TLSConfig{
+		CertFile:          certFile,
+		KeyFile:           keyFile,
+		ClientCAFile:      clientCAFile,
+		RequireClientCert: requireClientCert,
+		ReloadInterval:    reloadInterval,
+	}
+}
+
+func (l *loader) recovery() RecoveryConfig {
+	policy := l.get("AUTOSCALER_RECOVERY_POLICY")
+	if policy == "" {
+		policy = "abort"
+	}
+	backoff := l.seconds("AUTOSCALER_RECOVERY_BACKOFF", 60)
+	maxRetries := l.int("AUTOSCALER_RECOVERY_MAX_RETRIES", 3)
+	// The circuit breaker only trips by default when a recovery policy is
+	// configured, so that aborting on FAILED never blocks scaling on its own
+	defaultBreakerThreshold := 3
+	if policy == "abort" {
+		defaultBreakerThreshold = 0
+	}
+	breakerThreshold := l.int("AUTOSCALER_CIRCUIT_BREAKER_THRESHOLD", defaultBreakerThreshold)
+	if backoff < 0 || maxRetries < 0 || breakerThreshold < 0 {
+		l.errorf("%s, %s and %s must not be negative",
+			l.name("AUTOSCALER_RECOVERY_BACKOFF"), l.name("AUTOSCALER_RECOVERY_MAX_RETRIES"), l.name("AUTOSCALER_CIRCUIT_BREAKER_THRESHOLD"))
+	}
+	notifyURL := l.get("AUTOSCALER_NOTIFY_URL")
+	if notifyURL != "" {
+		if parsed, err := url.Parse(notifyURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			l.errorf("invalid %s value: %s", l.name("AUTOSCALER_NOTIFY_URL"), notifyURL)
+		}
+	}
+	return RecoveryConfig{
+		Policy:           policy,
+		Backoff:          backoff,
+		MaxRetries:       maxRetries,
+		BreakerThreshold: breakerThreshold,
+		NotifyURL:        notifyURL,
+	}
+}
+
+func (l *loader) auth() AuthConfig {
+	jwks := l.get("AUTOSCALER_AUTH_JWKS")
+	if strings.Contains(jwks, "://") {
+		if !isHTTPURL(jwks) {
+			l.errorf("%s must be a file or an http or https URL, got %s", l.name("AUTOSCALER_AUTH_JWKS"), jwks)
+		}
+	}
+	jwksRefresh := l.seconds("AUTOSCALER_AUTH_JWKS_REFRESH", 300)
+	if jwksRefresh <= 0 {
+		l.errorf("%s must be positive", l.name("AUTOSCALER_AUTH_JWKS_REFRESH"))
+	}
+	rolesClaim := l.get("AUTOSCALER_AUTH_JWT_ROLES_CLAIM")
+	if rolesClaim == "" {
+		rolesClaim = "roles"
+	}
+	oidcIssuer := l.get("AUTOSCALER_AUTH_OIDC_ISSUER")
+	oidcClientID := l.get("AUTOSCALER_AUTH_OIDC_CLIENT_ID")
+	oidcRedirectURL := l.get("AUTOSCALER_AUTH_OIDC_REDIRECT_URL")
+	if oidcIssuer != "" {
+		if !isHTTPURL(oidcIssuer) {
+			l.errorf("%s must be an http or https URL, got %s", l.name("AUTOSCALER_AUTH_OIDC_ISSUER"), oidcIssuer)
+		}
+		if oidcClientID == "" {
+			l.errorf("%s is required with %s", l.name("AUTOSCALER_AUTH_OIDC_CLIENT_ID"), l.name("AUTOSCALER_AUTH_OIDC_ISSUER"))
+		}
+		if !isHTTPURL(oidcRedirectURL) {
+			l.errorf("%s must be the http or https URL of /login/callback with %s", l.name("AUTOSCALER_AUTH_OIDC_REDIRECT_URL"), l.name("AUTOSCALER_AUTH_OIDC_ISSUER"))
+		}
+	}
+	sessionTTL := l.seconds("AUTOSCALER_AUTH_SESSION_TTL", 28800)
+	if sessionTTL <= 0 {
+		l.errorf("%s must be positive", l.name("AUTOSCALER_AUTH_SESSION_TTL"))
+	}
+	return AuthConfig{
+		APIKeysFile:          l.get("AUTOSCALER_AUTH_API_KEYS_FILE"),
+		HMACSecretFile:       l.get("AUTOSCALER_AUTH_HMAC_SECRET_FILE"),
+		JWKS:                 jwks,
+		JWKSRefresh:          jwksRefresh,
+		JWTIssuer:            l.get("AUTOSCALER_AUTH_JWT_ISSUER"),
+		JWTAudience:          l.get("AUTOSCALER_AUTH_JWT_AUDIENCE"),
+		JWTRolesClaim:        rolesClaim,
+		UsersFile:            l.get("AUTOSCALER_AUTH_USERS_FILE"),
+		OIDCIssuer:           oidcIssuer,
+		OIDCClientID:         oidcClientID,
+		OIDCClientSecretFile: l.get("AUTOSCALER_AUTH_OIDC_CLIENT_SECRET_FILE"),
+		OIDCRedirectURL:      oidcRedirectURL,
+		SessionTTL:           sessionTTL,
+	}
+}
+
+// isHTTPURL reports whether a setting is an absolute http or https URL
+func isHTTPURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+func (l *loader) simulation() SimulationConfig {
+	initialCapacity := l.int("DRY_RUN_INITIAL_CAPACITY", 1)
+	if initialCapacity < 0 {
+		l.errorf("%s must not be negative", l.name("DRY_RUN_INITIAL_CAPACITY"))
+	}
+	scaleUpDelay := l.seconds("DRY_RUN_SCALE_UP_DELAY", 30)
+	scaleDownDelay := l.seconds("DRY_RUN_SCALE_DOWN_DELAY", 10)
+	maxReplicas := l.int("DRY_RUN_MAX_REPLICAS", 0)
+	latencyMillis := l.int("DRY_RUN_LATENCY_MS", 0)
+	failureRate := l.float("DRY_RUN_FAILURE_RATE", 0)
+	if failureRate < 0 || failureRate > 1 {
+		l.errorf("%s must be between 0 and 1", l.name("DRY_RUN_FAILURE_RATE"))
+	}
+	failureDuration := l.seconds("DRY_RUN_FAILURE_DURATION", 0)
+	if scaleUpDelay < 0 || scaleDownDelay < 0 || maxReplicas < 0 || latencyMillis < 0 || failureDuration < 0 {
+		l.errorf("dry-run delays, latency and max replicas must not be negative")
+	}
+	return SimulationConfig{
+		InitialCapacity: initialCapacity,
+		ScaleUpDelay:    scaleUpDelay,
+		ScaleDownDelay:  scaleDownDelay,
+		MaxReplicas:     maxReplicas,
+		Latency:         time.Duration(latencyMillis) * time.Millisecond,
+		FailureRate:     failureRate,
+		FailureDuration: failureDuration,
+	}
+}
+
+func (l *loader) idle() IdleConfig {
+	idle := IdleConfig{
+		Metric:       l.get("AUTOSCALER_IDLE_METRIC"),
+		Threshold:    l.float("AUTOSCALER_IDLE_THRESHOLD", 0),
+		Period:       l.seconds("AUTOSCALER_IDLE_PERIOD", 0),
+		WakeCapacity: l.int("AUTOSCALER_WAKE_CAPACITY", 1),
+	}
+	if idle.Enabled() && idle.Metric == "" {
+		l.errorf("%s is required when %s is set", l.name("AUTOSCALER_IDLE_METRIC"), l.name("AUTOSCALER_IDLE_PERIOD"))
+	}
+	if idle.WakeCapacity < 1 {
+		l.errorf("%s must be at least 1", l.name("AUTOSCALER_WAKE_CAPACITY"))
+	}
+	return idle
+}
+
+// metricSources loads the sources listed in AUTOSCALER_METRICS. Each source
+// NAME is configured through AUTOSCALER_METRIC_<NAME>_* variables.
+func (l *loader) metricSources() []MetricSourceConfig {
+	var sources []MetricSourceConfig
+	for _, name := range l.list("AUTOSCALER_METRICS") {
+		prefix := "AUTOSCALER_METRIC_" + envName(name) + "_"
+		source := MetricSourceConfig{
+			Name:        name,
+			URLs:        l.list(prefix + "URLS"),
+			Family:      l.get(prefix + "FAMILY"),
+			Aggregation: l.get(prefix + "AGGREGATION"),
+			Threshold:   l.float(prefix+"THRESHOLD", 0),
+			Labels:      l.labels(prefix + "LABELS"),
+		}
+		if len(source.URLs) == 0 {
+			l.errorf("%s is required for metric source %s", l.name(prefix+"URLS"), name)
+		}
+		if source.Family == "" {
+			l.errorf("%s is required for metric source %s", l.name(prefix+"FAMILY"), name)
+		}
+		if source.Aggregation == "" {
+			source.Aggregation = "avg"
+		}
+		sources = append(sources, source)
+	}
+	return sources
+}
+
+// policy loads a policy whose variables share the given prefix
+func (l *loader) policy(prefix string) PolicyConfig {
+	timeout := l.seconds(prefix+"TIMEOUT", 5)
+	if timeout <= 0 {
+		l.errorf("%s must be positive", l.name(prefix+"TIMEOUT"))
+	}
+	var fallback *PolicyConfig
+	if l.get(prefix+"FALLBACK_TYPE") != "" {
+		fallbackConfig := l.policy(prefix + "FALLBACK_")
+		fallback = &fallbackConfig
+	}
+	return PolicyConfig{
+		Type:       l.get(prefix + "TYPE"),
+		Metric:     l.get(prefix + "METRIC"),
+		Target:     l.float(prefix+"TARGET", 0),
+		Expression: l.get(prefix + "EXPRESSION"),
+		Timezone:   l.get(prefix + "TIMEZONE"),
+		URL:        l.get(prefix + "URL"),
+		Timeout:    timeout,
+		Fallback:   fallback,
+		SLO:        l.slo(prefix),
+	}
+}
+
+// Clone returns a deep copy of the configuration
+func (c *Config) Clone() *Config {
+	clone := *c
+	clone.StatePolicies = cloneMap(c.StatePolicies)
+	clone.MetricSources = nil
+	for _, source := range c.MetricSources {
+		source.URLs = append([]string(nil), source.URLs...)
+		source.Labels = cloneMap(source.Labels)
+		clone.MetricSources = append(clone.MetricSources, source)
+	}
+	clone.Policy = c.Policy.clone()
+	clone.Policies = clonePolicies(c.Policies)
+	clone.ShadowPolicies = clonePolicies(c.ShadowPolicies)
+	return &clone
+}
+
+// clone returns a deep copy of a policy and its fallbacks
+func (c PolicyConfig) clone() PolicyConfig {
+	c.Inputs = append([]string(nil), c.Inputs...)
+	if c.Fallback != nil {
+		fallback := c.Fallback.clone()
+		c.Fallback = &fallback
+	}
+	return c
+}
+
+func clonePolicies(policies []PolicyConfig) []PolicyConfig {
+	var clones []PolicyConfig
+	for _, policy := range policies {
+		clones = append(clones, policy.clone())
+	}
+	return clones
+}
+
+func cloneMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	clone := make(map[string]string, len(m))
+	for key, value := range m {
+		clone[key] = value
+	}
+	return clone
+}
+
+// SetMetricInputs makes the named metric sources available to every
+// configured policy, including composed, shadow and fallback policies
+func (c *Config) SetMetricInputs(inputs []string) {
+	c.Policy.setInputs(inputs)
+	for i := range c.Policies {
+		c.Policies[i].setInputs(inputs)
+	}
+	for i := range c.ShadowPolicies {
+		c.ShadowPolicies[i].setInputs(inputs)
+	}
+}
+
+// setInputs makes the metric sources available to a policy and its fallbacks
+func (c *PolicyConfig) setInputs(inputs []string) {
+	c.Inputs = inputs
+	if c.Fallback != nil {
+		c.Fallback.setInputs(inputs)
+	}
+}
+
+// policies loads the named policies listed in AUTOSCALER_POLICIES
+func (l *loader) policies() []PolicyConfig {
+	var policies []PolicyConfig
+	for _, name := range l.list("AUTOSCALER_POLICIES") {
+		prefix := "AUTOSCALER_POLICY_" + envName(name) + "_"
+		policy := l.policy(prefix)
+		if policy.Type == "" {
+			l.errorf("%s is required for policy %s", l.name(prefix+"TYPE"), name)
+		}
+		weight := l.float(prefix+"WEIGHT", 1)
+		if weight <= 0 {
+			l.errorf("%s must be positive, got %v", l.name(prefix+"WEIGHT"), weight)
+		}
+		policy.Name = name
+		policy.Weight = weight
+		policies = append(policies, policy)
+	}
+	return policies
+}
+
+// shadowPolicies loads the named policies listed in
+// AUTOSCALER_SHADOW_POLICIES, which are evaluated without ever scaling
+func (l *loader) shadowPolicies() []PolicyConfig {
+	var policies []PolicyConfig
+	for _, name := range l.list("AUTOSCALER_SHADOW_POLICIES") {
+		prefix := "AUTOSCALER_SHADOW_POLICY_" + envName(name) + "_"
+		policy := l.policy(prefix)
+		if policy.Type == "" {
+			l.errorf("%s is required for shadow policy %s", l.name(prefix+"TYPE"), name)
+		}
+		policy.Name = name
+		policies = append(policies, policy)
+	}
+	return policies
+}
+
+// slo loads the error budget settings of a policy
+func (l *loader) slo(prefix string) SLOConfig {
+	objective := l.float(prefix+"OBJECTIVE", 0.99)
+	if objective <= 0 || objective >= 1 {
+		l.errorf("%s must be between 0 and 1, got %v", l.name(prefix+"OBJECTIVE"), objective)
+	}
+	shortWindow := l.seconds(prefix+"SHORT_WINDOW", 300)
+	longWindow := l.seconds(prefix+"LONG_WINDOW", 3600)
+	if shortWindow <= 0 || longWindow < shortWindow {
+		l.errorf("%s must be positive and no longer than %s", l.name(prefix+"SHORT_WINDOW"), l.name(prefix+"LONG_WINDOW"))
+	}
+	step := l.int(prefix+"STEP", 1)
+	if step < 1 {
+		l.errorf("%s must be at least 1, got %d", l.name(prefix+"STEP"), step)
+	}
+	return SLOConfig{
+		Objective:         objective,
+		ShortWindow:       shortWindow,
+		LongWindow:        longWindow,
+		ShortBurnRate:     l.float(prefix+"SHORT_BURN_RATE", 2),
+		LongBurnRate:      l.float(prefix+"LONG_BURN_RATE", 1),
+		ScaleDownBurnRate: l.float(prefix+"SCALE_DOWN_BURN_RATE", 0.25),
+		Step:              step,
+	}
+}
+
+// envName converts a user supplied name into the form used inside variable names
+func envName(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// loader reads settings from environment variables, falling back to the
+// values of a configuration file, and records every problem it finds
+type loader struct {
+	lookupEnv func(string) (string, bool)
+	file      map[string]fileValue
+	problems  []string
+}
+
+// get returns the value of a setting, preferring the environment over the file
+func (l *loader) get(key string) string {
+	if value, ok := l.lookupEnv(key); ok && value != "" {
+		return value
+	}
+	return l.file[key].value
+}
+
+// name refers to a setting in messages, naming the file key when the value
+// comes from the configuration file
+func (l *loader) name(key string) string {
+	if value, ok := l.lookupEnv(key); ok && value != "" {
+		return key
+	}
+	if value, ok := l.file[key]; ok {
+		return fmt.Sprintf("%s (%s)", value.path, key)
+	}
+	return key
+}
+
+func (l *loader) errorf(format string, args ...interface{}) {
+	l.problems = append(l.problems, fmt.Sprintf(format, args...))
+}
+
+func (l *loader) list(key string) []string {
+	var values []string
+	for _, value := range strings.Split(l.get(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func (l *loader) labels(key string) map[string]string {
+	labels := map[string]string{}
+	for _, pair := range l.list(key) {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			l.errorf("invalid %s value: %s", l.name(key), pair)
+			continue
+		}
+		labels[strings.TrimSpace(name)] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return labels
+}
+
+func (l *loader) int(key string, defaultValue int) int {
+	str := l.get(key)
+	if str == "" {
+		return defaultValue
+	}
+	value, err := strconv.Atoi(str)
+	if err != nil {
+		l.errorf("invalid %s value: %s", l.name(key), str)
+		return defaultValue
+	}
+	return value
+}
+
+func (l *loader) float(key string, defaultValue float64) float64 {
+	str := l.get(key)
+	if str == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		l.errorf("invalid %s value: %s", l.name(key), str)
+		return defaultValue
+	}
+	return value
+}
+
+func (l *loader) bool(key string, defaultValue bool) bool {
+	str := l.get(key)
+	if str == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(str)
+	if err != nil {
+		l.errorf("invalid %s value: %s", l.name(key), str)
+		return defaultValue
+	}
+	return value
+}
+
+func (l *loader) seconds(key string, defaultSeconds int) time.Duration {
+	return time.Duration(l.int(key, defaultSeconds)) * time.Second
 }
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -142,6 +144,11 @@ func TestConfigFromEnv_Defaults(t *testing.T) {
 	if cfg.WaitForActiveCheckInterval != expectedCheckInterval {
 		t.Errorf("expected default WaitForActiveCheckInterval %v, got %v", expectedCheckInterval, cfg.WaitForActiveCheckInterval)
 	}
+
+	// Verify staleness checks are disabled by default
+	if cfg.MaxObservationAge != 0 {
+		t.Errorf("expected default MaxObservationAge 0, got %v", cfg.MaxObservationAge)
+	}
 }
 
 func TestConfigFromEnv_MissingTargetResource(t *testing.T) {
@@ -186,6 +193,17 @@ func TestConfigFromEnv_InvalidSteps(t *testing.T) {
 	}
 }
 
+func TestConfigFromEnv_ZeroCheckInterval(t *testing.T) {
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_WAIT_FOR_ACTIVE_CHECK_INTERVAL", "0")
+
+	// Waiting for the resource would poll without pause
+	_, err := NewConfigFromEnv()
+	if err == nil || !strings.Contains(err.Error(), "AUTOSCALER_WAIT_FOR_ACTIVE_CHECK_INTERVAL must be positive") {
+		t.Errorf("expected error for zero AUTOSCALER_WAIT_FOR_ACTIVE_CHECK_INTERVAL, got %v", err)
+	}
+}
+
 func TestConfigFromEnv_DryRunTrue(t *testing.T) {
 	// Set up environment with DRY_RUN=true
 	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
@@ -271,3 +289,612 @@ func TestConfigFromEnv_InvalidDryRun(t *testing.T) {
 		t.Error("expected error for invalid DRY_RUN, got nil")
 	}
 }
+
+func TestConfigFromEnv_MetricSourcesAndPolicy(t *testing.T) {
+	// Set up environment with a metric source and a target tracking policy
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_METRICS", "cpu, queue-depth")
+	t.Setenv("AUTOSCALER_METRIC_CPU_URLS", "http://worker-1:9090/metrics,http://worker-2:9090/metrics")
+	t.Setenv("AUTOSCALER_METRIC_CPU_FAMILY", "process_cpu_seconds_total")
+	t.Setenv("AUTOSCALER_METRIC_CPU_LABELS", `job="worker", env=prod`)
+	t.Setenv("AUTOSCALER_METRIC_QUEUE_DEPTH_URLS", "http://broker:9090/metrics")
+	t.Setenv("AUTOSCALER_METRIC_QUEUE_DEPTH_FAMILY", "queue_depth")
+	t.Setenv("AUTOSCALER_METRIC_QUEUE_DEPTH_AGGREGATION", "sum")
+	t.Setenv("AUTOSCALER_POLICY_TYPE", "target-tracking")
+	t.Setenv("AUTOSCALER_POLICY_METRIC", "cpu")
+	t.Setenv("AUTOSCALER_POLICY_TARGET", "0.6")
+	t.Setenv("AUTOSCALER_MAX_CAPACITY", "3")
+
+	// Call NewConfigFromEnv to load configuration
+	cfg, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify metric sources are parsed in order
+	if len(cfg.MetricSources) != 2 {
+		t.Fatalf("expected 2 metric sources, got %d", len(cfg.MetricSources))
+	}
+	cpu := cfg.MetricSources[0]
+	if cpu.Name != "cpu" || len(cpu.URLs) != 2 || cpu.Family != "process_cpu_seconds_total" {
+		t.Errorf("unexpected cpu metric source: %+v", cpu)
+	}
+	if cpu.Labels["job"] != "worker" || cpu.Labels["env"] != "prod" {
+		t.Errorf("unexpected cpu labels: %v", cpu.Labels)
+	}
+	if cpu.Aggregation != "avg" {
+		t.Errorf("expected default aggregation avg, got %s", cpu.Aggregation)
+	}
+	if cfg.MetricSources[1].Name != "queue-depth" || cfg.MetricSources[1].Aggregation != "sum" {
+		t.Errorf("unexpected queue-depth metric source: %+v", cfg.MetricSources[1])
+	}
+
+	// Verify policy is parsed
+	if cfg.Policy.Type != "target-tracking" || cfg.Policy.Metric != "cpu" || cfg.Policy.Target != 0.6 {
+		t.Errorf("unexpected policy: %+v", cfg.Policy)
+	}
+
+	// Verify bounds and default evaluation interval
+	if cfg.MinCapacity != 0 || cfg.MaxCapacity != 3 {
+		t.Errorf("expected bounds 0..3, got %d..%d", cfg.MinCapacity, cfg.MaxCapacity)
+	}
+	if cfg.EvaluationInterval != 30*time.Second {
+		t.Errorf("expected default EvaluationInterval 30s, got %v", cfg.EvaluationInterval)
+	}
+}
+
+func TestConfigFromEnv_MetricSourceMissingURLs(t *testing.T) {
+	// Set up environment with a metric source lacking URLs
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_METRICS", "cpu")
+	t.Setenv("AUTOSCALER_METRIC_CPU_FAMILY", "cpu_usage")
+
+	// Call NewConfigFromEnv and expect an error
+	_, err := NewConfigFromEnv()
+
+	// Verify that an error is returned for the incomplete source
+	if err == nil {
+		t.Error("expected error for missing AUTOSCALER_METRIC_CPU_URLS, got nil")
+	}
+}
+
+func TestConfigFromEnv_InvalidBounds(t *testing.T) {
+	// Set up environment with a minimum above the maximum
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_MIN_CAPACITY", "4")
+	t.Setenv("AUTOSCALER_MAX_CAPACITY", "2")
+
+	// Call NewConfigFromEnv and expect an error
+	_, err := NewConfigFromEnv()
+
+	// Verify that an error is returned for inconsistent bounds
+	if err == nil {
+		t.Error("expected error for AUTOSCALER_MIN_CAPACITY above AUTOSCALER_MAX_CAPACITY, got nil")
+	}
+}
+
+func TestConfigFromEnv_Idle(t *testing.T) {
+	// Set up environment with scale-to-zero enabled
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_IDLE_METRIC", "rps")
+	t.Setenv("AUTOSCALER_IDLE_PERIOD", "600")
+	t.Setenv("AUTOSCALER_WAKE_CAPACITY", "2")
+
+	// Call NewConfigFromEnv to load configuration
+	cfg, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify idle settings are parsed
+	if !cfg.Idle.Enabled() || cfg.Idle.Period != 10*time.Minute {
+		t.Errorf("expected idle period 10m, got %v", cfg.Idle.Period)
+	}
+	if cfg.Idle.Metric != "rps" || cfg.Idle.WakeCapacity != 2 || cfg.Idle.Threshold != 0 {
+		t.Errorf("unexpected idle config: %+v", cfg.Idle)
+	}
+}
+
+func TestConfigFromEnv_IdleRequiresZeroMinimum(t *testing.T) {
+	// Set up environment with scale-to-zero and a non-zero minimum
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_IDLE_METRIC", "rps")
+	t.Setenv("AUTOSCALER_IDLE_PERIOD", "600")
+	t.Setenv("AUTOSCALER_MIN_CAPACITY", "1")
+
+	// Call NewConfigFromEnv and expect an error
+	_, err := NewConfigFromEnv()
+
+	// Verify that an error is returned for the conflicting settings
+	if err == nil {
+		t.Error("expected error for AUTOSCALER_IDLE_PERIOD with AUTOSCALER_MIN_CAPACITY 1, got nil")
+	}
+}
+
+func TestConfigFromEnv_Proxy(t *testing.T) {
+	// Set up environment with the request proxy enabled
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_PROXY_TARGET", "http://worker:8080")
+	t.Setenv("AUTOSCALER_PROXY_PORT", "9090")
+	t.Setenv("AUTOSCALER_PROXY_HOLD_TIMEOUT", "30")
+
+	// Call NewConfigFromEnv to load configuration
+	cfg, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify proxy settings are parsed and defaults applied
+	if !cfg.Proxy.Enabled() || cfg.Proxy.Target != "http://worker:8080" {
+		t.Errorf("expected proxy target http://worker:8080, got %q", cfg.Proxy.Target)
+	}
+	if cfg.Proxy.Port != "9090" || cfg.Proxy.HoldTimeout != 30*time.Second || cfg.Proxy.Window != time.Minute {
+		t.Errorf("unexpected proxy config: %+v", cfg.Proxy)
+	}
+}
+
+func TestConfigFromEnv_DashboardLogin(t *testing.T) {
+	// Set up environment with password and OIDC login
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_AUTH_USERS_FILE", "/etc/autoscaler/users")
+	t.Setenv("AUTOSCALER_AUTH_OIDC_ISSUER", "https://login.example.com")
+	t.Setenv("AUTOSCALER_AUTH_OIDC_CLIENT_ID", "autoscaler")
+	t.Setenv("AUTOSCALER_AUTH_OIDC_REDIRECT_URL", "https://autoscaler.example.com/login/callback")
+
+	// Call NewConfigFromEnv to load configuration
+	cfg, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify login is enabled without API credentials and sessions default to 8 hours
+	if !cfg.Auth.LoginEnabled() || cfg.Auth.APIEnabled() || !cfg.Auth.Enabled() {
+		t.Errorf("expected only login to be enabled: %+v", cfg.Auth)
+	}
+	if cfg.Auth.SessionTTL != 8*time.Hour {
+		t.Errorf("expected session TTL 8h, got %v", cfg.Auth.SessionTTL)
+	}
+}
+
+func TestConfigFromEnv_InvalidOIDC(t *testing.T) {
+	// Set up environment with an OIDC issuer lacking a client and redirect URL
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_AUTH_OIDC_ISSUER", "login.example.com")
+	t.Setenv("AUTOSCALER_AUTH_SESSION_TTL", "0")
+
+	// Verify every problem is reported
+	_, err := NewConfigFromEnv()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	for _, key := range []string{"AUTOSCALER_AUTH_OIDC_ISSUER", "AUTOSCALER_AUTH_OIDC_CLIENT_ID", "AUTOSCALER_AUTH_OIDC_REDIRECT_URL", "AUTOSCALER_AUTH_SESSION_TTL"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("expected %s to be reported in %q", key, err.Error())
+		}
+	}
+}
+
+func TestConfigFromEnv_TLS(t *testing.T) {
+	// Set up environment with TLS and client certificates
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_TLS_CERT_FILE", "/etc/autoscaler/tls.crt")
+	t.Setenv("AUTOSCALER_TLS_KEY_FILE", "/etc/autoscaler/tls.key")
+	t.Setenv("AUTOSCALER_TLS_CLIENT_CA_FILE", "/etc/autoscaler/ca.crt")
+
+	// Call NewConfigFromEnv to load configuration
+	cfg, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify client certificates are optional and the certificate is checked every minute
+	if !cfg.TLS.Enabled() || cfg.TLS.RequireClientCert || cfg.TLS.ReloadInterval != time.Minute {
+		t.Errorf("unexpected TLS config: %+v", cfg.TLS)
+	}
+}
+
+func TestConfigFromEnv_InvalidTLS(t *testing.T) {
+	// Set up environment with a key but no certificate, and required client
+	// certificates without a CA
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_TLS_KEY_FILE", "/etc/autoscaler/tls.key")
+	t.Setenv("AUTOSCALER_TLS_REQUIRE_CLIENT_CERT", "true")
+
+	// Verify both problems are reported
+	_, err := NewConfigFromEnv()
+	if err == nil || !strings.Contains(err.Error(), "AUTOSCALER_TLS_CERT_FILE and AUTOSCALER_TLS_KEY_FILE must be set together") {
+		t.Errorf("expected error for a key without certificate, got %v", err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "AUTOSCALER_TLS_REQUIRE_CLIENT_CERT requires AUTOSCALER_TLS_CLIENT_CA_FILE") {
+		t.Errorf("expected error for required client certificates without CA, got %v", err)
+	}
+}
+
+func TestConfigFromEnv_SLOPolicy(t *testing.T) {
+	// Set up environment with an SLO policy on a latency histogram
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_METRICS", "slow-requests")
+	t.Setenv("AUTOSCALER_METRIC_SLOW_REQUESTS_URLS", "http://worker:9090/metrics")
+	t.Setenv("AUTOSCALER_METRIC_SLOW_REQUESTS_FAMILY", "http_request_duration_seconds")
+	t.Setenv("AUTOSCALER_METRIC_SLOW_REQUESTS_THRESHOLD", "0.2")
+	t.Setenv("AUTOSCALER_POLICY_TYPE", "slo")
+	t.Setenv("AUTOSCALER_POLICY_METRIC", "slow-requests")
+	t.Setenv("AUTOSCALER_POLICY_OBJECTIVE", "0.95")
+	t.Setenv("AUTOSCALER_POLICY_SHORT_BURN_RATE", "3")
+
+	// Call NewConfigFromEnv to load configuration
+	cfg, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify the histogram threshold and SLO settings with their defaults
+	if cfg.MetricSources[0].Threshold != 0.2 {
+		t.Errorf("expected threshold 0.2, got %v", cfg.MetricSources[0].Threshold)
+	}
+	slo := cfg.Policy.SLO
+	if slo.Objective != 0.95 || slo.ShortBurnRate != 3 || slo.LongBurnRate != 1 || slo.ScaleDownBurnRate != 0.25 {
+		t.Errorf("unexpected slo config: %+v", slo)
+	}
+	if slo.ShortWindow != 5*time.Minute || slo.LongWindow != time.Hour || slo.Step != 1 {
+		t.Errorf("unexpected slo windows: %+v", slo)
+	}
+}
+
+func TestConfigFromEnv_InvalidSLOObjective(t *testing.T) {
+	// Set up environment with an objective expressed as a percentage
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_POLICY_OBJECTIVE", "95")
+
+	// Call NewConfigFromEnv and expect an error
+	_, err := NewConfigFromEnv()
+	if err == nil {
+		t.Fatal("expected error for objective outside (0, 1)")
+	}
+}
+
+func TestConfigFromEnv_ComposedPolicies(t *testing.T) {
+	// Set up environment with two named policies
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_POLICIES", "cpu,queue-backlog")
+	t.Setenv("AUTOSCALER_POLICY_STRATEGY", "weighted")
+	t.Setenv("AUTOSCALER_POLICY_CPU_TYPE", "target-tracking")
+	t.Setenv("AUTOSCALER_POLICY_CPU_METRIC", "cpu")
+	t.Setenv("AUTOSCALER_POLICY_CPU_TARGET", "0.6")
+	t.Setenv("AUTOSCALER_POLICY_QUEUE_BACKLOG_TYPE", "concurrency")
+	t.Setenv("AUTOSCALER_POLICY_QUEUE_BACKLOG_METRIC", "queue")
+	t.Setenv("AUTOSCALER_POLICY_QUEUE_BACKLOG_TARGET", "50")
+	t.Setenv("AUTOSCALER_POLICY_QUEUE_BACKLOG_WEIGHT", "2")
+
+	// Call NewConfigFromEnv to load configuration
+	cfg, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify both policies are loaded in order
+	if cfg.PolicyStrategy != "weighted" || len(cfg.Policies) != 2 {
+		t.Fatalf("unexpected policies: %s %+v", cfg.PolicyStrategy, cfg.Policies)
+	}
+	if cfg.Policies[0].Name != "cpu" || cfg.Policies[0].Target != 0.6 || cfg.Policies[0].Weight != 1 {
+		t.Errorf("unexpected cpu policy: %+v", cfg.Policies[0])
+	}
+	if cfg.Policies[1].Name != "queue-backlog" || cfg.Policies[1].Metric != "queue" || cfg.Policies[1].Weight != 2 {
+		t.Errorf("unexpected queue policy: %+v", cfg.Policies[1])
+	}
+}
+
+func TestConfigFromEnv_ComposedPolicyRequiresType(t *testing.T) {
+	// Set up environment with a named policy missing its type
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_POLICIES", "cpu")
+
+	// Call NewConfigFromEnv and expect an error
+	_, err := NewConfigFromEnv()
+	if err == nil {
+		t.Fatal("expected error for policy without a type")
+	}
+}
+
+func TestConfigFromEnv_ExpressionPolicyInputs(t *testing.T) {
+	// Set up environment with an expression policy over a metric source and the proxy
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_METRICS", "queue")
+	t.Setenv("AUTOSCALER_METRIC_QUEUE_URLS", "http://worker:9090/metrics")
+	t.Setenv("AUTOSCALER_METRIC_QUEUE_FAMILY", "queue_depth")
+	t.Setenv("AUTOSCALER_PROXY_TARGET", "http://worker:8080")
+	t.Setenv("AUTOSCALER_POLICY_TYPE", "cel")
+	t.Setenv("AUTOSCALER_POLICY_EXPRESSION", "ceil(queue / 50.0)")
+	t.Setenv("AUTOSCALER_POLICY_TIMEZONE", "Europe/Berlin")
+
+	// Call NewConfigFromEnv to load configuration
+	cfg, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify the expression and the metric names it can reference
+	if cfg.Policy.Expression != "ceil(queue / 50.0)" || cfg.Policy.Timezone != "Europe/Berlin" {
+		t.Errorf("unexpected policy: %+v", cfg.Policy)
+	}
+	expected := []string{"queue", "concurrency", "rps"}
+	if strings.Join(cfg.Policy.Inputs, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected inputs %v, got %v", expected, cfg.Policy.Inputs)
+	}
+}
+
+func TestConfigFromEnv_PolicyServerWithFallback(t *testing.T) {
+	// Set up environment with an external policy server and a fallback policy
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_POLICY_TYPE", "http")
+	t.Setenv("AUTOSCALER_POLICY_URL", "http://policy-server:8080/recommend")
+	t.Setenv("AUTOSCALER_POLICY_TIMEOUT", "2")
+	t.Setenv("AUTOSCALER_POLICY_FALLBACK_TYPE", "target-tracking")
+	t.Setenv("AUTOSCALER_POLICY_FALLBACK_METRIC", "cpu")
+	t.Setenv("AUTOSCALER_POLICY_FALLBACK_TARGET", "0.7")
+
+	// Call NewConfigFromEnv to load configuration
+	cfg, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify the policy server and its fallback are parsed
+	if cfg.Policy.URL != "http://policy-server:8080/recommend" || cfg.Policy.Timeout != 2*time.Second {
+		t.Errorf("unexpected policy server config: %+v", cfg.Policy)
+	}
+	fallback := cfg.Policy.Fallback
+	if fallback == nil || fallback.Type != "target-tracking" || fallback.Metric != "cpu" || fallback.Target != 0.7 {
+		t.Errorf("unexpected fallback policy: %+v", fallback)
+	}
+}
+
+func TestConfigFromEnv_RecommendOnly(t *testing.T) {
+	// Set up environment with recommend-only mode enabled
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_RECOMMEND_ONLY", "true")
+
+	// Call NewConfigFromEnv to load configuration
+	cfg, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify recommend-only mode is enabled
+	if !cfg.RecommendOnly {
+		t.Error("expected RecommendOnly to be true")
+	}
+
+	// An invalid value is rejected
+	t.Setenv("AUTOSCALER_RECOMMEND_ONLY", "maybe")
+	if _, err := NewConfigFromEnv(); err == nil {
+		t.Error("expected error for invalid AUTOSCALER_RECOMMEND_ONLY value")
+	}
+}
+
+func TestConfigFromEnv_ShadowPolicies(t *testing.T) {
+	// Set up environment with a shadow policy next to the active one
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_POLICY_TYPE", "target-tracking")
+	t.Setenv("AUTOSCALER_POLICY_METRIC", "cpu")
+	t.Setenv("AUTOSCALER_POLICY_TARGET", "0.6")
+	t.Setenv("AUTOSCALER_SHADOW_POLICIES", "tighter")
+	t.Setenv("AUTOSCALER_SHADOW_POLICY_TIGHTER_TYPE", "target-tracking")
+	t.Setenv("AUTOSCALER_SHADOW_POLICY_TIGHTER_METRIC", "cpu")
+	t.Setenv("AUTOSCALER_SHADOW_POLICY_TIGHTER_TARGET", "0.4")
+
+	// Call NewConfigFromEnv to load configuration
+	cfg, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify the shadow policy is loaded separately from the active policy
+	if len(cfg.ShadowPolicies) != 1 {
+		t.Fatalf("expected 1 shadow policy, got %d", len(cfg.ShadowPolicies))
+	}
+	shadow := cfg.ShadowPolicies[0]
+	if shadow.Name != "tighter" || shadow.Target != 0.4 || cfg.Policy.Target != 0.6 {
+		t.Errorf("unexpected shadow policy: %+v", shadow)
+	}
+}
+
+func TestConfigFromEnv_Simulation(t *testing.T) {
+	// Set up environment with a simulated resource for dry runs
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("DRY_RUN", "true")
+	t.Setenv("DRY_RUN_INITIAL_CAPACITY", "3")
+	t.Setenv("DRY_RUN_SCALE_UP_DELAY", "60")
+	t.Setenv("DRY_RUN_MAX_REPLICAS", "8")
+	t.Setenv("DRY_RUN_LATENCY_MS", "250")
+	t.Setenv("DRY_RUN_FAILURE_RATE", "0.1")
+
+	// Call NewConfigFromEnv to load configuration
+	cfg, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify the simulation settings, with the scale down delay defaulted
+	expected := SimulationConfig{
+		InitialCapacity: 3,
+		ScaleUpDelay:    time.Minute,
+		ScaleDownDelay:  10 * time.Second,
+		MaxReplicas:     8,
+		Latency:         250 * time.Millisecond,
+		FailureRate:     0.1,
+	}
+	if cfg.Simulation != expected {
+		t.Errorf("expected simulation %+v, got %+v", expected, cfg.Simulation)
+	}
+
+	// A failure rate above 1 is rejected
+	t.Setenv("DRY_RUN_FAILURE_RATE", "1.5")
+	if _, err := NewConfigFromEnv(); err == nil {
+		t.Error("expected error for DRY_RUN_FAILURE_RATE above 1")
+	}
+}
+
+func TestConfigFromEnv_Sidecar(t *testing.T) {
+	// Set up environment without sidecar settings
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+
+	// Call NewConfigFromEnv to load configuration
+	cfg, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify the defaults match the local sidecar
+	if cfg.Sidecar.URL != "http://127.0.0.1:49750" || cfg.Sidecar.Timeout != 60*time.Second || cfg.Sidecar.RetryMax != 3 {
+		t.Errorf("unexpected default sidecar config: %+v", cfg.Sidecar)
+	}
+	if cfg.Sidecar.RateLimit != 5 || cfg.Sidecar.Burst != 10 || cfg.Sidecar.MaxRetryAfter != 30*time.Second {
+		t.Errorf("unexpected default sidecar rate limit: %+v", cfg.Sidecar)
+	}
+
+	// A timeout of a second still caps a Retry-After
+	t.Setenv("AUTOSCALER_SIDECAR_TIMEOUT", "1")
+	cfg, err = NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Sidecar.MaxRetryAfter != 500*time.Millisecond {
+		t.Errorf("expected Retry-After to be capped at half the timeout, got %s", cfg.Sidecar.MaxRetryAfter)
+	}
+	t.Setenv("AUTOSCALER_SIDECAR_TIMEOUT", "")
+
+	// Set up environment with a Unix socket sidecar and custom retries
+	t.Setenv("AUTOSCALER_SIDECAR_URL", "unix:///var/run/sidecar.sock")
+	t.Setenv("AUTOSCALER_SIDECAR_RETRY_MAX", "5")
+	t.Setenv("AUTOSCALER_SIDECAR_RETRY_WAIT_MAX", "10")
+	cfg, err = NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Sidecar.URL != "unix:///var/run/sidecar.sock" || cfg.Sidecar.RetryMax != 5 || cfg.Sidecar.RetryWaitMax != 10*time.Second {
+		t.Errorf("unexpected sidecar config: %+v", cfg.Sidecar)
+	}
+
+	// Verify invalid settings are rejected
+	for key, value := range map[string]string{
+		"AUTOSCALER_SIDECAR_URL":             "ftp://sidecar",
+		"AUTOSCALER_SIDECAR_RETRY_WAIT_MIN":  "20",
+		"AUTOSCALER_SIDECAR_CERT_FILE":       "client.pem",
+		"AUTOSCALER_SIDECAR_RATE_LIMIT":      "-1",
+		"AUTOSCALER_SIDECAR_BURST":           "0",
+		"AUTOSCALER_SIDECAR_MAX_RETRY_AFTER": "60",
+	} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := NewConfigFromEnv(); err == nil {
+				t.Errorf("expected error for %s=%s", key, value)
+			}
+		})
+	}
+}
+
+func TestConfigFromEnv_Recovery(t *testing.T) {
+	// Set up environment without recovery settings
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+
+	// Call NewConfigFromEnv to load configuration
+	cfg, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify a failed instance aborts scaling by default without a circuit breaker
+	if cfg.Recovery.Policy != "abort" || cfg.Recovery.Backoff != 60*time.Second || cfg.Recovery.BreakerThreshold != 0 {
+		t.Errorf("unexpected default recovery config: %+v", cfg.Recovery)
+	}
+
+	// Set up environment with a rollback policy paging a webhook
+	t.Setenv("AUTOSCALER_RECOVERY_POLICY", "rollback")
+	t.Setenv("AUTOSCALER_RECOVERY_MAX_RETRIES", "5")
+	t.Setenv("AUTOSCALER_NOTIFY_URL", "https://events.example.com/hook")
+	cfg, err = NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Recovery.Policy != "rollback" || cfg.Recovery.MaxRetries != 5 || cfg.Recovery.NotifyURL != "https://events.example.com/hook" {
+		t.Errorf("unexpected recovery config: %+v", cfg.Recovery)
+	}
+	// Recovery policies enable the circuit breaker by default
+	if cfg.Recovery.BreakerThreshold != 3 {
+		t.Errorf("unexpected recovery config: %+v", cfg.Recovery)
+	}
+
+	// Verify invalid settings are rejected
+	for key, value := range map[string]string{
+		"AUTOSCALER_CIRCUIT_BREAKER_THRESHOLD": "-1",
+		"AUTOSCALER_NOTIFY_URL":                "events.example.com",
+	} {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := NewConfigFromEnv(); err == nil {
+				t.Errorf("expected error for %s=%s", key, value)
+			}
+		})
+	}
+}
+
+func TestConfigFromEnv_NegativeSteps(t *testing.T) {
+	// Set up environment with a negative step, which would wrap around as uint
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_STEPS", "-1")
+
+	// Verify the step is rejected
+	_, err := NewConfigFromEnv()
+	if err == nil || !strings.Contains(err.Error(), "AUTOSCALER_STEPS must be at least 1") {
+		t.Errorf("expected error for negative AUTOSCALER_STEPS, got %v", err)
+	}
+}
+
+func TestConfigFromEnv_ReportsAllProblems(t *testing.T) {
+	// Set up environment with several invalid values
+	t.Setenv("AUTOSCALER_COOLDOWN", "soon")
+	t.Setenv("AUTOSCALER_STEPS", "0")
+	t.Setenv("DRY_RUN", "maybe")
+	t.Setenv("AUTOSCALER_SIDECAR_URL", "ftp://sidecar")
+
+	// Verify every problem is reported, not just the first one
+	_, err := NewConfigFromEnv()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	if len(validationErr.Problems) != 5 {
+		t.Errorf("expected 5 problems, got %d: %v", len(validationErr.Problems), validationErr.Problems)
+	}
+	for _, key := range []string{"AUTOSCALER_COOLDOWN", "AUTOSCALER_TARGET_RESOURCE", "AUTOSCALER_STEPS", "DRY_RUN", "AUTOSCALER_SIDECAR_URL"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("expected %s to be reported in %q", key, err.Error())
+		}
+	}
+}
+
+func TestConfig_Clone(t *testing.T) {
+	// Set up environment with maps, lists and a fallback policy
+	t.Setenv("AUTOSCALER_TARGET_RESOURCE", "test-resource")
+	t.Setenv("AUTOSCALER_STATE_POLICIES", "PAUSED=zero")
+	t.Setenv("AUTOSCALER_METRICS", "cpu")
+	t.Setenv("AUTOSCALER_METRIC_CPU_URLS", "http://worker:9090/metrics")
+	t.Setenv("AUTOSCALER_METRIC_CPU_FAMILY", "cpu_utilization")
+	t.Setenv("AUTOSCALER_POLICY_TYPE", "http")
+	t.Setenv("AUTOSCALER_POLICY_FALLBACK_TYPE", "target-tracking")
+	cfg, err := NewConfigFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify changes to the clone do not reach the original
+	clone := cfg.Clone()
+	clone.StatePolicies["PAUSED"] = "abort"
+	clone.MetricSources[0].URLs[0] = "http://other:9090/metrics"
+	clone.Policy.Fallback.Type = "cel"
+	if cfg.StatePolicies["PAUSED"] != "zero" || cfg.MetricSources[0].URLs[0] != "http://worker:9090/metrics" || cfg.Policy.Fallback.Type != "target-tracking" {
+		t.Errorf("clone shares state with the original: %+v", cfg)
+	}
+}
@@ -0,0 +1,600 @@
+package config
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema is the JSON Schema of the configuration file, for use by editors
+//
+//go:embed schema.json
+var Schema []byte
+
+// File is the configuration file format. Both YAML and JSON are accepted,
+// durations are given in seconds, and every setting can be overridden by the
+// environment variable named after it. The env tag of a setting names its
+// variable relative to the enclosing section; the env tag of a list names the
+// variable listing the item names and the item tag the prefix of the item
+// variables. The json tags name the same keys when a configuration is shown.
+type File struct {
+	TargetResource             *string           `yaml:"targetResource" json:"targetResource,omitempty" env:"AUTOSCALER_TARGET_RESOURCE"`
+	Cooldown                   *int              `yaml:"cooldown" json:"cooldown,omitempty" env:"AUTOSCALER_COOLDOWN"`
+	Steps                      *int              `yaml:"steps" json:"steps,omitempty" env:"AUTOSCALER_STEPS"`
+	DryRun                     *bool             `yaml:"dryRun" json:"dryRun,omitempty" env:"DRY_RUN"`
+	RecommendOnly              *bool             `yaml:"recommendOnly" json:"recommendOnly,omitempty" env:"AUTOSCALER_RECOMMEND_ONLY"`
+	WaitForActiveTimeout       *int              `yaml:"waitForActiveTimeout" json:"waitForActiveTimeout,omitempty" env:"AUTOSCALER_WAIT_FOR_ACTIVE_TIMEOUT"`
+	WaitForActiveCheckInterval *int              `yaml:"waitForActiveCheckInterval" json:"waitForActiveCheckInterval,omitempty" env:"AUTOSCALER_WAIT_FOR_ACTIVE_CHECK_INTERVAL"`
+	MaxObservationAge          *int              `yaml:"maxObservationAge" json:"maxObservationAge,omitempty" env:"AUTOSCALER_MAX_OBSERVATION_AGE"`
+	StatePolicies              map[string]string `yaml:"statePolicies" json:"statePolicies,omitempty" env:"AUTOSCALER_STATE_POLICIES"`
+	EvaluationInterval         *int              `yaml:"evaluationInterval" json:"evaluationInterval,omitempty" env:"AUTOSCALER_EVALUATION_INTERVAL"`
+	MinCapacity                *int              `yaml:"minCapacity" json:"minCapacity,omitempty" env:"AUTOSCALER_MIN_CAPACITY"`
+	MaxCapacity                *int              `yaml:"maxCapacity" json:"maxCapacity,omitempty" env:"AUTOSCALER_MAX_CAPACITY"`
+	Metrics                    []FileMetric      `yaml:"metrics" json:"metrics,omitempty" env:"AUTOSCALER_METRICS" item:"AUTOSCALER_METRIC_"`
+	Policy                     *FilePolicy       `yaml:"policy" json:"policy,omitempty" env:"AUTOSCALER_POLICY_"`
+	Policies                   []FileNamedPolicy `yaml:"policies" json:"policies,omitempty" env:"AUTOSCALER_POLICIES" item:"AUTOSCALER_POLICY_"`
+	PolicyStrategy             *string           `yaml:"policyStrategy" json:"policyStrategy,omitempty" env:"AUTOSCALER_POLICY_STRATEGY"`
+	ShadowPolicies             []FileNamedPolicy `yaml:"shadowPolicies" json:"shadowPolicies,omitempty" env:"AUTOSCALER_SHADOW_POLICIES" item:"AUTOSCALER_SHADOW_POLICY_"`
+	Idle                       *FileIdle         `yaml:"idle" json:"idle,omitempty" env:"AUTOSCALER_"`
+	Proxy                      *FileProxy        `yaml:"proxy" json:"proxy,omitempty" env:"AUTOSCALER_PROXY_"`
+	Sidecar                    *FileSidecar      `yaml:"sidecar" json:"sidecar,omitempty" env:"AUTOSCALER_SIDECAR_"`
+	Recovery                   *FileRecovery     `yaml:"recovery" json:"recovery,omitempty" env:"AUTOSCALER_"`
+	Simulation                 *FileSimulation   `yaml:"simulation" json:"simulation,omitempty" env:"DRY_RUN_"`
+	Auth                       *FileAuth         `yaml:"auth" json:"auth,omitempty" env:"AUTOSCALER_AUTH_"`
+	TLS                        *FileTLS          `yaml:"tls" json:"tls,omitempty" env:"AUTOSCALER_TLS_"`
+}
+
+// FileMetric is a metric source in the configuration file
+type FileMetric struct {
+	Name        string            `yaml:"name" json:"name,omitempty"`
+	URLs        []string          `yaml:"urls" json:"urls,omitempty" env:"URLS"`
+	Family      *string           `yaml:"family" json:"family,omitempty" env:"FAMILY"`
+	Labels      map[string]string `yaml:"labels" json:"labels,omitempty" env:"LABELS"`
+	Aggregation *string           `yaml:"aggregation" json:"aggregation,omitempty" env:"AGGREGATION"`
+	Threshold   *float64          `yaml:"threshold" json:"threshold,omitempty" env:"THRESHOLD"`
+}
+
+// FilePolicy is a scaling policy in the configuration file
+type FilePolicy struct {
+	Type              *string     `yaml:"type" json:"type,omitempty" env:"TYPE"`
+	Metric            *string     `yaml:"metric" json:"metric,omitempty" env:"METRIC"`
+	Target            *float64    `yaml:"target" json:"target,omitempty" env:"TARGET"`
+	Expression        *string     `yaml:"expression" json:"expression,omitempty" env:"EXPRESSION"`
+	Timezone          *string     `yaml:"timezone" json:"timezone,omitempty" env:"TIMEZONE"`
+	URL               *string     `yaml:"url" json:"url,omitempty" env:"URL"`
+	Timeout           *int        `yaml:"timeout" json:"timeout,omitempty" env:"TIMEOUT"`
+	Fallback          *FilePolicy `yaml:"fallback" json:"fallback,omitempty" env:"FALLBACK_"`
+	Objective         *float64    `yaml:"objective" json:"objective,omitempty" env:"OBJECTIVE"`
+	ShortWindow       *int        `yaml:"shortWindow" json:"shortWindow,omitempty" env:"SHORT_WINDOW"`
+	LongWindow        *int        `yaml:"longWindow" json:"longWindow,omitempty" env:"LONG_WINDOW"`
+	ShortBurnRate     *float64    `yaml:"shortBurnRate" json:"shortBurnRate,omitempty" env:"SHORT_BURN_RATE"`
+	LongBurnRate      *float64    `yaml:"longBurnRate" json:"longBurnRate,omitempty" env:"LONG_BURN_RATE"`
+	ScaleDownBurnRate *float64    `yaml:"scaleDownBurnRate" json:"scaleDownBurnRate,omitempty" env:"SCALE_DOWN_BURN_RATE"`
+	Step              *int        `yaml:"step" json:"step,omitempty" env:"STEP"`
+}
+
+// FileNamedPolicy is a composed or shadow policy in the configuration file
+type FileNamedPolicy struct {
+	Name       string   `yaml:"name" json:"name,omitempty"`
+	Weight     *float64 `yaml:"weight" json:"weight,omitempty" env:"WEIGHT"`
+	FilePolicy `yaml:",inline"`
+}
+
+// FileIdle holds the scale-to-zero settings of the configuration file
+type FileIdle struct {
+	Metric       *string  `yaml:"metric" json:"metric,omitempty" env:"IDLE_METRIC"`
+	Threshold    *float64 `yaml:"threshold" json:"threshold,omitempty" env:"IDLE_THRESHOLD"`
+	Period       *int     `yaml:"period" json:"period,omitempty" env:"IDLE_PERIOD"`
+	WakeCapacity *int     `yaml:"wakeCapacity" json:"wakeCapacity,omitempty" env:"WAKE_CAPACITY"`
+}
+
+// FileProxy holds the reverse proxy settings of the configuration file
+type FileProxy struct {
+	Target      *string `yaml:"target" json:"target,omitempty" env:"TARGET"`
+	Port        *string `yaml:"port" json:"port,omitempty" env:"PORT"`
+	HoldTimeout *int    `yaml:"holdTimeout" json:"holdTimeout,omitempty" env:"HOLD_TIMEOUT"`
+	Window      *int    `yaml:"window" json:"window,omitempty" env:"WINDOW"`
+}
+
+// FileSidecar holds the sidecar connection settings of the configuration file
+type FileSidecar struct {
+	URL                *string  `yaml:"url" json:"url,omitempty" env:"URL"`
+	Timeout            *int     `yaml:"timeout" json:"timeout,omitempty" env:"TIMEOUT"`
+	RetryMax           *int     `yaml:"retryMax" json:"retryMax,omitempty" env:"RETRY_MAX"`
+	RetryWaitMin       *int     `yaml:"retryWaitMin" json:"retryWaitMin,omitempty" env:"RETRY_WAIT_MIN"`
+	RetryWaitMax       *int     `yaml:"retryWaitMax" json:"retryWaitMax,omitempty" env:"RETRY_WAIT_MAX"`
+	RateLimit          *float64 `yaml:"rateLimit" json:"rateLimit,omitempty" env:"RATE_LIMIT"`
+	Burst              *int     `yaml:"burst" json:"burst,omitempty" env:"BURST"`
+	MaxRetryAfter      *int     `yaml:"maxRetryAfter" json:"maxRetryAfter,omitempty" env:"MAX_RETRY_AFTER"`
+	CAFile             *string  `yaml:"caFile" json:"caFile,omitempty" env:"CA_FILE"`
+	CertFile           *string  `yaml:"certFile" json:"certFile,omitempty" env:"CERT_FILE"`
+	KeyFile            *string  `yaml:"keyFile" json:"keyFile,omitempty" env:"KEY_FILE"`
+	ServerName         *string  `yaml:"serverName" json:"serverName,omitempty" env:"SERVER_NAME"`
+	InsecureSkipVerify *bool    `yaml:"insecureSkipVerify" json:"insecureSkipVerify,omitempty" env:"INSECURE_SKIP_VERIFY"`
+}
+
+// FileRecovery holds the failure recovery settings of the configuration file
+type FileRecovery struct {
+	Policy           *string `yaml:"policy" json:"policy,omitempty" env:"RECOVERY_POLICY"`
+	Backoff          *int    `yaml:"backoff" json:"backoff,omitempty" env:"RECOVERY_BACKOFF"`
+	MaxRetries       *int    `yaml:"maxRetries" json:"maxRetries,omitempty" env:"RECOVERY_MAX_RETRIES"`
+	BreakerThreshold *int    `yaml:"breakerThreshold" json:"breakerThreshold,omitempty" env:"CIRCUIT_BREAKER_THRESHOLD"`
+	NotifyURL        *string `yaml:"notifyUrl" json:"notifyUrl,omitempty" env:"NOTIFY_URL"`
+}
+
+// FileSimulation holds the dry-run simulation settings of the configuration file
+type FileSimulation struct {
+	InitialCapacity *int     `yaml:"initialCapacity" json:"initialCapacity,omitempty" env:"INITIAL_CAPACITY"`
+	ScaleUpDelay    *int     `yaml:"scaleUpDelay" json:"scaleUpDelay,omitempty" env:"SCALE_UP_DELAY"`
+	ScaleDownDelay  *int     `yaml:"scaleDownDelay" json:"scaleDownDelay,omitempty" env:"SCALE_DOWN_DELAY"`
+	MaxReplicas     *int     `yaml:"maxReplicas" json:"maxReplicas,omitempty" env:"MAX_REPLICAS"`
+	LatencyMillis   *int     `yaml:"latencyMs" json:"latencyMs,omitempty" env:"LATENCY_MS"`
+	FailureRate     *float64 `yaml:"failureRate" json:"failureRate,omitempty" env:"FAILURE_RATE"`
+	FailureDuration *int     `yaml:"failureDuration" json:"failureDuration,omitempty" env:"FAILURE_DURATION"`
+}
+
+// FileAuth holds the API authentication settings of the configuration file
+type FileAuth struct {
+	APIKeysFile          *string `yaml:"apiKeysFile" json:"apiKeysFile,omitempty" env:"API_KEYS_FILE"`
+	HMACSecretFile       *string `yaml:"hmacSecretFile" json:"hmacSecretFile,omitempty" env:"HMAC_SECRET_FILE"`
+	JWKS                 *string `yaml:"jwks" json:"jwks,omitempty" env:"JWKS"`
+	JWKSRefresh          *int    `yaml:"jwksRefresh" json:"jwksRefresh,omitempty" env:"JWKS_REFRESH"`
+	JWTIssuer            *string `yaml:"jwtIssuer" json:"jwtIssuer,omitempty" env:"JWT_ISSUER"`
+	JWTAudience          *string `yaml:"jwtAudience" json:"jwtAudience,omitempty" env:"JWT_AUDIENCE"`
+	JWTRolesClaim        *string `yaml:"jwtRolesClaim" json:"jwtRolesClaim,omitempty" env:"JWT_ROLES_CLAIM"`
+	UsersFile            *string `yaml:"usersFile" json:"usersFile,omitempty" env:"USERS_FILE"`
+	OIDCIssuer           *string `yaml:"oidcIssuer" json:"oidcIssuer,omitempty" env:"OIDC_ISSUER"`
+	OIDCClientID         *string `yaml:"oidcClientId" json:"oidcClientId,omitempty" env:"OIDC_CLIENT_ID"`
+	OIDCClientSecretFile *string `yaml:"oidcClientSecretFile" json:"oidcClientSecretFile,omitempty" env:"OIDC_CLIENT_SECRET_FILE"`
+	OIDCRedirectURL      *string `yaml:"oidcRedirectUrl" json:"oidcRedirectUrl,omitempty" env:"OIDC_REDIRECT_URL"`
+	SessionTTL           *int    `yaml:"sessionTtl" json:"sessionTtl,omitempty" env:"SESSION_TTL"`
+}
+
+// FileTLS holds the TLS settings of the API in the configuration file
+type FileTLS struct {
+	CertFile          *string `yaml:"certFile" json:"certFile,omitempty" env:"CERT_FILE"`
+	KeyFile           *string `yaml:"keyFile" json:"keyFile,omitempty" env:"KEY_FILE"`
+	ClientCAFile      *string `yaml:"clientCaFile" json:"clientCaFile,omitempty" env:"CLIENT_CA_FILE"`
+	RequireClientCert *bool   `yaml:"requireClientCert" json:"requireClientCert,omitempty" env:"REQUIRE_CLIENT_CERT"`
+	ReloadInterval    *int    `yaml:"reloadInterval" json:"reloadInterval,omitempty" env:"RELOAD_INTERVAL"`
+}
+
+// fileValue is a setting read from the configuration file and where it was found
+type fileValue struct {
+	value string
+	path  string
+}
+
+// LoadFile reads a YAML or JSON configuration file. Unknown keys and values
+// of the wrong type are all reported at once in a *ValidationError.
+func LoadFile(path string) (*File, error) {
+	return checked(loadFile(path))
+}
+
+// ParseFile parses the contents of a YAML or JSON configuration file
+func ParseFile(data []byte) (*File, error) {
+	return checked(parseFile(data))
+}
+
+// loadFile reads a configuration file like parseFile
+func loadFile(path string) (*File, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read configuration file: %w", err)
+	}
+	return parseFile(data)
+}
+
+// parseFile parses a configuration file, returning values of the wrong type
+// as problems. Decoding goes on past them, leaving those settings unset, so
+// they can be reported together with the problems of the rest of the file.
+func parseFile(data []byte) (*File, []string, error) {
+	file := &File{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err := decoder.Decode(file)
+	if errors.Is(err, io.EOF) {
+		return file, nil, nil
+	}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		var root yaml.Node
+		if yaml.Unmarshal(data, &root) == nil {
+			unsetMistyped(reflect.ValueOf(file).Elem(), &root)
+		}
+		return file, typeErr.Errors, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse configuration file: %w", err)
+	}
+	return file, nil, nil
+}
+
+// checked turns the problems of a parsed file into a *ValidationError
+func checked(file *File, problems []string, err error) (*File, error) {
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return file, nil
+}
+
+// unsetMistyped clears the settings of a file section whose value in the
+// node does not decode. The decoder leaves zero values behind for them, which
+// must not be taken for settings. Mistyped items of lists are removed.
+func unsetMistyped(section reflect.Value, node *yaml.Node) {
+	if node.Kind == yaml.DocumentNode && len(node.Content) == 1 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		field, ok := fieldByName(section, node.Content[i].Value)
+		if !ok {
+			continue
+		}
+		value := node.Content[i+1]
+		fieldType := field.Type()
+		switch {
+		case fieldType.Kind() == reflect.Pointer && fieldType.Elem().Kind() == reflect.Struct && value.Kind == yaml.MappingNode:
+			if !field.IsNil() {
+				unsetMistyped(field.Elem(), value)
+			}
+		case fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() == reflect.Struct && value.Kind == yaml.SequenceNode:
+			if len(value.Content) != field.Len() {
+				continue
+			}
+			kept := reflect.MakeSlice(fieldType, 0, field.Len())
+			for j, item := range value.Content {
+				if item.Kind == yaml.MappingNode {
+					unsetMistyped(field.Index(j), item)
+					kept = reflect.Append(kept, field.Index(j))
+				}
+			}
+			field.Set(kept)
+		case fieldType.Kind() == reflect.Slice && value.Kind == yaml.SequenceNode:
+			// The decoder drops the whole list, so it is decoded again
+			// without the mistyped items
+			kept := reflect.MakeSlice(fieldType, 0, len(value.Content))
+			for _, item := range value.Content {
+				decoded := reflect.New(fieldType.Elem())
+				if item.Decode(decoded.Interface()) == nil {
+					kept = reflect.Append(kept, decoded.Elem())
+				}
+			}
+			field.Set(kept)
+		default:
+			if value.Decode(reflect.New(fieldType).Interface()) != nil {
+				field.Set(reflect.Zero(fieldType))
+			}
+		}
+	}
+}
+
+// fieldByName returns the field of a file section with the given key,
+// looking into inlined sections
+func fieldByName(section reflect.Value, name string) (reflect.Value, bool) {
+	sectionType := section.Type()
+	for i := 0; i < sectionType.NumField(); i++ {
+		field := sectionType.Field(i)
+		if field.Anonymous {
+			if value, ok := fieldByName(section.Field(i), name); ok {
+				return value, true
+			}
+			continue
+		}
+		if key, _, _ := strings.Cut(field.Tag.Get("yaml"), ","); key == name {
+			return section.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// values flattens the file into the environment variables it stands for
+func (f *File) values() (map[string]fileValue, []string) {
+	values := map[string]fileValue{}
+	var problems []string
+	flatten(reflect.ValueOf(f).Elem(), "", "", values, &problems)
+	return values, problems
+}
+
+// flatten records the settings of a file section under the variable names
+// formed with prefix, and their location in the file under path
+func flatten(section reflect.Value, prefix, path string, values map[string]fileValue, problems *[]string) {
+	sectionType := section.Type()
+	for i := 0; i < sectionType.NumField(); i++ {
+		field := sectionType.Field(i)
+		value := section.Field(i)
+		if field.Anonymous {
+			flatten(value, prefix, path, values, problems)
+			continue
+		}
+		env, ok := field.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+
+		switch value.Kind() {
+		case reflect.Pointer:
+			if value.IsNil() {
+				continue
+			}
+			if value.Elem().Kind() == reflect.Struct {
+				flatten(value.Elem(), prefix+env, fieldPath, values, problems)
+				continue
+			}
+			values[prefix+env] = fileValue{value: scalar(value.Elem()), path: fieldPath}
+		case reflect.Map:
+			if value.Len() == 0 {
+				continue
+			}
+			var pairs []string
+			for _, key := range value.MapKeys() {
+				pairs = append(pairs, key.String()+"="+value.MapIndex(key).String())
+			}
+			sort.Strings(pairs)
+			values[prefix+env] = fileValue{value: strings.Join(pairs, ","), path: fieldPath}
+		case reflect.Slice:
+			if value.Len() == 0 {
+				continue
+			}
+			item, named := field.Tag.Lookup("item")
+			if !named {
+				var items []string
+				for j := 0; j < value.Len(); j++ {
+					items = append(items, value.Index(j).String())
+				}
+				values[prefix+env] = fileValue{value: strings.Join(items, ","), path: fieldPath}
+				continue
+			}
+			seen := map[string]bool{}
+			var names []string
+			for j := 0; j < value.Len(); j++ {
+				itemPath := fmt.Sprintf("%s[%d]", fieldPath, j)
+				itemName := strings.TrimSpace(value.Index(j).FieldByName("Name").String())
+				if itemName == "" {
+					*problems = append(*problems, itemPath+".name is required")
+					continue
+				}
+				if seen[envName(itemName)] {
+					*problems = append(*problems, fmt.Sprintf("%s.name %q is used more than once", itemPath, itemName))
+					continue
+				}
+				seen[envName(itemName)] = true
+				names = append(names, itemName)
+				flatten(value.Index(j), item+envName(itemName)+"_", itemPath, values, problems)
+			}
+			values[prefix+env] = fileValue{value: strings.Join(names, ","), path: fieldPath}
+		}
+	}
+}
+
+// scalar formats a setting the way it would be written in its variable
+func scalar(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.Float64:
+		return strconv.FormatFloat(value.Float(), 'g', -1, 64)
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
+
+// NewFile describes a configuration in the configuration file format, so the
+// configuration in effect can be shown in the same shape operators write it
+func NewFile(cfg *Config) *File {
+	file := &File{
+		TargetResource:             &cfg.TargetResource,
+		Cooldown:                   seconds(cfg.CooldownDuration),
+		Steps:                      intPointer(int(cfg.Steps)),
+		DryRun:                     &cfg.DryRun,
+		RecommendOnly:              &cfg.RecommendOnly,
+		WaitForActiveTimeout:       seconds(cfg.WaitForActiveTimeout),
+		WaitForActiveCheckInterval: seconds(cfg.WaitForActiveCheckInterval),
+		MaxObservationAge:          seconds(cfg.MaxObservationAge),
+		StatePolicies:              cloneMap(cfg.StatePolicies),
+		EvaluationInterval:         seconds(cfg.EvaluationInterval),
+		MinCapacity:                &cfg.MinCapacity,
+		MaxCapacity:                &cfg.MaxCapacity,
+		PolicyStrategy:             &cfg.PolicyStrategy,
+		Recovery: &FileRecovery{
+			Policy:           &cfg.Recovery.Policy,
+			Backoff:          seconds(cfg.Recovery.Backoff),
+			MaxRetries:       &cfg.Recovery.MaxRetries,
+			BreakerThreshold: &cfg.Recovery.BreakerThreshold,
+			NotifyURL:        &cfg.Recovery.NotifyURL,
+		},
+		Sidecar: &FileSidecar{
+			URL:                &cfg.Sidecar.URL,
+			Timeout:            seconds(cfg.Sidecar.Timeout),
+			RetryMax:           &cfg.Sidecar.RetryMax,
+			RetryWaitMin:       seconds(cfg.Sidecar.RetryWaitMin),
+			RetryWaitMax:       seconds(cfg.Sidecar.RetryWaitMax),
+			RateLimit:          &cfg.Sidecar.RateLimit,
+			Burst:              &cfg.Sidecar.Burst,
+			MaxRetryAfter:      seconds(cfg.Sidecar.MaxRetryAfter),
+			CAFile:             &cfg.Sidecar.CAFile,
+			CertFile:           &cfg.Sidecar.CertFile,
+			KeyFile:            &cfg.Sidecar.KeyFile,
+			ServerName:         &cfg.Sidecar.ServerName,
+			InsecureSkipVerify: &cfg.Sidecar.InsecureSkipVerify,
+		},
+	}
+	for _, source := range cfg.MetricSources {
+		file.Metrics = append(file.Metrics, FileMetric{
+			Name:        source.Name,
+			URLs:        source.URLs,
+			Family:      &source.Family,
+			Labels:      source.Labels,
+			Aggregation: &source.Aggregation,
+			Threshold:   &source.Threshold,
+		})
+	}
+	if cfg.Policy.Type != "" {
+		file.Policy = newFilePolicy(cfg.Policy)
+	}
+	for _, p := range cfg.Policies {
+		file.Policies = append(file.Policies, FileNamedPolicy{Name: p.Name, Weight: &p.Weight, FilePolicy: *newFilePolicy(p)})
+	}
+	for _, p := range cfg.ShadowPolicies {
+		file.ShadowPolicies = append(file.ShadowPolicies, FileNamedPolicy{Name: p.Name, FilePolicy: *newFilePolicy(p)})
+	}
+	if cfg.Sidecar.MaxRetryAfter > 0 && cfg.Sidecar.MaxRetryAfter < time.Second {
+		// A default cap below a second cannot be written in seconds
+		file.Sidecar.MaxRetryAfter = nil
+	}
+	if cfg.Idle.Enabled() {
+		file.Idle = &FileIdle{
+			Metric:       &cfg.Idle.Metric,
+			Threshold:    &cfg.Idle.Threshold,
+			Period:       seconds(cfg.Idle.Period),
+			WakeCapacity: &cfg.Idle.WakeCapacity,
+		}
+	}
+	if cfg.Proxy.Enabled() {
+		file.Proxy = &FileProxy{
+			Target:      &cfg.Proxy.Target,
+			Port:        &cfg.Proxy.Port,
+			HoldTimeout: seconds(cfg.Proxy.HoldTimeout),
+			Window:      seconds(cfg.Proxy.Window),
+		}
+	}
+	if cfg.Auth.Enabled() {
+		file.Auth = &FileAuth{
+			APIKeysFile:          &cfg.Auth.APIKeysFile,
+			HMACSecretFile:       &cfg.Auth.HMACSecretFile,
+			JWKS:                 &cfg.Auth.JWKS,
+			JWKSRefresh:          seconds(cfg.Auth.JWKSRefresh),
+			JWTIssuer:            &cfg.Auth.JWTIssuer,
+			JWTAudience:          &cfg.Auth.JWTAudience,
+			JWTRolesClaim:        &cfg.Auth.JWTRolesClaim,
+			UsersFile:            &cfg.Auth.UsersFile,
+			OIDCIssuer:           &cfg.Auth.OIDCIssuer,
+			OIDCClientID:         &cfg.Auth.OIDCClientID,
+			OIDCClientSecretFile: &cfg.Auth.OIDCClientSecretFile,
+			OIDCRedirectURL:      &cfg.Auth.OIDCRedirectURL,
+			SessionTTL:           seconds(cfg.Auth.SessionTTL),
+		}
+	}
+	if cfg.TLS.Enabled() {
+		file.TLS = &FileTLS{
+			CertFile:          &cfg.TLS.CertFile,
+			KeyFile:           &cfg.TLS.KeyFile,
+			ClientCAFile:      &cfg.TLS.ClientCAFile,
+			RequireClientCert: &cfg.TLS.RequireClientCert,
+			ReloadInterval:    seconds(cfg.TLS.ReloadInterval),
+		}
+	}
+	if cfg.DryRun {
+		file.Simulation = &FileSimulation{
+			InitialCapacity: &cfg.Simulation.InitialCapacity,
+			ScaleUpDelay:    seconds(cfg.Simulation.ScaleUpDelay),
+			ScaleDownDelay:  seconds(cfg.Simulation.ScaleDownDelay),
+			MaxReplicas:     &cfg.Simulation.MaxReplicas,
+			LatencyMillis:   intPointer(int(cfg.Simulation.Latency.Milliseconds())),
+			FailureRate:     &cfg.Simulation.FailureRate,
+			FailureDuration: seconds(cfg.Simulation.FailureDuration),
+		}
+	}
+	return file
+}
+
+// Redact removes what may carry credentials from a file about to be shown to
+// someone who may not read them. The notification webhook is left out, since
+// its URL usually embeds a token, and passwords in the other URLs are masked.
+// Values are replaced rather than written through, as NewFile shares them with
+// the configuration in effect.
+func (f *File) Redact() {
+	if f.Recovery != nil {
+		f.Recovery.NotifyURL = nil
+	}
+	if f.Sidecar != nil {
+		f.Sidecar.URL = redactURL(f.Sidecar.URL)
+	}
+	for i := range f.Metrics {
+		urls := make([]string, len(f.Metrics[i].URLs))
+		for j, u := range f.Metrics[i].URLs {
+			urls[j] = *redactURL(&u)
+		}
+		f.Metrics[i].URLs = urls
+	}
+	redactPolicy(f.Policy)
+	for i := range f.Policies {
+		redactPolicy(&f.Policies[i].FilePolicy)
+	}
+	for i := range f.ShadowPolicies {
+		redactPolicy(&f.ShadowPolicies[i].FilePolicy)
+	}
+}
+
+// redactPolicy masks the passwords in the URLs of a policy and its fallbacks
+func redactPolicy(p *FilePolicy) {
+	for ; p != nil; p = p.Fallback {
+		p.URL = redactURL(p.URL)
+	}
+}
+
+// redactURL masks the password of a URL, if it has one
+func redactURL(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	u, err := url.Parse(*s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	redacted := u.Redacted()
+	return &redacted
+}
+
+// newFilePolicy describes a policy and its fallbacks in the file format
+func newFilePolicy(p PolicyConfig) *FilePolicy {
+	policy := &FilePolicy{
+		Type:       &p.Type,
+		Metric:     &p.Metric,
+		Target:     &p.Target,
+		Expression: &p.Expression,
+		Timezone:   &p.Timezone,
+		URL:        &p.URL,
+		Timeout:    seconds(p.Timeout),
+	}
+	if p.Type == "slo" {
+		policy.Objective = &p.SLO.Objective
+		policy.ShortWindow = seconds(p.SLO.ShortWindow)
+		policy.LongWindow = seconds(p.SLO.LongWindow)
+		policy.ShortBurnRate = &p.SLO.ShortBurnRate
+		policy.LongBurnRate = &p.SLO.LongBurnRate
+		policy.ScaleDownBurnRate = &p.SLO.ScaleDownBurnRate
+		policy.Step = &p.SLO.Step
+	}
+	if p.Fallback != nil {
+		policy.Fallback = newFilePolicy(*p.Fallback)
+	}
+	return policy
+}
+
+func seconds(d time.Duration) *int {
+	return intPointer(int(d / time.Second))
+}
+
+func intPointer(i int) *int {
+	return &i
+}
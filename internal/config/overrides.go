@@ -0,0 +1,232 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrOverrideNotFound is returned when an override to revert does not exist
+var ErrOverrideNotFound = errors.New("override not found")
+
+// Override is a change of settings made at runtime. Its settings are keyed by
+// environment variable and take precedence over both the environment and the
+// configuration file until the override is reverted or expires.
+type Override struct {
+	ID        string            `json:"id"`
+	Time      time.Time         `json:"time"`
+	Actor     string            `json:"actor,omitempty"`
+	Reason    string            `json:"reason,omitempty"`
+	Settings  map[string]string `json:"settings"`
+	ExpiresAt *time.Time        `json:"expiresAt,omitempty"`
+}
+
+// Expired reports whether the override has run out at the given time
+func (o Override) Expired(now time.Time) bool {
+	return o.ExpiresAt != nil && !now.Before(*o.ExpiresAt)
+}
+
+// WithOverrides returns a lookupEnv for Load that finds the settings of the
+// overrides before those of the environment. Later overrides win.
+func WithOverrides(overrides []Override, lookupEnv func(string) (string, bool)) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		for i := len(overrides) - 1; i >= 0; i-- {
+			if value, ok := overrides[i].Settings[key]; ok {
+				return value, true
+			}
+		}
+		return lookupEnv(key)
+	}
+}
+
+// ParsePatch parses a partial configuration file holding the settings an
+// override changes, keyed by environment variable. Entries of the metrics and
+// policy lists refer by name to entries of the current configuration; they
+// change their settings but cannot add or remove entries.
+func ParsePatch(data []byte, current *Config) (map[string]string, error) {
+	file, problems, err := parseFile(data)
+	if err != nil {
+		return nil, err
+	}
+	values, valueProblems := file.values()
+	problems = append(problems, valueProblems...)
+
+	configured := func(name string, entries []string) bool {
+		for _, entry := range entries {
+			if envName(entry) == envName(name) {
+				return true
+			}
+		}
+		return false
+	}
+	var metrics, policies, shadowPolicies []string
+	for _, source := range current.MetricSources {
+		metrics = append(metrics, source.Name)
+	}
+	for _, p := range current.Policies {
+		policies = append(policies, p.Name)
+	}
+	for _, p := range current.ShadowPolicies {
+		shadowPolicies = append(shadowPolicies, p.Name)
+	}
+	for i, metric := range file.Metrics {
+		if metric.Name != "" && !configured(metric.Name, metrics) {
+			problems = append(problems, fmt.Sprintf("metrics[%d].name %q is not a configured metric", i, metric.Name))
+		}
+	}
+	for i, p := range file.Policies {
+		if p.Name != "" && !configured(p.Name, policies) {
+			problems = append(problems, fmt.Sprintf("policies[%d].name %q is not a configured policy", i, p.Name))
+		}
+	}
+	for i, p := range file.ShadowPolicies {
+		if p.Name != "" && !configured(p.Name, shadowPolicies) {
+			problems = append(problems, fmt.Sprintf("shadowPolicies[%d].name %q is not a configured shadow policy", i, p.Name))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+
+	// The lists name the entries being changed, not the entries to keep
+	delete(values, "AUTOSCALER_METRICS")
+	delete(values, "AUTOSCALER_POLICIES")
+	delete(values, "AUTOSCALER_SHADOW_POLICIES")
+	if len(values) == 0 {
+		return nil, &ValidationError{Problems: []string{"no settings to change"}}
+	}
+
+	settings := make(map[string]string, len(values))
+	for key, value := range values {
+		settings[key] = value.value
+	}
+	return settings, nil
+}
+
+// OverrideStore keeps the active overrides, persisting them to a JSON file so
+// they survive restarts
+type OverrideStore struct {
+	path      string
+	mu        sync.Mutex
+	overrides []Override
+}
+
+// OpenOverrideStore reads the overrides persisted at path. With an empty path
+// the overrides are only kept in memory.
+func OpenOverrideStore(path string) (*OverrideStore, error) {
+	s := &OverrideStore{path: path}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read overrides: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse overrides %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// List returns the active overrides, oldest first
+func (s *OverrideStore) List() []Override {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Override(nil), s.overrides...)
+}
+
+// Add persists a new override
+func (s *OverrideStore) Add(override Override) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	overrides := append(append([]Override(nil), s.overrides...), override)
+	if err := s.save(overrides); err != nil {
+		return err
+	}
+	s.overrides = overrides
+	return nil
+}
+
+// Remove reverts the override with the given ID
+func (s *OverrideStore) Remove(id string) (Override, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var removed *Override
+	var overrides []Override
+	for _, override := range s.overrides {
+		if override.ID == id {
+			removed = &override
+			continue
+		}
+		overrides = append(overrides, override)
+	}
+	if removed == nil {
+		return Override{}, fmt.Errorf("%w: %s", ErrOverrideNotFound, id)
+	}
+	if err := s.save(overrides); err != nil {
+		return Override{}, err
+	}
+	s.overrides = overrides
+	return *removed, nil
+}
+
+// Expire removes the overrides that have run out at the given time and
+// returns them
+func (s *OverrideStore) Expire(now time.Time) ([]Override, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var expired, overrides []Override
+	for _, override := range s.overrides {
+		if override.Expired(now) {
+			expired = append(expired, override)
+			continue
+		}
+		overrides = append(overrides, override)
+	}
+	if len(expired) == 0 {
+		return nil, nil
+	}
+	if err := s.save(overrides); err != nil {
+		return nil, err
+	}
+	s.overrides = overrides
+	return expired, nil
+}
+
+// save replaces the persisted overrides. The file is written next to its
+// final location and renamed, so a crash never leaves it half written.
+func (s *OverrideStore) save(overrides []Override) error {
+	if s.path == "" {
+		return nil
+	}
+	if overrides == nil {
+		overrides = []Override{}
+	}
+	data, err := json.MarshalIndent(overrides, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode overrides: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to persist overrides: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to persist overrides: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to persist overrides: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to persist overrides: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePatch(t *testing.T) {
+	current, err := Load(writeConfigFile(t, "autoscaler.yaml", testConfigFile), noEnv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	settings, err := ParsePatch([]byte(`{"cooldown": 60, "policies": [{"name": "queue", "target": 20}]}`), current)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The list of policies is left alone, only the named policy changes
+	expected := map[string]string{
+		"AUTOSCALER_COOLDOWN":            "60",
+		"AUTOSCALER_POLICY_QUEUE_TARGET": "20",
+	}
+	if !reflect.DeepEqual(settings, expected) {
+		t.Errorf("expected %v, got %v", expected, settings)
+	}
+}
+
+func TestParsePatch_Problems(t *testing.T) {
+	current, err := Load(writeConfigFile(t, "autoscaler.yaml", testConfigFile), noEnv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := map[string]string{
+		`{"policies": [{"name": "latency", "target": 1}]}`: `"latency" is not a configured policy`,
+		`{"cooldown": "soon"}`:                             "cannot unmarshal",
+		`{"colldown": 60}`:                                 "field colldown not found",
+		`{}`:                                               "no settings to change",
+	}
+	for patch, problem := range testCases {
+		_, err := ParsePatch([]byte(patch), current)
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("%s: expected a validation error, got %v", patch, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("%s: expected %q in %q", patch, problem, err.Error())
+		}
+	}
+}
+
+func TestWithOverrides_LaterOverridesWin(t *testing.T) {
+	env := map[string]string{"AUTOSCALER_TARGET_RESOURCE": "worker", "AUTOSCALER_STEPS": "2"}
+	lookupEnv := WithOverrides([]Override{
+		{ID: "a", Settings: map[string]string{"AUTOSCALER_STEPS": "3", "AUTOSCALER_COOLDOWN": "60"}},
+		{ID: "b", Settings: map[string]string{"AUTOSCALER_STEPS": "4"}},
+	}, func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	})
+
+	cfg, err := Load("", lookupEnv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Steps != 4 {
+		t.Errorf("expected steps 4, got %d", cfg.Steps)
+	}
+	if cfg.CooldownDuration != time.Minute {
+		t.Errorf("expected cooldown 1m, got %v", cfg.CooldownDuration)
+	}
+	if cfg.TargetResource != "worker" {
+		t.Errorf("expected target resource worker, got %s", cfg.TargetResource)
+	}
+}
+
+func TestOverrideStore_PersistsAndExpires(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.json")
+	store, err := OpenOverrideStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Unix(1700000000, 0).UTC()
+	expiresAt := now.Add(time.Hour)
+	if err := store.Add(Override{ID: "temporary", Time: now, Settings: map[string]string{"AUTOSCALER_STEPS": "2"}, ExpiresAt: &expiresAt}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Add(Override{ID: "permanent", Time: now, Settings: map[string]string{"AUTOSCALER_COOLDOWN": "60"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Reopen the store as a restarted controller would
+	store, err = OpenOverrideStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.List()) != 2 {
+		t.Fatalf("expected 2 persisted overrides, got %d", len(store.List()))
+	}
+
+	expired, err := store.Expire(now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(expired) != 1 || expired[0].ID != "temporary" {
+		t.Errorf("expected the temporary override to expire, got %+v", expired)
+	}
+
+	if _, err := store.Remove("missing"); !errors.Is(err, ErrOverrideNotFound) {
+		t.Errorf("expected ErrOverrideNotFound, got %v", err)
+	}
+	if _, err := store.Remove("permanent"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store, err = OpenOverrideStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.List()) != 0 {
+		t.Errorf("expected no persisted overrides, got %+v", store.List())
+	}
+}
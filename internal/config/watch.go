@@ -0,0 +1,50 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+)
+
+// Watch polls the configuration file at path every interval and calls
+// onChange whenever its contents change, until the context is cancelled.
+// Polling the contents rather than watching the directory also catches the
+// symlink swaps used to update mounted Kubernetes ConfigMaps.
+func Watch(ctx context.Context, path string, interval time.Duration, onChange func()) {
+	last, err := os.ReadFile(path)
+	if err != nil {
+		logger.Warn().Err(err).Str("path", path).Msg("Failed to read configuration file")
+	}
+
+	Poll(ctx, interval, func() {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			// The file can briefly disappear while it is being replaced
+			logger.Debug().Err(err).Str("path", path).Msg("Failed to read configuration file")
+			return
+		}
+		if bytes.Equal(contents, last) {
+			return
+		}
+		last = contents
+		logger.Info().Str("path", path).Msg("Configuration file changed")
+		onChange()
+	})
+}
+
+// Poll calls check every interval until the context is cancelled
+func Poll(ctx context.Context, interval time.Duration, check func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			check()
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWatch_CallsOnChange(t *testing.T) {
+	path := writeConfigFile(t, "autoscaler.yaml", "targetResource: worker\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan struct{}, 10)
+	go Watch(ctx, path, 5*time.Millisecond, func() { changes <- struct{}{} })
+
+	// Rewriting the same contents is not a change
+	time.Sleep(20 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("targetResource: worker\n"), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if len(changes) != 0 {
+		t.Fatalf("expected no change to be reported, got %d", len(changes))
+	}
+
+	if err := os.WriteFile(path, []byte("targetResource: worker\nsteps: 2\n"), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("expected the change to be reported")
+	}
+}
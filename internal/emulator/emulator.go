@@ -0,0 +1,375 @@
+// Package emulator serves the Omnistrate sidecar API from simulated resources,
+// so the controller's real HTTP client can be exercised without an Omnistrate
+// account. A control API injects faults and changes resources behind the
+// controller's back.
+package emulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
+)
+
+// Operations of the sidecar API that faults can be restricted to
+const (
+	OperationGet    = "get"
+	OperationAdd    = "add"
+	OperationRemove = "remove"
+)
+
+// Faults describes failures injected into the sidecar API of a resource.
+// Requests fail while Count is positive, each one using up one, and otherwise
+// at the given Rate. Failed requests are answered with Status, or dropped
+// without a response when Drop is set, with a Retry-After header when
+// RetryAfterSeconds is set. Every response is delayed by DelayMs.
+type Faults struct {
+	Operations        []string `json:"operations,omitempty"`
+	Count             int      `json:"count,omitempty"`
+	Rate              float64  `json:"rate,omitempty"`
+	Status            int      `json:"status,omitempty"`
+	Message           string   `json:"message,omitempty"`
+	Drop              bool     `json:"drop,omitempty"`
+	DelayMs           int      `json:"delayMs,omitempty"`
+	RetryAfterSeconds int      `json:"retryAfterSeconds,omitempty"`
+}
+
+// ResourceState is the state of an emulated resource reported by the control API
+type ResourceState struct {
+	Alias    string                         `json:"alias"`
+	Status   omnistrate_api.Status          `json:"status"`
+	Capacity int                            `json:"capacity"`
+	Requests int                            `json:"requests"`
+	Faults   *Faults                        `json:"faults,omitempty"`
+	Events   []omnistrate_api.CapacityEvent `json:"events"`
+}
+
+// ErrorResponse is returned by the emulator whenever a request fails
+type ErrorResponse struct {
+	Message string `json:"message"`
+}
+
+type resource struct {
+	simulator *omnistrate_api.Simulator
+	faults    *Faults
+}
+
+// Emulator serves the sidecar API for any resource alias, creating each
+// resource from the simulation settings on first use
+type Emulator struct {
+	clock      clock.Clock
+	simulation config.SimulationConfig
+	mux        *http.ServeMux
+
+	mu        sync.Mutex
+	resources map[string]*resource
+}
+
+// New creates an emulator whose resources follow the given simulation settings
+func New(clk clock.Clock, simulation config.SimulationConfig) *Emulator {
+	e := &Emulator{
+		clock:      clk,
+		simulation: simulation,
+		mux:        http.NewServeMux(),
+		resources:  map[string]*resource{},
+	}
+
+	e.mux.HandleFunc("GET /resource/{alias}/capacity", e.getCapacity)
+	e.mux.HandleFunc("POST /resource/{alias}/capacity/add", e.addCapacity)
+	e.mux.HandleFunc("POST /resource/{alias}/capacity/remove", e.removeCapacity)
+
+	e.mux.HandleFunc("GET /control/resources", e.listResources)
+	e.mux.HandleFunc("GET /control/resources/{alias}", e.getResource)
+	e.mux.HandleFunc("PUT /control/resources/{alias}/faults", e.setFaults)
+	e.mux.HandleFunc("DELETE /control/resources/{alias}/faults", e.clearFaults)
+	e.mux.HandleFunc("POST /control/resources/{alias}/capacity", e.driftCapacity)
+	e.mux.HandleFunc("POST /control/resources/{alias}/fail", e.failResource)
+	e.mux.HandleFunc("POST /control/reset", e.reset)
+	return e
+}
+
+func (e *Emulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mux.ServeHTTP(w, r)
+}
+
+func (e *Emulator) getCapacity(w http.ResponseWriter, r *http.Request) {
+	res, ok := e.inject(w, r, OperationGet)
+	if !ok {
+		return
+	}
+	capacity, err := res.simulator.GetCurrentCapacity(r.Context(), r.PathValue("alias"))
+	if err != nil {
+		writeSimulatorError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, capacity)
+}
+
+func (e *Emulator) addCapacity(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		CapacityToBeAdded *float64 `json:"capacityToBeAdded"`
+	}
+	res, ok := e.inject(w, r, OperationAdd)
+	if !ok {
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CapacityToBeAdded == nil || *req.CapacityToBeAdded < 0 {
+		writeError(w, http.StatusBadRequest, "capacityToBeAdded must be a non-negative number")
+		return
+	}
+	instance, err := res.simulator.AddCapacity(r.Context(), r.PathValue("alias"), uint(*req.CapacityToBeAdded))
+	if err != nil {
+		writeSimulatorError(w, err)
+		return
+	}
+	logger.Info().Str("alias", r.PathValue("alias")).Float64("capacityToBeAdded", *req.CapacityToBeAdded).Msg("Adding capacity")
+	writeJSON(w, http.StatusOK, instance)
+}
+
+func (e *Emulator) removeCapacity(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		CapacityToBeRemoved *float64 `json:"capacityToBeRemoved"`
+	}
+	res, ok := e.inject(w, r, OperationRemove)
+	if !ok {
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CapacityToBeRemoved == nil || *req.CapacityToBeRemoved < 0 {
+		writeError(w, http.StatusBadRequest, "capacityToBeRemoved must be a non-negative number")
+		return
+	}
+	instance, err := res.simulator.RemoveCapacity(r.Context(), r.PathValue("alias"), uint(*req.CapacityToBeRemoved))
+	if err != nil {
+		writeSimulatorError(w, err)
+		return
+	}
+	logger.Info().Str("alias", r.PathValue("alias")).Float64("capacityToBeRemoved", *req.CapacityToBeRemoved).Msg("Removing capacity")
+	writeJSON(w, http.StatusOK, instance)
+}
+
+// inject applies the faults configured for the request's resource. It
+// reports false when the request has already been failed.
+func (e *Emulator) inject(w http.ResponseWriter, r *http.Request, operation string) (*resource, bool) {
+	alias := r.PathValue("alias")
+
+	e.mu.Lock()
+	res := e.resource(alias)
+	faults := res.faults
+	fail := false
+	var injected Faults
+	if faults != nil && (len(faults.Operations) == 0 || slices.Contains(faults.Operations, operation)) {
+		injected = *faults
+		switch {
+		case faults.Count > 0:
+			faults.Count--
+			fail = true
+		case faults.Rate > 0:
+			fail = rand.Float64() < faults.Rate
+		}
+	}
+	e.mu.Unlock()
+
+	if injected.DelayMs > 0 {
+		select {
+		case <-time.After(time.Duration(injected.DelayMs) * time.Millisecond):
+		case <-r.Context().Done():
+			return nil, false
+		}
+	}
+	if !fail {
+		return res, true
+	}
+
+	logger.Info().Str("alias", alias).Str("operation", operation).Msg("Injecting fault")
+	if injected.Drop {
+		if hijacker, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hijacker.Hijack(); err == nil {
+				_ = conn.Close()
+				return nil, false
+			}
+		}
+	}
+	status := injected.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	message := injected.Message
+	if message == "" {
+		message = fmt.Sprintf("injected %s fault", operation)
+	}
+	if injected.RetryAfterSeconds > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(injected.RetryAfterSeconds))
+	}
+	writeError(w, status, message)
+	return nil, false
+}
+
+// resource returns the resource for an alias, creating it on first use. The
+// caller must hold the lock.
+func (e *Emulator) resource(alias string) *resource {
+	res, ok := e.resources[alias]
+	if !ok {
+		res = &resource{simulator: omnistrate_api.NewSimulator(e.clock, e.simulation)}
+		e.resources[alias] = res
+	}
+	return res
+}
+
+func (e *Emulator) listResources(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	aliases := make([]string, 0, len(e.resources))
+	for alias := range e.resources {
+		aliases = append(aliases, alias)
+	}
+	e.mu.Unlock()
+	sort.Strings(aliases)
+
+	states := make([]ResourceState, 0, len(aliases))
+	for _, alias := range aliases {
+		states = append(states, e.state(alias))
+	}
+	writeJSON(w, http.StatusOK, states)
+}
+
+func (e *Emulator) getResource(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, e.state(r.PathValue("alias")))
+}
+
+func (e *Emulator) setFaults(w http.ResponseWriter, r *http.Request) {
+	var faults Faults
+	if err := json.NewDecoder(r.Body).Decode(&faults); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid JSON: %v", err))
+		return
+	}
+	if faults.Rate < 0 || faults.Rate > 1 || faults.Count < 0 || faults.DelayMs < 0 || faults.RetryAfterSeconds < 0 {
+		writeError(w, http.StatusBadRequest, "rate must be between 0 and 1, count, delayMs and retryAfterSeconds must not be negative")
+		return
+	}
+	if faults.Status != 0 && (faults.Status < 400 || faults.Status > 599) {
+		writeError(w, http.StatusBadRequest, "status must be an HTTP error status")
+		return
+	}
+	for _, operation := range faults.Operations {
+		if operation != OperationGet && operation != OperationAdd && operation != OperationRemove {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown operation: %s", operation))
+			return
+		}
+	}
+
+	alias := r.PathValue("alias")
+	e.mu.Lock()
+	e.resource(alias).faults = &faults
+	e.mu.Unlock()
+	logger.Info().Str("alias", alias).Interface("faults", faults).Msg("Faults configured")
+	writeJSON(w, http.StatusOK, e.state(alias))
+}
+
+func (e *Emulator) clearFaults(w http.ResponseWriter, r *http.Request) {
+	alias := r.PathValue("alias")
+	e.mu.Lock()
+	e.resource(alias).faults = nil
+	e.mu.Unlock()
+	writeJSON(w, http.StatusOK, e.state(alias))
+}
+
+func (e *Emulator) driftCapacity(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Capacity *int `json:"capacity"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Capacity == nil || *req.Capacity < 0 {
+		writeError(w, http.StatusBadRequest, "capacity must be a non-negative integer")
+		return
+	}
+
+	alias := r.PathValue("alias")
+	e.mu.Lock()
+	res := e.resource(alias)
+	e.mu.Unlock()
+	res.simulator.SetCapacity(*req.Capacity)
+	logger.Info().Str("alias", alias).Int("capacity", *req.Capacity).Msg("Capacity drifted")
+	writeJSON(w, http.StatusOK, e.state(alias))
+}
+
+func (e *Emulator) failResource(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		DurationSeconds int `json:"durationSeconds"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.DurationSeconds < 0 {
+			writeError(w, http.StatusBadRequest, "durationSeconds must be a non-negative integer")
+			return
+		}
+	}
+
+	alias := r.PathValue("alias")
+	e.mu.Lock()
+	res := e.resource(alias)
+	e.mu.Unlock()
+	res.simulator.Fail(time.Duration(req.DurationSeconds) * time.Second)
+	logger.Info().Str("alias", alias).Int("durationSeconds", req.DurationSeconds).Msg("Resource failed")
+	writeJSON(w, http.StatusOK, e.state(alias))
+}
+
+func (e *Emulator) reset(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	e.resources = map[string]*resource{}
+	e.mu.Unlock()
+	logger.Info().Msg("Emulator reset")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// state reports the state of a resource, creating it on first use
+func (e *Emulator) state(alias string) ResourceState {
+	e.mu.Lock()
+	res := e.resource(alias)
+	var faults *Faults
+	if res.faults != nil {
+		copied := *res.faults
+		faults = &copied
+	}
+	e.mu.Unlock()
+
+	events := res.simulator.Events()
+	last := events[len(events)-1]
+	return ResourceState{
+		Alias:    alias,
+		Status:   last.Status,
+		Capacity: last.Capacity,
+		Requests: res.simulator.Requests(),
+		Faults:   faults,
+		Events:   events,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Warn().Err(err).Msg("Failed to encode JSON response")
+	}
+}
+
+// writeSimulatorError answers with the status and message the simulator chose
+func writeSimulatorError(w http.ResponseWriter, err error) {
+	var apiErr *omnistrate_api.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode != 0 {
+		writeError(w, apiErr.StatusCode, apiErr.Message)
+		return
+	}
+	writeError(w, http.StatusServiceUnavailable, err.Error())
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, ErrorResponse{Message: message})
+}
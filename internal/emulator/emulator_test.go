@@ -0,0 +1,142 @@
+package emulator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *clock.Simulated) {
+	clk := clock.NewSimulated(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	server := httptest.NewServer(New(clk, config.SimulationConfig{
+		InitialCapacity: 2,
+		ScaleUpDelay:    time.Minute,
+	}))
+	t.Cleanup(server.Close)
+	return server, clk
+}
+
+func do(t *testing.T, method, url, body string) *http.Response {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func getCapacity(t *testing.T, server *httptest.Server, alias string) omnistrate_api.ResourceInstanceCapacity {
+	resp := do(t, http.MethodGet, server.URL+"/resource/"+alias+"/capacity", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var capacity omnistrate_api.ResourceInstanceCapacity
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&capacity))
+	return capacity
+}
+
+func TestEmulator_StatusTransitions(t *testing.T) {
+	server, clk := newTestServer(t)
+
+	resp := do(t, http.MethodPost, server.URL+"/resource/worker/capacity/add", `{"capacityToBeAdded": 2}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	capacity := getCapacity(t, server, "worker")
+	assert.Equal(t, omnistrate_api.STARTING, capacity.Status)
+	assert.Equal(t, 2, capacity.CurrentCapacity)
+	assert.Equal(t, "worker", capacity.ResourceAlias)
+
+	resp = do(t, http.MethodPost, server.URL+"/resource/worker/capacity/remove", `{"capacityToBeRemoved": 1}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "capacity cannot change while STARTING")
+
+	clk.Advance(time.Minute)
+	capacity = getCapacity(t, server, "worker")
+	assert.Equal(t, omnistrate_api.ACTIVE, capacity.Status)
+	assert.Equal(t, 4, capacity.CurrentCapacity)
+
+	// Resources are independent per alias
+	assert.Equal(t, 2, getCapacity(t, server, "other").CurrentCapacity)
+}
+
+func TestEmulator_InjectedFaults(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	resp := do(t, http.MethodPut, server.URL+"/control/resources/worker/faults",
+		`{"operations": ["add"], "count": 2, "status": 503, "message": "sidecar overloaded"}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Only the add operation fails, for the configured number of requests
+	assert.Equal(t, omnistrate_api.ACTIVE, getCapacity(t, server, "worker").Status)
+	for range 2 {
+		resp = do(t, http.MethodPost, server.URL+"/resource/worker/capacity/add", `{"capacityToBeAdded": 1}`)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		var body ErrorResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "sidecar overloaded", body.Message)
+	}
+	resp = do(t, http.MethodPost, server.URL+"/resource/worker/capacity/add", `{"capacityToBeAdded": 1}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Dropped requests close the connection without a response
+	do(t, http.MethodPut, server.URL+"/control/resources/worker/faults", `{"count": 1, "drop": true}`)
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/resource/worker/capacity", nil)
+	require.NoError(t, err)
+	_, err = http.DefaultClient.Do(req)
+	assert.Error(t, err)
+
+	// Slow responses are delayed
+	do(t, http.MethodPut, server.URL+"/control/resources/worker/faults", `{"delayMs": 100}`)
+	start := time.Now()
+	getCapacity(t, server, "worker")
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	resp = do(t, http.MethodDelete, server.URL+"/control/resources/worker/faults", "")
+	var state ResourceState
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&state))
+	assert.Nil(t, state.Faults)
+	assert.Equal(t, 1, state.Requests)
+}
+
+func TestEmulator_DriftAndFailure(t *testing.T) {
+	server, clk := newTestServer(t)
+
+	resp := do(t, http.MethodPost, server.URL+"/control/resources/worker/capacity", `{"capacity": 5}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	capacity := getCapacity(t, server, "worker")
+	assert.Equal(t, omnistrate_api.ACTIVE, capacity.Status)
+	assert.Equal(t, 5, capacity.CurrentCapacity)
+
+	resp = do(t, http.MethodPost, server.URL+"/control/resources/worker/fail", `{"durationSeconds": 60}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, omnistrate_api.FAILED, getCapacity(t, server, "worker").Status)
+
+	clk.Advance(time.Minute)
+	assert.Equal(t, omnistrate_api.ACTIVE, getCapacity(t, server, "worker").Status)
+
+	resp = do(t, http.MethodGet, server.URL+"/control/resources", "")
+	var states []ResourceState
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&states))
+	require.Len(t, states, 1)
+	assert.Equal(t, "worker", states[0].Alias)
+	assert.Equal(t, 5, states[0].Capacity)
+}
+
+func TestEmulator_InvalidRequests(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	resp := do(t, http.MethodPost, server.URL+"/resource/worker/capacity/add", `{"capacityToBeAdded": -1}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = do(t, http.MethodPut, server.URL+"/control/resources/worker/faults", `{"rate": 2}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = do(t, http.MethodPut, server.URL+"/control/resources/worker/faults", `{"operations": ["scale"]}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,249 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metric types as declared by "# TYPE" lines
+const (
+	TypeCounter   = "counter"
+	TypeGauge     = "gauge"
+	TypeHistogram = "histogram"
+	TypeSummary   = "summary"
+	TypeUntyped   = "untyped"
+)
+
+// suffixes that samples of a family may carry in either exposition format
+var sampleSuffixes = []string{"_total", "_bucket", "_count", "_sum", "_created", "_gcount", "_gsum", "_info"}
+
+// Family is a group of samples sharing a metric name and type
+type Family struct {
+	Name    string
+	Type    string
+	Samples []Sample
+}
+
+// Sample is one line of an exposition
+type Sample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// Key returns a stable identifier for the sample's series
+func (s Sample) Key() string {
+	names := make([]string, 0, len(s.Labels))
+	for name := range s.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(s.Name)
+	for _, name := range names {
+		b.WriteString("|")
+		b.WriteString(name)
+		b.WriteString("=")
+		b.WriteString(s.Labels[name])
+	}
+	return b.String()
+}
+
+// Matches reports whether the sample carries every selector label with the same value
+func (s Sample) Matches(selector map[string]string) bool {
+	for name, value := range selector {
+		if s.Labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseExposition parses the Prometheus text format or OpenMetrics text format
+func ParseExposition(r io.Reader) (map[string]*Family, error) {
+	families := map[string]*Family{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			fields := strings.Fields(line)
+			if len(fields) >= 2 && fields[1] == "EOF" {
+				break
+			}
+			if len(fields) >= 4 && fields[1] == "TYPE" {
+				family := familyFor(families, fields[2])
+				family.Type = strings.ToLower(fields[3])
+				if family.Type == "unknown" {
+					family.Type = TypeUntyped
+				}
+			}
+			continue
+		}
+
+		sample, err := parseSample(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		family := familyFor(families, familyName(families, sample.Name))
+		family.Samples = append(family.Samples, sample)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return families, nil
+}
+
+// Lookup finds a family by name, accepting both the OpenMetrics family name
+// and the Prometheus sample name of counters ("requests" vs "requests_total")
+func Lookup(families map[string]*Family, name string) (*Family, bool) {
+	if family, ok := families[name]; ok {
+		return family, true
+	}
+	if trimmed := strings.TrimSuffix(name, "_total"); trimmed != name {
+		family, ok := families[trimmed]
+		return family, ok
+	}
+	family, ok := families[name+"_total"]
+	return family, ok
+}
+
+func familyFor(families map[string]*Family, name string) *Family {
+	family, ok := families[name]
+	if !ok {
+		family = &Family{Name: name, Type: TypeUntyped}
+		families[name] = family
+	}
+	return family
+}
+
+// familyName maps a sample name to the declared family it belongs to
+func familyName(families map[string]*Family, sampleName string) string {
+	if _, ok := families[sampleName]; ok {
+		return sampleName
+	}
+	for _, suffix := range sampleSuffixes {
+		if trimmed := strings.TrimSuffix(sampleName, suffix); trimmed != sampleName {
+			if _, ok := families[trimmed]; ok {
+				return trimmed
+			}
+		}
+	}
+	return sampleName
+}
+
+func parseSample(line string) (Sample, error) {
+	sample := Sample{Labels: map[string]string{}}
+
+	nameEnd := strings.IndexAny(line, "{ \t")
+	if nameEnd <= 0 {
+		return sample, fmt.Errorf("malformed sample %q", line)
+	}
+	sample.Name = line[:nameEnd]
+	rest := line[nameEnd:]
+
+	if strings.HasPrefix(rest, "{") {
+		labels, remaining, err := parseLabels(rest[1:])
+		if err != nil {
+			return sample, err
+		}
+		sample.Labels = labels
+		rest = remaining
+	}
+
+	// Drop OpenMetrics exemplars, then read the value and ignore any timestamp
+	if i := strings.Index(rest, " # "); i >= 0 {
+		rest = rest[:i]
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return sample, fmt.Errorf("missing value in sample %q", line)
+	}
+	value, err := parseValue(fields[0])
+	if err != nil {
+		return sample, fmt.Errorf("invalid value in sample %q: %w", line, err)
+	}
+	sample.Value = value
+
+	return sample, nil
+}
+
+// parseLabels reads name="value" pairs up to the closing brace
+func parseLabels(s string) (map[string]string, string, error) {
+	labels := map[string]string{}
+	i := 0
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == ',') {
+			i++
+		}
+		if i >= len(s) {
+			return nil, "", fmt.Errorf("unterminated label set")
+		}
+		if s[i] == '}' {
+			return labels, s[i+1:], nil
+		}
+
+		eq := strings.IndexByte(s[i:], '=')
+		if eq < 0 {
+			return nil, "", fmt.Errorf("malformed label set")
+		}
+		name := strings.TrimSpace(s[i : i+eq])
+		i += eq + 1
+		if i >= len(s) || s[i] != '"' {
+			return nil, "", fmt.Errorf("label %s is not quoted", name)
+		}
+		i++
+
+		var value strings.Builder
+		for {
+			if i >= len(s) {
+				return nil, "", fmt.Errorf("unterminated value for label %s", name)
+			}
+			c := s[i]
+			if c == '"' {
+				i++
+				break
+			}
+			if c == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(s[i])
+				}
+				i++
+				continue
+			}
+			value.WriteByte(c)
+			i++
+		}
+		labels[name] = value.String()
+	}
+}
+
+func parseValue(s string) (float64, error) {
+	switch s {
+	case "+Inf", "Inf":
+		return math.Inf(1), nil
+	case "-Inf":
+		return math.Inf(-1), nil
+	case "NaN":
+		return math.NaN(), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
@@ -0,0 +1,300 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+	"github.com/pkg/errors"
+)
+
+const acceptHeader = "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5,*/*;q=0.1"
+
+// Aggregation combines the selected series of all scraped endpoints into one value
+type Aggregation string
+
+const (
+	AggregationSum Aggregation = "sum"
+	AggregationAvg Aggregation = "avg"
+	AggregationMax Aggregation = "max"
+	AggregationMin Aggregation = "min"
+)
+
+// PrometheusScraper reads a metric family from one or more /metrics endpoints.
+// Gauges are reported as-is while counters are converted into a per-second rate
+// between consecutive scrapes. Histograms are reported as the fraction of
+// observations above the configured threshold between consecutive scrapes,
+// which makes them suitable as the bad-event ratio of a latency SLO.
+type PrometheusScraper struct {
+	name        string
+	urls        []string
+	family      string
+	labels      map[string]string
+	aggregation Aggregation
+	threshold   float64
+	httpClient  *http.Client
+	now         func() time.Time
+
+	mu       sync.Mutex
+	previous map[string]counterPoint
+}
+
+type counterPoint struct {
+	value float64
+	time  time.Time
+}
+
+// reading is what a single endpoint contributed to a scrape. Histogram event
+// counts are summed across endpoints rather than aggregated as ratios so that
+// busy endpoints weigh more than idle ones.
+type reading struct {
+	values    []float64
+	histogram bool
+	bad       float64
+	total     float64
+}
+
+// NewPrometheusScraper creates a scraper from its configuration
+func NewPrometheusScraper(cfg config.MetricSourceConfig) (*PrometheusScraper, error) {
+	aggregation := Aggregation(cfg.Aggregation)
+	switch aggregation {
+	case AggregationSum, AggregationAvg, AggregationMax, AggregationMin:
+	default:
+		return nil, fmt.Errorf("unsupported aggregation %q for metric source %s", cfg.Aggregation, cfg.Name)
+	}
+
+	return &PrometheusScraper{
+		name:        cfg.Name,
+		urls:        cfg.URLs,
+		family:      cfg.Family,
+		labels:      cfg.Labels,
+		aggregation: aggregation,
+		threshold:   cfg.Threshold,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		now:         time.Now,
+		previous:    map[string]counterPoint{},
+	}, nil
+}
+
+func (s *PrometheusScraper) Name() string {
+	return s.name
+}
+
+// Read scrapes every endpoint and aggregates the selected series. Endpoints that
+// cannot be scraped are skipped as long as at least one succeeds.
+func (s *PrometheusScraper) Read(ctx context.Context) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var values []float64
+	var histogram bool
+	var bad, total float64
+	var lastErr error
+	failed := 0
+	for _, url := range s.urls {
+		endpoint, err := s.scrape(ctx, url)
+		if err != nil {
+			logger.Warn().Err(err).Str("source", s.name).Str("url", url).Msg("Failed to scrape metrics endpoint")
+			lastErr = err
+			failed++
+			continue
+		}
+		values = append(values, endpoint.values...)
+		histogram = histogram || endpoint.histogram
+		bad += endpoint.bad
+		total += endpoint.total
+	}
+
+	if failed == len(s.urls) {
+		return 0, errors.Wrapf(lastErr, "Failed to scrape metric source %s", s.name)
+	}
+	if histogram {
+		if total <= 0 {
+			return 0, ErrNoData
+		}
+		return bad / total, nil
+	}
+	if len(values) == 0 {
+		return 0, ErrNoData
+	}
+	return aggregate(s.aggregation, values), nil
+}
+
+func (s *PrometheusScraper) scrape(ctx context.Context, url string) (reading, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return reading{}, err
+	}
+	req.Header.Set("Accept", acceptHeader)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return reading{}, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return reading{}, errors.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	families, err := ParseExposition(resp.Body)
+	if err != nil {
+		return reading{}, errors.Wrap(err, "Failed to parse exposition")
+	}
+	family, ok := Lookup(families, s.family)
+	if !ok {
+		return reading{}, errors.Errorf("metric family %s not found", s.family)
+	}
+
+	now := s.now()
+	var values []float64
+	switch family.Type {
+	case TypeHistogram:
+		return s.histogram(url, family, now)
+	case TypeCounter:
+		for _, sample := range family.Samples {
+			if (sample.Name != family.Name && sample.Name != family.Name+"_total") || !sample.Matches(s.labels) {
+				continue
+			}
+			if rate, ok := s.rate(url+"|"+sample.Key(), sample.Value, now); ok {
+				values = append(values, rate)
+			}
+		}
+	case TypeGauge, TypeUntyped:
+		for _, sample := range family.Samples {
+			if sample.Name == family.Name && sample.Matches(s.labels) && !math.IsNaN(sample.Value) {
+				values = append(values, sample.Value)
+			}
+		}
+	default:
+		return reading{}, errors.Errorf("metric family %s has unsupported type %s", family.Name, family.Type)
+	}
+
+	return reading{values: values}, nil
+}
+
+// histogram counts the observations above the threshold since the previous
+// scrape. An observation is good when it falls into the smallest bucket whose
+// upper bound is at or above the threshold, so the threshold should match a
+// bucket boundary of the instrumented histogram.
+func (s *PrometheusScraper) histogram(url string, family *Family, now time.Time) (reading, error) {
+	if s.threshold <= 0 {
+		return reading{}, errors.Errorf("histogram metric family %s requires a positive threshold", family.Name)
+	}
+
+	type series struct {
+		goodBound float64
+		good      float64
+		count     float64
+		hasBucket bool
+		hasCount  bool
+	}
+	all := map[string]*series{}
+	seriesFor := func(sample Sample) *series {
+		labels := make(map[string]string, len(sample.Labels))
+		for name, value := range sample.Labels {
+			if name != "le" {
+				labels[name] = value
+			}
+		}
+		key := Sample{Name: family.Name, Labels: labels}.Key()
+		if all[key] == nil {
+			all[key] = &series{goodBound: math.Inf(1)}
+		}
+		return all[key]
+	}
+
+	for _, sample := range family.Samples {
+		if !sample.Matches(s.labels) {
+			continue
+		}
+		switch sample.Name {
+		case family.Name + "_bucket":
+			bound, err := strconv.ParseFloat(sample.Labels["le"], 64)
+			if err != nil || bound < s.threshold {
+				continue
+			}
+			if current := seriesFor(sample); !current.hasBucket || bound < current.goodBound {
+				current.goodBound = bound
+				current.good = sample.Value
+				current.hasBucket = true
+			}
+		case family.Name + "_count":
+			current := seriesFor(sample)
+			current.count = sample.Value
+			current.hasCount = true
+		}
+	}
+
+	result := reading{histogram: true}
+	for key, current := range all {
+		if !current.hasBucket || !current.hasCount {
+			continue
+		}
+		good, okGood := s.increase(url+"|"+key+"|good", current.good, now)
+		count, okCount := s.increase(url+"|"+key+"|count", current.count, now)
+		if !okGood || !okCount || count <= 0 {
+			continue
+		}
+		result.bad += math.Max(count-good, 0)
+		result.total += count
+	}
+	return result, nil
+}
+
+// rate records the counter value and returns the per-second increase since the
+// previous scrape of the same series, treating a decrease as a counter reset
+func (s *PrometheusScraper) rate(key string, value float64, now time.Time) (float64, bool) {
+	previous := s.previous[key]
+	increase, ok := s.increase(key, value, now)
+	if !ok {
+		return 0, false
+	}
+	return increase / now.Sub(previous.time).Seconds(), true
+}
+
+// increase records the counter value and returns how much it grew since the
+// previous scrape of the same series, treating a decrease as a counter reset
+func (s *PrometheusScraper) increase(key string, value float64, now time.Time) (float64, bool) {
+	previous, ok := s.previous[key]
+	s.previous[key] = counterPoint{value: value, time: now}
+	if !ok || !now.After(previous.time) {
+		return 0, false
+	}
+
+	increase := value - previous.value
+	if increase < 0 {
+		increase = value
+	}
+	return increase, true
+}
+
+func aggregate(aggregation Aggregation, values []float64) float64 {
+	result := values[0]
+	switch aggregation {
+	case AggregationSum, AggregationAvg:
+		result = 0
+		for _, v := range values {
+			result += v
+		}
+		if aggregation == AggregationAvg {
+			result /= float64(len(values))
+		}
+	case AggregationMax:
+		for _, v := range values[1:] {
+			result = math.Max(result, v)
+		}
+	case AggregationMin:
+		for _, v := range values[1:] {
+			result = math.Min(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,211 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const prometheusText = `# HELP worker_busy_ratio Fraction of time the worker was busy
+# TYPE worker_busy_ratio gauge
+worker_busy_ratio{queue="default"} 0.5
+worker_busy_ratio{queue="batch"} 0.9
+# HELP http_requests_total Requests served
+# TYPE http_requests_total counter
+http_requests_total{code="200",path="/a\"b"} 100 1700000000000
+http_requests_total{code="500",path="/"} 4
+`
+
+const openMetricsText = `# TYPE http_requests counter
+# UNIT http_requests requests
+http_requests_total{code="200"} 10 # {trace_id="abc"} 1.0 1700000000.000
+http_requests_created{code="200"} 1700000000
+# TYPE worker_busy_ratio gauge
+worker_busy_ratio 0.25
+# EOF
+`
+
+func TestParseExposition_PrometheusText(t *testing.T) {
+	families, err := ParseExposition(strings.NewReader(prometheusText))
+	require.NoError(t, err)
+
+	gauge, ok := Lookup(families, "worker_busy_ratio")
+	require.True(t, ok)
+	assert.Equal(t, TypeGauge, gauge.Type)
+	assert.Len(t, gauge.Samples, 2)
+
+	counter, ok := Lookup(families, "http_requests")
+	require.True(t, ok)
+	assert.Equal(t, TypeCounter, counter.Type)
+	require.Len(t, counter.Samples, 2)
+	assert.Equal(t, `/a"b`, counter.Samples[0].Labels["path"])
+	assert.Equal(t, 100.0, counter.Samples[0].Value)
+}
+
+func TestParseExposition_OpenMetrics(t *testing.T) {
+	families, err := ParseExposition(strings.NewReader(openMetricsText))
+	require.NoError(t, err)
+
+	counter, ok := Lookup(families, "http_requests_total")
+	require.True(t, ok)
+	assert.Equal(t, "http_requests", counter.Name)
+	assert.Equal(t, TypeCounter, counter.Type)
+	require.Len(t, counter.Samples, 2)
+	assert.Equal(t, "http_requests_total", counter.Samples[0].Name)
+	assert.Equal(t, 10.0, counter.Samples[0].Value)
+}
+
+func TestParseExposition_Malformed(t *testing.T) {
+	_, err := ParseExposition(strings.NewReader(`broken{label=unquoted} 1`))
+	assert.Error(t, err)
+}
+
+func TestPrometheusScraper_GaugeWithLabels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, prometheusText)
+	}))
+	defer server.Close()
+
+	scraper, err := NewPrometheusScraper(config.MetricSourceConfig{
+		Name:        "busy",
+		URLs:        []string{server.URL},
+		Family:      "worker_busy_ratio",
+		Labels:      map[string]string{"queue": "batch"},
+		Aggregation: "avg",
+	})
+	require.NoError(t, err)
+
+	value, err := scraper.Read(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0.9, value)
+}
+
+func TestPrometheusScraper_CounterRateAcrossEndpoints(t *testing.T) {
+	var requests atomic.Int64
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Each endpoint serves 10 more requests between scrapes
+		total := 10 * (requests.Add(1) + 1) / 2
+		_, _ = fmt.Fprintf(w, "# TYPE http_requests counter\nhttp_requests_total{code=\"200\"} %d\n# EOF\n", total)
+	})
+	first := httptest.NewServer(handler)
+	defer first.Close()
+	second := httptest.NewServer(handler)
+	defer second.Close()
+
+	scraper, err := NewPrometheusScraper(config.MetricSourceConfig{
+		Name:        "rps",
+		URLs:        []string{first.URL, second.URL},
+		Family:      "http_requests",
+		Aggregation: "sum",
+	})
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	scraper.now = func() time.Time { return now }
+
+	_, err = scraper.Read(context.Background())
+	assert.True(t, errors.Is(err, ErrNoData))
+
+	now = now.Add(5 * time.Second)
+	value, err := scraper.Read(context.Background())
+	require.NoError(t, err)
+	assert.InDelta(t, 4.0, value, 0.0001) // 2 endpoints * 10 requests / 5s
+}
+
+func TestPrometheusScraper_CounterReset(t *testing.T) {
+	scraper, err := NewPrometheusScraper(config.MetricSourceConfig{Name: "rps", Aggregation: "sum"})
+	require.NoError(t, err)
+	start := time.Unix(1700000000, 0)
+
+	_, ok := scraper.rate("series", 100, start)
+	assert.False(t, ok)
+	rate, ok := scraper.rate("series", 20, start.Add(10*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, rate)
+}
+
+func TestPrometheusScraper_AllEndpointsFail(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	scraper, err := NewPrometheusScraper(config.MetricSourceConfig{
+		Name:        "busy",
+		URLs:        []string{server.URL},
+		Family:      "worker_busy_ratio",
+		Aggregation: "max",
+	})
+	require.NoError(t, err)
+
+	_, err = scraper.Read(context.Background())
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrNoData))
+}
+
+func TestPrometheusScraper_HistogramBadEventRatio(t *testing.T) {
+	var scrapes atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Between scrapes 100 more requests arrive, 90 of them within 200ms
+		n := scrapes.Add(1)
+		_, _ = fmt.Fprintf(w, `# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{route="/api",le="0.1"} %d
+request_duration_seconds_bucket{route="/api",le="0.2"} %d
+request_duration_seconds_bucket{route="/api",le="+Inf"} %d
+request_duration_seconds_sum{route="/api"} 12.5
+request_duration_seconds_count{route="/api"} %d
+`, 50*n, 90*n, 100*n, 100*n)
+	}))
+	defer server.Close()
+
+	scraper, err := NewPrometheusScraper(config.MetricSourceConfig{
+		Name:        "slow-requests",
+		URLs:        []string{server.URL},
+		Family:      "request_duration_seconds",
+		Aggregation: "avg",
+		Threshold:   0.2,
+	})
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+	scraper.now = func() time.Time { return now }
+
+	_, err = scraper.Read(context.Background())
+	assert.True(t, errors.Is(err, ErrNoData))
+
+	now = now.Add(30 * time.Second)
+	value, err := scraper.Read(context.Background())
+	require.NoError(t, err)
+	assert.InDelta(t, 0.1, value, 0.0001)
+}
+
+func TestPrometheusScraper_HistogramRequiresThreshold(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "# TYPE latency histogram\nlatency_bucket{le=\"+Inf\"} 1\nlatency_count 1\n")
+	}))
+	defer server.Close()
+
+	scraper, err := NewPrometheusScraper(config.MetricSourceConfig{
+		Name:        "latency",
+		URLs:        []string{server.URL},
+		Family:      "latency",
+		Aggregation: "avg",
+	})
+	require.NoError(t, err)
+
+	_, err = scraper.Read(context.Background())
+	assert.Error(t, err)
+}
+
+func TestNewPrometheusScraper_InvalidAggregation(t *testing.T) {
+	_, err := NewPrometheusScraper(config.MetricSourceConfig{Name: "busy", Aggregation: "median"})
+	assert.Error(t, err)
+}
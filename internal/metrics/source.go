@@ -0,0 +1,16 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoData is returned by a source that cannot produce a reading yet, for
+// example a counter that needs a second scrape before a rate can be computed
+var ErrNoData = errors.New("metric source has no data yet")
+
+// Source produces a single scalar reading that scaling policies can act upon
+type Source interface {
+	Name() string
+	Read(ctx context.Context) (float64, error)
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+	"github.com/pkg/errors"
+)
+
+// Event types
+const (
+	EventInstanceFailed = "instance_failed"
+	EventFrozen         = "automation_frozen"
+	EventCircuitOpen    = "circuit_open"
+	EventCircuitReset   = "circuit_reset"
+)
+
+// Event is a notification about something an operator has to look at
+type Event struct {
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	Resource string    `json:"resource"`
+	Message  string    `json:"message"`
+}
+
+// Notifier delivers events to operators
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Log is a notifier that only writes events to the log
+type Log struct{}
+
+func (Log) Notify(ctx context.Context, event Event) error {
+	logger.Error().
+		Str("type", event.Type).
+		Str("resource", event.Resource).
+		Msg(event.Message)
+	return nil
+}
+
+// Webhook posts events as JSON to a URL, such as the inbound webhook of a
+// paging service. Events are logged as well, so they are not lost when the
+// webhook cannot be reached.
+type Webhook struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewWebhook creates a notifier posting to the given URL
+func NewWebhook(url string, timeout time.Duration) *Webhook {
+	return &Webhook{url: url, httpClient: &http.Client{Timeout: timeout}}
+}
+
+func (w *Webhook) Notify(ctx context.Context, event Event) error {
+	_ = Log{}.Notify(ctx, event)
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to marshal %s notification", event.Type)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "Failed to create %s notification request", event.Type)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to send %s notification", event.Type)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Failed to send %s notification, status code: %d", event.Type, resp.StatusCode)
+	}
+	return nil
+}
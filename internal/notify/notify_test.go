@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_PostsEvent(t *testing.T) {
+	received := make(chan Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var event Event
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		received <- event
+	}))
+	defer server.Close()
+
+	event := Event{
+		Time:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Type:     EventCircuitOpen,
+		Resource: "worker",
+		Message:  "3 consecutive failures",
+	}
+	require.NoError(t, NewWebhook(server.URL, time.Second).Notify(context.Background(), event))
+	assert.Equal(t, event, <-received)
+}
+
+func TestWebhook_ReportsFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	err := NewWebhook(server.URL, time.Second).Notify(context.Background(), Event{Type: EventFrozen})
+	assert.ErrorContains(t, err, "status code: 500")
+}
@@ -6,19 +6,19 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"time"
+	"net/url"
+	"strings"
 
-	"github.com/go-openapi/strfmt"
 	"github.com/hashicorp/go-retryablehttp"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
 	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
 	"github.com/pkg/errors"
 )
 
 const (
-	baseURL                  = "http://127.0.0.1:49750/resource/"
-	addCapacityURL           = baseURL + "%s/capacity/add"
-	removeCapacityURL        = baseURL + "%s/capacity/remove"
-	getCapacityURL           = baseURL + "%s/capacity"
+	addCapacityPath          = "/resource/%s/capacity/add"
+	removeCapacityPath       = "/resource/%s/capacity/remove"
+	getCapacityPath          = "/resource/%s/capacity"
 	capacityToBeAddedField   = "capacityToBeAdded"
 	capacityToBeRemovedField = "capacityToBeRemoved"
 )
@@ -35,73 +35,65 @@ type Client interface {
 type ClientImpl struct {
 	config     *config.Config
 	httpClient *retryablehttp.Client
+	baseURL    string
+	throttle   *throttle
 }
 
-func NewWithHTTPClient(config *config.Config, httpClient *retryablehttp.Client) Client {
-	return &ClientImpl{config: config, httpClient: httpClient}
+// NewWithHTTPClient returns a client sending requests to the sidecar at
+// baseURL through the given HTTP client
+func NewWithHTTPClient(config *config.Config, httpClient *retryablehttp.Client, baseURL string) Client {
+	return &ClientImpl{config: config, httpClient: httpClient, baseURL: strings.TrimSuffix(baseURL, "/")}
 }
 
-func NewClient(config *config.Config) Client {
-	retryClient := retryablehttp.NewClient()
-	retryClient.RetryMax = 3
-	retryClient.RetryWaitMin = 1 * time.Second
-	retryClient.RetryWaitMax = 30 * time.Second
-	retryClient.HTTPClient.Timeout = 60 * time.Second
-	return NewWithHTTPClient(config, retryClient)
-}
-
-func (c *ClientImpl) GetCurrentCapacity(ctx context.Context, resourceAlias string) (resp ResourceInstanceCapacity, err error) {
-	if c.config.DryRun {
-		return ResourceInstanceCapacity{
-			InstanceID:            "instance-abc",
-			ResourceID:            "resource-abc",
-			ResourceAlias:         resourceAlias,
-			Status:                ACTIVE,
-			CurrentCapacity:       10,
-			LastObservedTimestamp: strfmt.DateTime(time.Now().UTC()),
-		}, nil
+// NewClient returns a client for the configured sidecar, or a simulated
+// resource when dry-run mode is enabled
+func NewClient(config *config.Config) (Client, error) {
+	if config.DryRun {
+		return NewSimulator(clock.Real{}, config.Simulation), nil
 	}
 
-	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(getCapacityURL, resourceAlias), nil)
+	transport, baseURL, err := newTransport(config.Sidecar)
 	if err != nil {
-		return
-	}
-	httpResp, err := c.httpClient.Do(req)
-	if err != nil {
-		err = errors.Wrapf(err, "Failed get current capacity for resourceAlias: %s", resourceAlias)
-		return
-	}
-	if httpResp.StatusCode != http.StatusOK {
-		err = errors.Errorf("Failed get current capacity for resourceAlias: %s, status code: %d", resourceAlias, httpResp.StatusCode)
-		return
+		return nil, err
 	}
-	defer func() {
-		if closeErr := httpResp.Body.Close(); closeErr != nil {
-			err = errors.Wrapf(closeErr, "Failed to close response body")
-		}
-	}()
-	body, err := io.ReadAll(httpResp.Body)
-	if err != nil {
-		err = errors.Wrapf(err, "Failed read response body when querying current capacity for resourceAlias: %s", resourceAlias)
-		return
+	throttle := newThrottle(config.Sidecar.RateLimit, config.Sidecar.Burst, config.Sidecar.MaxRetryAfter)
+	retryClient := retryablehttp.NewClient()
+	retryClient.RetryMax = config.Sidecar.RetryMax
+	retryClient.RetryWaitMin = config.Sidecar.RetryWaitMin
+	retryClient.RetryWaitMax = config.Sidecar.RetryWaitMax
+	retryClient.Backoff = throttle.backoff
+	// Hand the last response back once retries are exhausted so its status
+	// and error message can be reported
+	retryClient.ErrorHandler = retryablehttp.PassthroughErrorHandler
+	retryClient.HTTPClient = &http.Client{
+		Transport: &throttledTransport{next: transport, throttle: throttle},
+		Timeout:   config.Sidecar.Timeout,
 	}
-	err = json.Unmarshal(body, &resp)
-	if err != nil {
-		err = errors.Wrapf(err, "Failed unmarshal response body when querying current capacity for resourceAlias: %s", resourceAlias)
-		return
+
+	client := NewWithHTTPClient(config, retryClient, baseURL).(*ClientImpl)
+	client.throttle = throttle
+	return client, nil
+}
+
+// ThrottleStatus reports how requests to the sidecar are being throttled
+func (c *ClientImpl) ThrottleStatus() ThrottleStatus {
+	if c.throttle == nil {
+		return ThrottleStatus{}
 	}
+	return c.throttle.status()
+}
+
+// url returns the URL of a sidecar endpoint for the given resource
+func (c *ClientImpl) url(path string, resourceAlias string) string {
+	return c.baseURL + fmt.Sprintf(path, url.PathEscape(resourceAlias))
+}
+
+func (c *ClientImpl) GetCurrentCapacity(ctx context.Context, resourceAlias string) (resp ResourceInstanceCapacity, err error) {
+	err = c.do(ctx, "get current capacity", http.MethodGet, getCapacityPath, resourceAlias, nil, &resp)
 	return
 }
 
 func (c *ClientImpl) AddCapacity(ctx context.Context, resourceAlias string, capacityToBeAdded uint) (resp ResourceInstance, err error) {
-	if c.config.DryRun {
-		return ResourceInstance{
-			InstanceID:    "instance-abc",
-			ResourceID:    "resource-abc",
-			ResourceAlias: resourceAlias,
-		}, nil
-	}
-
 	if capacityToBeAdded == 0 {
 		return ResourceInstance{
 			InstanceID:    "instance-abc",
@@ -113,50 +105,11 @@ func (c *ClientImpl) AddCapacity(ctx context.Context, resourceAlias string, capa
 	reqBody := map[string]interface{}{
 		capacityToBeAddedField: float64(capacityToBeAdded),
 	}
-	reqBytes, err := json.Marshal(reqBody)
-	if err != nil {
-		err = errors.Wrapf(err, "Failed marshal request body when adding capacity for resourceAlias: %s", resourceAlias)
-		return
-	}
-	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(addCapacityURL, resourceAlias), reqBytes)
-	if err != nil {
-		return
-	}
-	req.Header.Add("Content-Type", "application/json")
-	httpResp, err := c.httpClient.Do(req)
-	if err != nil {
-		err = errors.Wrapf(err, "Failed to add capacity for resourceAlias: %s", resourceAlias)
-		return
-	}
-	if httpResp.StatusCode != http.StatusOK {
-		err = errors.Errorf("Failed to add capacity for resourceAlias: %s, status code: %d", resourceAlias, httpResp.StatusCode)
-		return
-	}
-	defer func() {
-		if closeErr := httpResp.Body.Close(); closeErr != nil {
-			err = errors.Wrapf(closeErr, "Failed to close response body")
-		}
-	}()
-	body, err := io.ReadAll(httpResp.Body)
-	if err != nil {
-		err = errors.Wrapf(err, "Failed read response body when adding capacity for resourceAlias: %s", resourceAlias)
-		return
-	}
-	err = json.Unmarshal(body, &resp)
-	if err != nil {
-		err = errors.Wrapf(err, "Failed unmarshal response body when adding capacity for resourceAlias: %s", resourceAlias)
-		return
-	}
+	err = c.do(ctx, "add capacity", http.MethodPost, addCapacityPath, resourceAlias, reqBody, &resp)
 	return
 }
 
 func (c *ClientImpl) RemoveCapacity(ctx context.Context, resourceAlias string, capacityToBeRemoved uint) (resp ResourceInstance, err error) {
-	if c.config.DryRun {
-		return ResourceInstance{
-			ResourceAlias: resourceAlias,
-		}, nil
-	}
-
 	if capacityToBeRemoved == 0 {
 		return ResourceInstance{
 			ResourceAlias: resourceAlias,
@@ -166,40 +119,57 @@ func (c *ClientImpl) RemoveCapacity(ctx context.Context, resourceAlias string, c
 	reqBody := map[string]interface{}{
 		capacityToBeRemovedField: float64(capacityToBeRemoved),
 	}
-	reqBytes, err := json.Marshal(reqBody)
-	if err != nil {
-		err = errors.Wrapf(err, "Failed marshal request body when removing capacity for resourceAlias: %s", resourceAlias)
-		return
+	err = c.do(ctx, "remove capacity", http.MethodPost, removeCapacityPath, resourceAlias, reqBody, &resp)
+	return
+}
+
+// do sends a request to a sidecar endpoint and decodes the response into out.
+// Requests that get no response or an error response fail with an *APIError.
+func (c *ClientImpl) do(ctx context.Context, operation, method, path, resourceAlias string, reqBody interface{}, out interface{}) (err error) {
+	var reqBytes []byte
+	if reqBody != nil {
+		reqBytes, err = json.Marshal(reqBody)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to marshal %s request for resourceAlias: %s", operation, resourceAlias)
+		}
 	}
-	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(removeCapacityURL, resourceAlias), reqBytes)
-	if err != nil {
-		err = errors.Wrapf(err, "Failed to create remove capacity request for resourceAlias: %s", resourceAlias)
-		return
+
+	var body interface{}
+	if reqBytes != nil {
+		body = reqBytes
 	}
-	req.Header.Add("Content-Type", "application/json")
-	httpResp, err := c.httpClient.Do(req)
+	req, err := retryablehttp.NewRequestWithContext(ctx, method, c.url(path, resourceAlias), body)
 	if err != nil {
-		err = errors.Wrapf(err, "Failed to remove capacity for resourceAlias: %s", resourceAlias)
-		return
+		return errors.Wrapf(err, "Failed to create %s request for resourceAlias: %s", operation, resourceAlias)
 	}
-	if httpResp.StatusCode != http.StatusOK {
-		err = errors.Errorf("Failed to remove capacity for resourceAlias: %s, status code: %d", resourceAlias, httpResp.StatusCode)
-		return
+	if reqBytes != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+
+	if c.throttle != nil {
+		if err := c.throttle.waitRetryAfter(ctx); err != nil {
+			return newTransportError(operation, resourceAlias, err)
+		}
+	}
+	httpResp, err := c.httpClient.Do(req)
+	if err != nil && httpResp == nil {
+		return newTransportError(operation, resourceAlias, err)
 	}
 	defer func() {
-		if closeErr := httpResp.Body.Close(); closeErr != nil {
+		if closeErr := httpResp.Body.Close(); closeErr != nil && err == nil {
 			err = errors.Wrapf(closeErr, "Failed to close response body")
 		}
 	}()
-	body, err := io.ReadAll(httpResp.Body)
+
+	respBytes, err := io.ReadAll(httpResp.Body)
 	if err != nil {
-		err = errors.Wrapf(err, "Failed read response body when removing capacity for resourceAlias: %s", resourceAlias)
-		return
+		return errors.Wrapf(err, "Failed to read %s response for resourceAlias: %s", operation, resourceAlias)
 	}
-	err = json.Unmarshal(body, &resp)
-	if err != nil {
-		err = errors.Wrapf(err, "Failed unmarshal response body when removing capacity for resourceAlias: %s", resourceAlias)
-		return
+	if httpResp.StatusCode != http.StatusOK {
+		return newResponseError(operation, resourceAlias, httpResp.StatusCode, respBytes)
+	}
+	if err := json.Unmarshal(respBytes, out); err != nil {
+		return errors.Wrapf(err, "Failed to unmarshal %s response for resourceAlias: %s", operation, resourceAlias)
 	}
-	return resp, nil
+	return nil
 }
@@ -0,0 +1,227 @@
+package omnistrate_api_test
+
+import (
+	"context"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/emulator"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEmulator() *emulator.Emulator {
+	return emulator.New(clock.Real{}, config.SimulationConfig{InitialCapacity: 2})
+}
+
+func newClient(t *testing.T, sidecar config.SidecarConfig) omnistrate_api.Client {
+	sidecar.Timeout = 5 * time.Second
+	sidecar.RetryWaitMin = 10 * time.Millisecond
+	sidecar.RetryWaitMax = 10 * time.Millisecond
+	client, err := omnistrate_api.NewClient(&config.Config{Sidecar: sidecar})
+	require.NoError(t, err)
+	return client
+}
+
+func exerciseClient(t *testing.T, client omnistrate_api.Client) {
+	ctx := context.Background()
+
+	capacity, err := client.GetCurrentCapacity(ctx, "worker")
+	require.NoError(t, err)
+	assert.Equal(t, omnistrate_api.ACTIVE, capacity.Status)
+	assert.Equal(t, 2, capacity.CurrentCapacity)
+
+	_, err = client.AddCapacity(ctx, "worker", 3)
+	require.NoError(t, err)
+	_, err = client.RemoveCapacity(ctx, "worker", 1)
+	require.NoError(t, err)
+
+	capacity, err = client.GetCurrentCapacity(ctx, "worker")
+	require.NoError(t, err)
+	assert.Equal(t, 4, capacity.CurrentCapacity)
+}
+
+func TestClient_HTTP(t *testing.T) {
+	server := httptest.NewServer(newEmulator())
+	defer server.Close()
+
+	exerciseClient(t, newClient(t, config.SidecarConfig{URL: server.URL}))
+}
+
+func TestClient_UnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "sidecar.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	server := &http.Server{Handler: newEmulator(), ReadHeaderTimeout: time.Second}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	exerciseClient(t, newClient(t, config.SidecarConfig{URL: "unix://" + socket}))
+}
+
+func TestClient_TLSWithCustomCA(t *testing.T) {
+	server := httptest.NewTLSServer(newEmulator())
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0o600))
+
+	exerciseClient(t, newClient(t, config.SidecarConfig{URL: server.URL, CAFile: caFile}))
+
+	// Without the CA the server's certificate is not trusted
+	_, err := newClient(t, config.SidecarConfig{URL: server.URL}).GetCurrentCapacity(context.Background(), "worker")
+	assert.Error(t, err)
+}
+
+func TestClient_MissingCAFile(t *testing.T) {
+	_, err := omnistrate_api.NewClient(&config.Config{Sidecar: config.SidecarConfig{
+		URL:    "https://sidecar.example.com",
+		CAFile: filepath.Join(t.TempDir(), "missing.pem"),
+	}})
+	assert.ErrorContains(t, err, "Failed to read sidecar CA file")
+}
+
+func TestClient_TypedErrors(t *testing.T) {
+	server := httptest.NewServer(newEmulator())
+	defer server.Close()
+	client := newClient(t, config.SidecarConfig{URL: server.URL, RetryMax: 1})
+	ctx := context.Background()
+
+	injectFaults := func(faults string) {
+		req, err := http.NewRequest(http.MethodPut, server.URL+"/control/resources/worker/faults", strings.NewReader(faults))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	testCases := []struct {
+		name      string
+		faults    string
+		sentinel  error
+		status    int
+		retryable bool
+	}{
+		{"not found", `{"count": 1, "status": 404, "message": "unknown alias"}`, omnistrate_api.ErrNotFound, 404, false},
+		{"conflict", `{"count": 1, "status": 409, "message": "instance is STARTING"}`, omnistrate_api.ErrConflict, 409, false},
+		{"unavailable", `{"count": 2, "status": 503, "message": "sidecar not ready"}`, omnistrate_api.ErrUnavailable, 503, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			injectFaults(tc.faults)
+
+			_, err := client.GetCurrentCapacity(ctx, "worker")
+
+			assert.ErrorIs(t, err, tc.sentinel)
+			var apiErr *omnistrate_api.APIError
+			if assert.ErrorAs(t, err, &apiErr) {
+				assert.Equal(t, tc.status, apiErr.StatusCode)
+				assert.NotEmpty(t, apiErr.Message)
+				assert.Contains(t, err.Error(), apiErr.Message)
+				assert.Equal(t, tc.retryable, apiErr.Retryable)
+			}
+			assert.Equal(t, tc.retryable, omnistrate_api.IsRetryable(err))
+		})
+	}
+
+	// Requests that never reach the sidecar are retryable and unavailable
+	server.Close()
+	_, err := client.GetCurrentCapacity(ctx, "worker")
+	assert.ErrorIs(t, err, omnistrate_api.ErrUnavailable)
+	assert.True(t, omnistrate_api.IsRetryable(err))
+}
+
+func TestClient_RetryAfter(t *testing.T) {
+	server := httptest.NewServer(newEmulator())
+	defer server.Close()
+	client := newClient(t, config.SidecarConfig{URL: server.URL, RetryMax: 1})
+
+	req, err := http.NewRequest(http.MethodPut, server.URL+"/control/resources/worker/faults",
+		strings.NewReader(`{"count": 1, "status": 429, "message": "slow down", "retryAfterSeconds": 1}`))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// The retry waits for the Retry-After instead of the short backoff
+	start := time.Now()
+	_, err = client.GetCurrentCapacity(context.Background(), "worker")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+
+	status := client.(omnistrate_api.ThrottleReporter).ThrottleStatus()
+	assert.Equal(t, 1, status.RateLimitedCount)
+	assert.NotNil(t, status.LastRateLimited)
+	assert.Equal(t, 1, status.DelayedRequests)
+	assert.False(t, status.Throttled)
+}
+
+func TestClient_RetryAfterLongerThanTimeout(t *testing.T) {
+	server := httptest.NewServer(newEmulator())
+	defer server.Close()
+	client, err := omnistrate_api.NewClient(&config.Config{Sidecar: config.SidecarConfig{
+		URL:          server.URL,
+		Timeout:      500 * time.Millisecond,
+		RetryMax:     1,
+		RetryWaitMin: 10 * time.Millisecond,
+		RetryWaitMax: 10 * time.Millisecond,
+	}})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPut, server.URL+"/control/resources/worker/faults",
+		strings.NewReader(`{"count": 1, "status": 429, "message": "slow down", "retryAfterSeconds": 1}`))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// The Retry-After is waited out between attempts, not within the timeout
+	// of the retry
+	start := time.Now()
+	_, err = client.GetCurrentCapacity(context.Background(), "worker")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+
+	status := client.(omnistrate_api.ThrottleReporter).ThrottleStatus()
+	assert.Equal(t, 1, status.RateLimitedCount)
+	assert.Equal(t, 1, status.DelayedRequests)
+	assert.Zero(t, status.RejectedByLimiter)
+}
+
+func TestClient_RateLimit(t *testing.T) {
+	server := httptest.NewServer(newEmulator())
+	defer server.Close()
+	client := newClient(t, config.SidecarConfig{URL: server.URL, RateLimit: 10, Burst: 2})
+
+	// Requests beyond the burst are spaced out at the rate limit
+	start := time.Now()
+	for range 4 {
+		_, err := client.GetCurrentCapacity(context.Background(), "worker")
+		require.NoError(t, err)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+
+	status := client.(omnistrate_api.ThrottleReporter).ThrottleStatus()
+	assert.Equal(t, 10.0, status.RateLimit)
+	assert.Equal(t, 2, status.DelayedRequests)
+	assert.Zero(t, status.RateLimitedCount)
+
+	// Requests waiting for a token give up with their context
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	for range 3 {
+		_, _ = client.GetCurrentCapacity(ctx, "worker")
+	}
+	assert.Positive(t, client.(omnistrate_api.ThrottleReporter).ThrottleStatus().RejectedByLimiter)
+}
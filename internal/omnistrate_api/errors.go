@@ -0,0 +1,123 @@
+package omnistrate_api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Sentinel errors matched with errors.Is against the errors returned by a Client
+var (
+	// ErrNotFound means the sidecar does not know the resource alias
+	ErrNotFound = errors.New("resource not found")
+	// ErrConflict means the resource cannot change capacity in its current state
+	ErrConflict = errors.New("resource state conflict")
+	// ErrBadRequest means the sidecar rejected the request as invalid
+	ErrBadRequest = errors.New("invalid request")
+	// ErrRateLimited means the sidecar asked the client to slow down
+	ErrRateLimited = errors.New("rate limited by sidecar")
+	// ErrUnavailable means the sidecar could not be reached or is not ready
+	ErrUnavailable = errors.New("sidecar unavailable")
+)
+
+// maxErrorMessageLength bounds how much of an unstructured error body is kept
+const maxErrorMessageLength = 512
+
+// APIError is returned when a sidecar request fails, either with an error
+// response or without reaching the sidecar at all (StatusCode 0). Retryable
+// reports whether the same request may succeed later.
+type APIError struct {
+	Operation     string
+	ResourceAlias string
+	StatusCode    int
+	Message       string
+	Retryable     bool
+	Err           error
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("Failed to %s for resourceAlias: %s", e.Operation, e.ResourceAlias)
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(", status code: %d", e.StatusCode)
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	} else if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the sentinel error for the response status
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrUnavailable:
+		return e.StatusCode == 0 || e.StatusCode == http.StatusBadGateway ||
+			e.StatusCode == http.StatusServiceUnavailable || e.StatusCode == http.StatusGatewayTimeout
+	}
+	return false
+}
+
+// IsRetryable reports whether err is a sidecar error that may succeed when retried
+func IsRetryable(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Retryable
+}
+
+// newResponseError builds the error for a non-200 response, parsing the
+// message from a JSON error body or falling back to the body text
+func newResponseError(operation, resourceAlias string, statusCode int, body []byte) *APIError {
+	return &APIError{
+		Operation:     operation,
+		ResourceAlias: resourceAlias,
+		StatusCode:    statusCode,
+		Message:       errorMessage(body),
+		Retryable:     statusCode == http.StatusTooManyRequests || statusCode >= 500,
+	}
+}
+
+// newTransportError builds the error for a request that got no response
+func newTransportError(operation, resourceAlias string, err error) *APIError {
+	return &APIError{
+		Operation:     operation,
+		ResourceAlias: resourceAlias,
+		Retryable:     true,
+		Err:           err,
+	}
+}
+
+func errorMessage(body []byte) string {
+	var parsed struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+		Detail  string `json:"detail"`
+	}
+	if json.Unmarshal(body, &parsed) == nil {
+		for _, message := range []string{parsed.Message, parsed.Error, parsed.Detail} {
+			if message != "" {
+				return message
+			}
+		}
+	}
+
+	message := strings.TrimSpace(string(body))
+	if len(message) > maxErrorMessageLength {
+		message = message[:maxErrorMessageLength] + "..."
+	}
+	return message
+}
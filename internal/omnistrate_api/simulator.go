@@ -0,0 +1,224 @@
+package omnistrate_api
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+)
+
+// CapacityEvent records a change in the capacity or status of the simulated resource
+type CapacityEvent struct {
+	Time     time.Time `json:"time"`
+	Capacity int       `json:"capacity"`
+	Status   Status    `json:"status"`
+}
+
+// Simulator is an in-memory Client for a single resource. Adding or removing
+// capacity moves the resource to STARTING, and the new capacity becomes
+// ACTIVE once the configured delay has passed on the simulator's clock. A
+// change can instead end in FAILED, either at random at the configured
+// failure rate or when injected with Fail.
+type Simulator struct {
+	clock  clock.Clock
+	config config.SimulationConfig
+
+	mu          sync.Mutex
+	capacity    int
+	status      Status
+	pending     int
+	pendingFail bool
+	readyAt     time.Time
+	recoverAt   time.Time
+	events      []CapacityEvent
+	requests    int
+}
+
+// NewSimulator creates a simulated resource that is ACTIVE at its initial capacity
+func NewSimulator(clk clock.Clock, config config.SimulationConfig) *Simulator {
+	s := &Simulator{
+		clock:    clk,
+		config:   config,
+		capacity: config.InitialCapacity,
+		status:   ACTIVE,
+	}
+	s.record(clk.Now())
+	return s
+}
+
+func (s *Simulator) GetCurrentCapacity(ctx context.Context, resourceAlias string) (ResourceInstanceCapacity, error) {
+	if err := s.wait(ctx); err != nil {
+		return ResourceInstanceCapacity{}, newTransportError("get current capacity", resourceAlias, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	s.advance(now)
+
+	return ResourceInstanceCapacity{
+		InstanceID:            "instance-sim",
+		ResourceID:            "resource-sim",
+		ResourceAlias:         resourceAlias,
+		Status:                s.status,
+		CurrentCapacity:       s.capacity,
+		LastObservedTimestamp: strfmt.DateTime(now.UTC()),
+	}, nil
+}
+
+func (s *Simulator) AddCapacity(ctx context.Context, resourceAlias string, capacityToBeAdded uint) (ResourceInstance, error) {
+	if err := s.change(ctx, "add capacity", resourceAlias, int(capacityToBeAdded), s.config.ScaleUpDelay); err != nil {
+		return ResourceInstance{}, err
+	}
+	return s.instance(resourceAlias), nil
+}
+
+func (s *Simulator) RemoveCapacity(ctx context.Context, resourceAlias string, capacityToBeRemoved uint) (ResourceInstance, error) {
+	if err := s.change(ctx, "remove capacity", resourceAlias, -int(capacityToBeRemoved), s.config.ScaleDownDelay); err != nil {
+		return ResourceInstance{}, err
+	}
+	return s.instance(resourceAlias), nil
+}
+
+// Fail puts the resource in FAILED immediately, abandoning any pending change.
+// It returns to ACTIVE at its current capacity after the given duration, or
+// stays FAILED when the duration is zero.
+func (s *Simulator) Fail(duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	s.advance(now)
+	s.fail(now, duration)
+}
+
+// SetCapacity changes the capacity immediately without a status transition,
+// as if the resource had drifted from what the controller last requested
+func (s *Simulator) SetCapacity(capacity int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	s.advance(now)
+	s.capacity = max(capacity, 0)
+	s.record(now)
+}
+
+// Capacity returns the capacity currently serving, without recording an observation
+func (s *Simulator) Capacity() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(s.clock.Now())
+	return s.capacity
+}
+
+// Events returns every capacity and status change so far, oldest first
+func (s *Simulator) Events() []CapacityEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(s.clock.Now())
+	return append([]CapacityEvent(nil), s.events...)
+}
+
+// Requests returns how many add and remove capacity requests were accepted
+func (s *Simulator) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// change starts a change of capacity, failing the way the sidecar would when
+// the resource is not ACTIVE or the change exceeds the max replicas
+func (s *Simulator) change(ctx context.Context, operation, resourceAlias string, delta int, delay time.Duration) error {
+	if err := s.wait(ctx); err != nil {
+		return newTransportError(operation, resourceAlias, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	s.advance(now)
+
+	if delta == 0 {
+		return nil
+	}
+	if s.status != ACTIVE {
+		return &APIError{
+			Operation:     operation,
+			ResourceAlias: resourceAlias,
+			StatusCode:    http.StatusConflict,
+			Message:       fmt.Sprintf("instance is %s", s.status),
+		}
+	}
+	target := max(s.capacity+delta, 0)
+	if s.config.MaxReplicas > 0 && target > s.config.MaxReplicas {
+		return &APIError{
+			Operation:     operation,
+			ResourceAlias: resourceAlias,
+			StatusCode:    http.StatusBadRequest,
+			Message:       fmt.Sprintf("capacity %d exceeds max replicas %d", target, s.config.MaxReplicas),
+		}
+	}
+
+	s.requests++
+	s.pending = target
+	s.pendingFail = s.config.FailureRate > 0 && rand.Float64() < s.config.FailureRate
+	s.status = STARTING
+	s.readyAt = now.Add(delay)
+	s.record(now)
+	s.advance(now)
+	return nil
+}
+
+// wait simulates the latency of a call to the sidecar
+func (s *Simulator) wait(ctx context.Context) error {
+	if s.config.Latency <= 0 {
+		return nil
+	}
+	return s.clock.Sleep(ctx, s.config.Latency)
+}
+
+// advance completes a pending change or recovers from a failure once its time
+// has come. The caller must hold the lock.
+func (s *Simulator) advance(now time.Time) {
+	if s.status == STARTING && !now.Before(s.readyAt) {
+		if s.pendingFail {
+			s.fail(s.readyAt, s.config.FailureDuration)
+		} else {
+			s.capacity = s.pending
+			s.status = ACTIVE
+			s.record(s.readyAt)
+		}
+	}
+	if s.status == FAILED && !s.recoverAt.IsZero() && !now.Before(s.recoverAt) {
+		s.status = ACTIVE
+		s.record(s.recoverAt)
+	}
+}
+
+// fail moves the resource to FAILED. The caller must hold the lock.
+func (s *Simulator) fail(at time.Time, duration time.Duration) {
+	s.status = FAILED
+	s.recoverAt = time.Time{}
+	if duration > 0 {
+		s.recoverAt = at.Add(duration)
+	}
+	s.record(at)
+}
+
+// record appends the current state to the event log. The caller must hold the lock.
+func (s *Simulator) record(t time.Time) {
+	s.events = append(s.events, CapacityEvent{Time: t, Capacity: s.capacity, Status: s.status})
+}
+
+func (s *Simulator) instance(resourceAlias string) ResourceInstance {
+	return ResourceInstance{
+		InstanceID:    "instance-sim",
+		ResourceID:    "resource-sim",
+		ResourceAlias: resourceAlias,
+	}
+}
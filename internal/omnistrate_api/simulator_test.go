@@ -0,0 +1,112 @@
+package omnistrate_api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/clock"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulator_ProvisioningDelay(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewSimulated(start)
+	sim := NewSimulator(clk, config.SimulationConfig{InitialCapacity: 2, ScaleUpDelay: time.Minute})
+
+	_, err := sim.AddCapacity(ctx, "r", 1)
+	require.NoError(t, err)
+
+	capacity, err := sim.GetCurrentCapacity(ctx, "r")
+	require.NoError(t, err)
+	assert.Equal(t, STARTING, capacity.Status)
+	assert.Equal(t, 2, capacity.CurrentCapacity)
+
+	_, err = sim.AddCapacity(ctx, "r", 1)
+	assert.Error(t, err, "capacity cannot change while STARTING")
+
+	clk.Advance(time.Minute)
+	capacity, err = sim.GetCurrentCapacity(ctx, "r")
+	require.NoError(t, err)
+	assert.Equal(t, ACTIVE, capacity.Status)
+	assert.Equal(t, 3, capacity.CurrentCapacity)
+	assert.Equal(t, 1, sim.Requests())
+
+	assert.Equal(t, []CapacityEvent{
+		{Time: start, Capacity: 2, Status: ACTIVE},
+		{Time: start, Capacity: 2, Status: STARTING},
+		{Time: start.Add(time.Minute), Capacity: 3, Status: ACTIVE},
+	}, sim.Events())
+}
+
+func TestSimulator_RemoveWithoutDelay(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewSimulated(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	sim := NewSimulator(clk, config.SimulationConfig{InitialCapacity: 1})
+
+	_, err := sim.RemoveCapacity(ctx, "r", 3)
+	require.NoError(t, err)
+
+	capacity, err := sim.GetCurrentCapacity(ctx, "r")
+	require.NoError(t, err)
+	assert.Equal(t, ACTIVE, capacity.Status)
+	assert.Equal(t, 0, capacity.CurrentCapacity)
+}
+
+func TestSimulator_InjectedFailures(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewSimulated(start)
+	sim := NewSimulator(clk, config.SimulationConfig{
+		InitialCapacity: 2,
+		ScaleUpDelay:    time.Minute,
+		FailureRate:     1,
+		FailureDuration: 5 * time.Minute,
+	})
+
+	_, err := sim.AddCapacity(ctx, "r", 1)
+	require.NoError(t, err)
+	clk.Advance(time.Minute)
+
+	capacity, err := sim.GetCurrentCapacity(ctx, "r")
+	require.NoError(t, err)
+	assert.Equal(t, FAILED, capacity.Status)
+	assert.Equal(t, 2, capacity.CurrentCapacity)
+	_, err = sim.RemoveCapacity(ctx, "r", 1)
+	assert.ErrorContains(t, err, "instance is FAILED")
+
+	clk.Advance(5 * time.Minute)
+	capacity, err = sim.GetCurrentCapacity(ctx, "r")
+	require.NoError(t, err)
+	assert.Equal(t, ACTIVE, capacity.Status)
+	assert.Equal(t, 2, capacity.CurrentCapacity)
+
+	sim.Fail(0)
+	clk.Advance(time.Hour)
+	capacity, err = sim.GetCurrentCapacity(ctx, "r")
+	require.NoError(t, err)
+	assert.Equal(t, FAILED, capacity.Status, "a failure without a duration is permanent")
+}
+
+func TestSimulator_MaxReplicasAndLatency(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewSimulated(start)
+	sim := NewSimulator(clk, config.SimulationConfig{
+		InitialCapacity: 2,
+		MaxReplicas:     3,
+		Latency:         200 * time.Millisecond,
+	})
+
+	_, err := sim.AddCapacity(ctx, "r", 2)
+	assert.ErrorContains(t, err, "capacity 4 exceeds max replicas 3")
+	assert.Equal(t, start.Add(200*time.Millisecond), clk.Now())
+
+	_, err = sim.AddCapacity(ctx, "r", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, sim.Capacity())
+	assert.Equal(t, 1, sim.Requests())
+}
@@ -0,0 +1,239 @@
+package omnistrate_api
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ThrottleStatus reports how sidecar requests are being throttled, both by the
+// client-side rate limiter and by Retry-After responses from the sidecar
+type ThrottleStatus struct {
+	RateLimit         float64       `json:"rateLimit"`
+	Burst             int           `json:"burst"`
+	Throttled         bool          `json:"throttled"`
+	RetryAfterUntil   *time.Time    `json:"retryAfterUntil,omitempty"`
+	RateLimitedCount  int           `json:"rateLimitedCount"`
+	LastRateLimited   *time.Time    `json:"lastRateLimited,omitempty"`
+	DelayedRequests   int           `json:"delayedRequests"`
+	TotalDelay        time.Duration `json:"totalDelay"`
+	RejectedByLimiter int           `json:"rejectedByLimiter"`
+}
+
+// ThrottleReporter is implemented by clients that throttle sidecar requests
+type ThrottleReporter interface {
+	ThrottleStatus() ThrottleStatus
+}
+
+// throttle spaces out sidecar requests with a token bucket and holds them back
+// while the sidecar has asked the client to retry later
+type throttle struct {
+	rate          float64
+	burst         int
+	maxRetryAfter time.Duration
+
+	mu              sync.Mutex
+	tokens          float64
+	last            time.Time
+	retryAfterUntil time.Time
+	rateLimited     int
+	lastRateLimited time.Time
+	delayed         int
+	totalDelay      time.Duration
+	rejected        int
+}
+
+// newThrottle creates a throttle allowing rate requests per second with the
+// given burst. A rate of zero disables the token bucket.
+func newThrottle(rate float64, burst int, maxRetryAfter time.Duration) *throttle {
+	return &throttle{
+		rate:          rate,
+		burst:         max(burst, 1),
+		maxRetryAfter: maxRetryAfter,
+		tokens:        float64(max(burst, 1)),
+		last:          time.Now(),
+	}
+}
+
+// wait blocks until the token bucket allows a request to be sent, or the
+// context is done
+func (t *throttle) wait(ctx context.Context) error {
+	return t.sleep(ctx, t.reserve(), true)
+}
+
+// waitRetryAfter blocks until a Retry-After from the sidecar has passed, or the
+// context is done. It runs before a request is handed to the HTTP client so
+// that the wait does not count against the per-attempt timeout.
+func (t *throttle) waitRetryAfter(ctx context.Context) error {
+	return t.sleep(ctx, t.retryAfterDelay(), false)
+}
+
+// sleep waits for delay, counting the request as rejected if the context is
+// done first. A request that reserved a token gives it back.
+func (t *throttle) sleep(ctx context.Context, delay time.Duration, reserved bool) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		t.rejected++
+		if reserved && t.rate > 0 {
+			t.tokens = math.Min(t.tokens+1, float64(t.burst))
+		}
+		t.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token and returns how long the caller must wait before
+// sending its request
+func (t *throttle) reserve() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rate <= 0 {
+		return 0
+	}
+	now := time.Now()
+
+	var delay time.Duration
+	t.tokens = math.Min(t.tokens+now.Sub(t.last).Seconds()*t.rate, float64(t.burst))
+	t.last = now
+	t.tokens--
+	if t.tokens < 0 {
+		delay = time.Duration(-t.tokens / t.rate * float64(time.Second))
+		t.delayed++
+		t.totalDelay += delay
+	}
+	return delay
+}
+
+// retryAfterDelay returns how long is left of the latest Retry-After from the
+// sidecar
+func (t *throttle) retryAfterDelay() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delay := t.retryAfterUntil.Sub(time.Now())
+	if delay <= 0 {
+		return 0
+	}
+	t.delayed++
+	t.totalDelay += delay
+	return delay
+}
+
+// observe records a Retry-After from a 429 or 503 response so that no further
+// requests are sent before it has passed
+func (t *throttle) observe(resp *http.Response) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		t.rateLimited++
+		t.lastRateLimited = now
+	}
+	retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	if !ok {
+		return
+	}
+	if t.maxRetryAfter > 0 && retryAfter > t.maxRetryAfter {
+		retryAfter = t.maxRetryAfter
+	}
+	if until := now.Add(retryAfter); until.After(t.retryAfterUntil) {
+		t.retryAfterUntil = until
+	}
+}
+
+func (t *throttle) status() ThrottleStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+
+	status := ThrottleStatus{
+		RateLimit:         t.rate,
+		Burst:             t.burst,
+		RateLimitedCount:  t.rateLimited,
+		DelayedRequests:   t.delayed,
+		TotalDelay:        t.totalDelay,
+		RejectedByLimiter: t.rejected,
+	}
+	if t.retryAfterUntil.After(now) {
+		until := t.retryAfterUntil
+		status.RetryAfterUntil = &until
+		status.Throttled = true
+	}
+	if t.rate > 0 {
+		tokens := t.tokens + now.Sub(t.last).Seconds()*t.rate
+		status.Throttled = status.Throttled || tokens < 1
+	}
+	if !t.lastRateLimited.IsZero() {
+		last := t.lastRateLimited
+		status.LastRateLimited = &last
+	}
+	return status
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// throttledTransport passes every request attempt, including retries,
+// through the token bucket and records Retry-After responses
+type throttledTransport struct {
+	next     http.RoundTripper
+	throttle *throttle
+}
+
+func (t *throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.throttle.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err == nil {
+		t.throttle.observe(resp)
+	}
+	return resp, err
+}
+
+// backoff is the retry backoff for a throttled client. The retry client sleeps
+// between attempts outside the per-attempt timeout, so a Retry-After from the
+// sidecar is waited out here rather than in the transport. Responses without
+// a Retry-After back off exponentially.
+func (t *throttle) backoff(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		if _, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return t.retryAfterDelay()
+		}
+	}
+	mult := math.Pow(2, float64(attemptNum)) * float64(min)
+	sleep := time.Duration(mult)
+	if float64(sleep) != mult || sleep > max {
+		sleep = max
+	}
+	return sleep
+}
@@ -0,0 +1,77 @@
+package omnistrate_api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/pkg/errors"
+)
+
+// unixHost stands in for the host of requests sent over a Unix socket
+const unixHost = "sidecar"
+
+// newTransport builds the HTTP transport and base URL for the configured
+// sidecar. Requests to a unix:// sidecar are sent over its socket with a
+// placeholder host.
+func newTransport(sidecar config.SidecarConfig) (*http.Transport, string, error) {
+	parsed, err := url.Parse(sidecar.URL)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "Failed to parse sidecar URL: %s", sidecar.URL)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	baseURL := sidecar.URL
+	switch parsed.Scheme {
+	case "unix":
+		socket := parsed.Path
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		transport.Proxy = nil
+		baseURL = "http://" + unixHost
+	case "https":
+		tlsConfig, err := newTLSConfig(sidecar)
+		if err != nil {
+			return nil, "", err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport, baseURL, nil
+}
+
+// newTLSConfig loads the CA and client certificate used to reach an https:// sidecar
+func newTLSConfig(sidecar config.SidecarConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         sidecar.ServerName,
+		InsecureSkipVerify: sidecar.InsecureSkipVerify, // #nosec G402 -- opt-in for test sidecars
+	}
+
+	if sidecar.CAFile != "" {
+		caPEM, err := os.ReadFile(sidecar.CAFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read sidecar CA file: %s", sidecar.CAFile)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, errors.Errorf("Failed to parse sidecar CA file: %s", sidecar.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if sidecar.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(sidecar.CertFile, sidecar.KeyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to load sidecar client certificate: %s", sidecar.CertFile)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
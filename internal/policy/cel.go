@@ -0,0 +1,202 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"cel.dev/cel-go/cel"
+	"cel.dev/cel-go/common/types"
+	"cel.dev/cel-go/common/types/ref"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+)
+
+// TypeCEL evaluates a CEL expression to compute the desired capacity
+const TypeCEL = "cel"
+
+// identifier matches metric names that can be used directly as CEL variables
+// once dashes have been replaced with underscores
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// CEL computes the desired capacity from a CEL expression over the metric
+// readings, the current capacity and bounds, and the time of day. Every metric
+// source is available as a variable of the same name with dashes replaced by
+// underscores, and through the metrics map. An expression that reads a metric
+// without data fails to evaluate and leaves the capacity unchanged.
+//
+// Besides the standard CEL functions, ceil, floor, round, min and max are
+// available for numbers. Fractional results are rounded up.
+type CEL struct {
+	expression string
+	program    cel.Program
+	variables  map[string]string
+	location   *time.Location
+}
+
+// NewCEL compiles and type checks an expression policy
+func NewCEL(cfg config.PolicyConfig) (*CEL, error) {
+	if strings.TrimSpace(cfg.Expression) == "" {
+		return nil, fmt.Errorf("cel policy requires an expression")
+	}
+	location := time.UTC
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cel policy timezone %q: %w", cfg.Timezone, err)
+		}
+		location = loc
+	}
+
+	options := []cel.EnvOption{
+		cel.Variable("capacity", cel.IntType),
+		cel.Variable("min_capacity", cel.IntType),
+		cel.Variable("max_capacity", cel.IntType),
+		cel.Variable("hour", cel.IntType),
+		cel.Variable("minute", cel.IntType),
+		cel.Variable("weekday", cel.IntType),
+		cel.Variable("metrics", cel.MapType(cel.StringType, cel.DoubleType)),
+	}
+	options = append(options, numericFunctions()...)
+
+	variables := map[string]string{}
+	for _, input := range cfg.Inputs {
+		name := strings.ReplaceAll(input, "-", "_")
+		if !identifier.MatchString(name) {
+			continue
+		}
+		variables[name] = input
+		options = append(options, cel.Variable(name, cel.DoubleType))
+	}
+
+	env, err := cel.NewEnv(options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cel environment: %w", err)
+	}
+	ast, issues := env.Compile(cfg.Expression)
+	if issues.Err() != nil {
+		return nil, fmt.Errorf("invalid cel expression: %w", issues.Err())
+	}
+	switch ast.OutputType() {
+	case cel.IntType, cel.DoubleType, cel.DynType:
+	default:
+		return nil, fmt.Errorf("cel expression must evaluate to a number, got %s", ast.OutputType())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cel expression: %w", err)
+	}
+
+	return &CEL{
+		expression: cfg.Expression,
+		program:    program,
+		variables:  variables,
+		location:   location,
+	}, nil
+}
+
+func (p *CEL) Name() string {
+	return TypeCEL
+}
+
+func (p *CEL) Evaluate(ctx context.Context, in Input) (Recommendation, error) {
+	now := in.Now.In(p.location)
+	metrics := make(map[string]float64, len(in.Metrics))
+	activation := map[string]any{
+		"capacity":     in.CurrentCapacity,
+		"min_capacity": in.MinCapacity,
+		"max_capacity": in.MaxCapacity,
+		"hour":         now.Hour(),
+		"minute":       now.Minute(),
+		"weekday":      int(now.Weekday()),
+		"metrics":      metrics,
+	}
+	for name, value := range in.Metrics {
+		metrics[name] = value
+	}
+	for variable, source := range p.variables {
+		if value, ok := in.Metrics[source]; ok {
+			activation[variable] = value
+		}
+	}
+
+	out, _, err := p.program.ContextEval(ctx, activation)
+	if err != nil {
+		return Recommendation{}, fmt.Errorf("failed to evaluate cel expression: %w", err)
+	}
+
+	var value float64
+	switch v := out.(type) {
+	case types.Int:
+		value = float64(v)
+	case types.Double:
+		value = float64(v)
+	default:
+		return Recommendation{}, fmt.Errorf("cel expression returned %s, expected a number", out.Type())
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return Recommendation{}, fmt.Errorf("cel expression returned %v", value)
+	}
+
+	return Recommendation{
+		Policy:          p.Name(),
+		DesiredCapacity: int(math.Ceil(value - 1e-9)),
+		Reason:          fmt.Sprintf("%s evaluated to %.3g", p.expression, value),
+		Details:         map[string]float64{"result": value},
+	}, nil
+}
+
+// numericFunctions declares the math helpers available to expressions. min and
+// max accept any mix of int and double arguments.
+func numericFunctions() []cel.EnvOption {
+	unary := func(name string, fn func(float64) float64) cel.EnvOption {
+		return cel.Function(name,
+			cel.Overload(name+"_double", []*cel.Type{cel.DoubleType}, cel.DoubleType,
+				cel.UnaryBinding(func(v ref.Val) ref.Val {
+					return types.Double(fn(float64(v.(types.Double))))
+				})),
+			cel.Overload(name+"_int", []*cel.Type{cel.IntType}, cel.IntType,
+				cel.UnaryBinding(func(v ref.Val) ref.Val {
+					return v
+				})),
+		)
+	}
+	binary := func(name string, pickFirst func(a, b float64) bool) cel.EnvOption {
+		pick := func(a, b ref.Val) ref.Val {
+			if pickFirst(number(a), number(b)) {
+				return a
+			}
+			return b
+		}
+		toDouble := func(a, b ref.Val) ref.Val {
+			return types.Double(number(pick(a, b)))
+		}
+		return cel.Function(name,
+			cel.Overload(name+"_int_int", []*cel.Type{cel.IntType, cel.IntType}, cel.IntType, cel.BinaryBinding(pick)),
+			cel.Overload(name+"_double_double", []*cel.Type{cel.DoubleType, cel.DoubleType}, cel.DoubleType, cel.BinaryBinding(pick)),
+			cel.Overload(name+"_int_double", []*cel.Type{cel.IntType, cel.DoubleType}, cel.DoubleType, cel.BinaryBinding(toDouble)),
+			cel.Overload(name+"_double_int", []*cel.Type{cel.DoubleType, cel.IntType}, cel.DoubleType, cel.BinaryBinding(toDouble)),
+		)
+	}
+	return []cel.EnvOption{
+		unary("ceil", math.Ceil),
+		unary("floor", math.Floor),
+		unary("round", math.Round),
+		binary("max", func(a, b float64) bool { return a >= b }),
+		binary("min", func(a, b float64) bool { return a <= b }),
+	}
+}
+
+// number converts an int or double CEL value into a float64
+func number(v ref.Val) float64 {
+	switch n := v.(type) {
+	case types.Int:
+		return float64(n)
+	case types.Double:
+		return float64(n)
+	}
+	return math.NaN()
+}
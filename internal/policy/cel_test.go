@@ -0,0 +1,89 @@
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCEL_QueueWithBusinessHoursFloor(t *testing.T) {
+	p, err := NewCEL(config.PolicyConfig{
+		Type:       TypeCEL,
+		Expression: "max(ceil(queue / 50.0), hour >= 8 && hour < 18 ? 2 : 0)",
+		Inputs:     []string{"queue"},
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+	morning := time.Date(2024, 5, 6, 9, 30, 0, 0, time.UTC)
+	night := time.Date(2024, 5, 6, 23, 0, 0, 0, time.UTC)
+
+	rec, err := p.Evaluate(ctx, Input{Now: morning, CurrentCapacity: 1, Metrics: map[string]float64{"queue": 20}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, rec.DesiredCapacity)
+
+	rec, err = p.Evaluate(ctx, Input{Now: night, CurrentCapacity: 2, Metrics: map[string]float64{"queue": 20}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, rec.DesiredCapacity)
+
+	rec, err = p.Evaluate(ctx, Input{Now: morning, CurrentCapacity: 2, Metrics: map[string]float64{"queue": 420}})
+	require.NoError(t, err)
+	assert.Equal(t, 9, rec.DesiredCapacity)
+	assert.Equal(t, 9.0, rec.Details["result"])
+}
+
+func TestCEL_CapacityBoundsAndMetricsMap(t *testing.T) {
+	p, err := NewCEL(config.PolicyConfig{
+		Type:       TypeCEL,
+		Expression: `"queue-depth" in metrics ? min(max_capacity, capacity + 1) : min_capacity`,
+		Inputs:     []string{"queue-depth"},
+	})
+	require.NoError(t, err)
+
+	rec, err := p.Evaluate(context.Background(), Input{
+		CurrentCapacity: 3, MinCapacity: 1, MaxCapacity: 3,
+		Metrics: map[string]float64{"queue-depth": 5},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, rec.DesiredCapacity)
+
+	rec, err = p.Evaluate(context.Background(), Input{CurrentCapacity: 3, MinCapacity: 1, MaxCapacity: 3})
+	require.NoError(t, err)
+	assert.Equal(t, 1, rec.DesiredCapacity)
+}
+
+func TestCEL_Timezone(t *testing.T) {
+	p, err := NewCEL(config.PolicyConfig{Type: TypeCEL, Expression: "hour", Timezone: "America/New_York"})
+	require.NoError(t, err)
+
+	rec, err := p.Evaluate(context.Background(), Input{Now: time.Date(2024, 1, 15, 14, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	assert.Equal(t, 9, rec.DesiredCapacity)
+}
+
+func TestCEL_MissingMetricFails(t *testing.T) {
+	p, err := NewCEL(config.PolicyConfig{Type: TypeCEL, Expression: "ceil(queue_depth / 10.0)", Inputs: []string{"queue-depth"}})
+	require.NoError(t, err)
+
+	_, err = p.Evaluate(context.Background(), Input{CurrentCapacity: 1, Metrics: map[string]float64{}})
+	assert.Error(t, err)
+}
+
+func TestNewCEL_InvalidExpressions(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"max(",
+		"unknown_metric * 2",
+		"queue > 10",
+		"queue / 50",
+	} {
+		_, err := NewCEL(config.PolicyConfig{Type: TypeCEL, Expression: expression, Inputs: []string{"queue"}})
+		assert.Error(t, err, expression)
+	}
+
+	_, err := NewCEL(config.PolicyConfig{Type: TypeCEL, Expression: "1", Timezone: "Mars/Olympus"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,148 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+)
+
+// TypeComposite is the name reported by a policy composed of several others
+const TypeComposite = "composite"
+
+// Strategies for combining the recommendations of composed policies
+const (
+	StrategyMax      = "max"
+	StrategyMin      = "min"
+	StrategyWeighted = "weighted"
+	StrategyPriority = "priority"
+)
+
+// Composite evaluates several named policies and combines their desired
+// capacities with a strategy. Policies that fail are left out of the
+// combination as long as at least one succeeds.
+type Composite struct {
+	strategy string
+	members  []member
+}
+
+type member struct {
+	name   string
+	weight float64
+	policy Policy
+}
+
+// NewComposite creates a composite policy from the configured members, which
+// are evaluated in the order given. The order decides the priority strategy.
+func NewComposite(strategy string, configs []config.PolicyConfig) (*Composite, error) {
+	switch strategy {
+	case StrategyMax, StrategyMin, StrategyWeighted, StrategyPriority:
+	default:
+		return nil, fmt.Errorf("unsupported policy strategy %q", strategy)
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("composite policy requires at least one policy")
+	}
+
+	composite := &Composite{strategy: strategy}
+	for _, cfg := range configs {
+		p, err := New(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %w", cfg.Name, err)
+		}
+		if p == nil {
+			return nil, fmt.Errorf("policy %s has no type", cfg.Name)
+		}
+		weight := cfg.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		composite.members = append(composite.members, member{name: cfg.Name, weight: weight, policy: p})
+	}
+	return composite, nil
+}
+
+func (p *Composite) Name() string {
+	return TypeComposite
+}
+
+// Evaluate reports the policy whose recommendation was chosen. The weighted
+// strategy blends all recommendations, so it reports itself as the winner.
+func (p *Composite) Evaluate(ctx context.Context, in Input) (Recommendation, error) {
+	type result struct {
+		name           string
+		weight         float64
+		recommendation Recommendation
+	}
+
+	var results []result
+	var failures []string
+	details := map[string]float64{}
+	for _, m := range p.members {
+		rec, err := m.policy.Evaluate(ctx, in)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", m.name, err))
+			continue
+		}
+		results = append(results, result{name: m.name, weight: m.weight, recommendation: rec})
+		details[m.name] = float64(rec.DesiredCapacity)
+		if p.strategy == StrategyPriority {
+			break
+		}
+	}
+	if len(results) == 0 {
+		return Recommendation{}, fmt.Errorf("all policies failed: %s", strings.Join(failures, "; "))
+	}
+
+	winner := results[0]
+	switch p.strategy {
+	case StrategyMax:
+		for _, r := range results[1:] {
+			if r.recommendation.DesiredCapacity > winner.recommendation.DesiredCapacity {
+				winner = r
+			}
+		}
+	case StrategyMin:
+		for _, r := range results[1:] {
+			if r.recommendation.DesiredCapacity < winner.recommendation.DesiredCapacity {
+				winner = r
+			}
+		}
+	case StrategyWeighted:
+		var sum, weights float64
+		var wake bool
+		for _, r := range results {
+			sum += r.weight * float64(r.recommendation.DesiredCapacity)
+			weights += r.weight
+			wake = wake || r.recommendation.Wake
+		}
+		winner = result{name: StrategyWeighted, recommendation: Recommendation{
+			DesiredCapacity: int(math.Ceil(sum/weights - 1e-9)),
+			Reason:          "weighted average of the recommended capacities",
+			Wake:            wake,
+		}}
+	}
+
+	var summary []string
+	for _, r := range results {
+		summary = append(summary, fmt.Sprintf("%s=%d", r.name, r.recommendation.DesiredCapacity))
+	}
+	reason := fmt.Sprintf("%s won by %s of [%s]: %s",
+		winner.name, p.strategy, strings.Join(summary, ", "), winner.recommendation.Reason)
+	if len(failures) > 0 {
+		reason += fmt.Sprintf("; skipped %s", strings.Join(failures, "; "))
+	}
+
+	for key, value := range winner.recommendation.Details {
+		details[winner.name+"."+key] = value
+	}
+	return Recommendation{
+		Policy:          winner.name,
+		DesiredCapacity: winner.recommendation.DesiredCapacity,
+		Reason:          reason,
+		Wake:            winner.recommendation.Wake,
+		Details:         details,
+	}, nil
+}
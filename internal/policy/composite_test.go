@@ -0,0 +1,71 @@
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compositeConfigs() []config.PolicyConfig {
+	return []config.PolicyConfig{
+		{Name: "cpu", Type: TypeTargetTracking, Metric: "cpu", Target: 0.5, Weight: 1},
+		{Name: "queue", Type: TypeConcurrency, Metric: "queue", Target: 50, Weight: 3},
+	}
+}
+
+func evaluateComposite(t *testing.T, strategy string, metrics map[string]float64) Recommendation {
+	p, err := NewComposite(strategy, compositeConfigs())
+	require.NoError(t, err)
+	rec, err := p.Evaluate(context.Background(), Input{CurrentCapacity: 2, Metrics: metrics})
+	require.NoError(t, err)
+	return rec
+}
+
+func TestComposite_Strategies(t *testing.T) {
+	// cpu recommends 4 replicas and queue recommends 2
+	metrics := map[string]float64{"cpu": 1.0, "queue": 100}
+
+	rec := evaluateComposite(t, StrategyMax, metrics)
+	assert.Equal(t, 4, rec.DesiredCapacity)
+	assert.Equal(t, "cpu", rec.Policy)
+	assert.Contains(t, rec.Reason, "cpu won by max of [cpu=4, queue=2]")
+	assert.Equal(t, map[string]float64{"cpu": 4, "queue": 2}, rec.Details)
+
+	rec = evaluateComposite(t, StrategyMin, metrics)
+	assert.Equal(t, 2, rec.DesiredCapacity)
+	assert.Equal(t, "queue", rec.Policy)
+
+	rec = evaluateComposite(t, StrategyWeighted, metrics)
+	assert.Equal(t, 3, rec.DesiredCapacity) // (4*1 + 2*3) / 4 rounded up
+	assert.Equal(t, StrategyWeighted, rec.Policy)
+
+	rec = evaluateComposite(t, StrategyPriority, metrics)
+	assert.Equal(t, 4, rec.DesiredCapacity)
+	assert.Equal(t, "cpu", rec.Policy)
+}
+
+func TestComposite_SkipsFailingPolicies(t *testing.T) {
+	rec := evaluateComposite(t, StrategyPriority, map[string]float64{"queue": 120})
+	assert.Equal(t, 3, rec.DesiredCapacity)
+	assert.Equal(t, "queue", rec.Policy)
+	assert.Contains(t, rec.Reason, "skipped cpu: metric cpu is not available")
+
+	p, err := NewComposite(StrategyMax, compositeConfigs())
+	require.NoError(t, err)
+	_, err = p.Evaluate(context.Background(), Input{CurrentCapacity: 2, Metrics: map[string]float64{}})
+	assert.Error(t, err)
+}
+
+func TestNewComposite_InvalidConfig(t *testing.T) {
+	_, err := NewComposite("average", compositeConfigs())
+	assert.Error(t, err)
+
+	_, err = NewComposite(StrategyMax, nil)
+	assert.Error(t, err)
+
+	_, err = NewComposite(StrategyMax, []config.PolicyConfig{{Name: "cpu", Type: TypeTargetTracking}})
+	assert.Error(t, err)
+}
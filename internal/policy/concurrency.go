@@ -0,0 +1,48 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+)
+
+// Concurrency sizes capacity so that each replica handles at most the target
+// number of concurrent requests. Unlike target tracking the metric is a total
+// across all replicas, so it remains meaningful while scaled to zero.
+type Concurrency struct {
+	metric string
+	target float64
+}
+
+// NewConcurrency creates a concurrency policy. The metric defaults to the
+// concurrency reported by the built-in reverse proxy.
+func NewConcurrency(cfg config.PolicyConfig) (*Concurrency, error) {
+	if cfg.Target <= 0 {
+		return nil, fmt.Errorf("concurrency policy requires a positive per-replica target, got %v", cfg.Target)
+	}
+	metric := cfg.Metric
+	if metric == "" {
+		metric = "concurrency"
+	}
+	return &Concurrency{metric: metric, target: cfg.Target}, nil
+}
+
+func (p *Concurrency) Name() string {
+	return TypeConcurrency
+}
+
+func (p *Concurrency) Evaluate(ctx context.Context, in Input) (Recommendation, error) {
+	value, err := metric(in, p.metric)
+	if err != nil {
+		return Recommendation{}, err
+	}
+
+	desired := int(math.Ceil(value/p.target - 1e-9))
+	return Recommendation{
+		Policy:          p.Name(),
+		DesiredCapacity: desired,
+		Reason:          fmt.Sprintf("%s is %.3g with a per-replica target of %.3g", p.metric, value, p.target),
+	}, nil
+}
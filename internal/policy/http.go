@@ -0,0 +1,167 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
+)
+
+// TypeHTTP delegates the desired capacity to an external policy server
+const TypeHTTP = "http"
+
+// ServerRequest is the snapshot POSTed to an external policy server
+type ServerRequest struct {
+	Time          time.Time                               `json:"time"`
+	Capacity      omnistrate_api.ResourceInstanceCapacity `json:"capacity"`
+	Cooldown      ServerCooldown                          `json:"cooldown"`
+	Bounds        ServerBounds                            `json:"bounds"`
+	Metrics       map[string]float64                      `json:"metrics"`
+	RecentMetrics []MetricSample                          `json:"recentMetrics"`
+}
+
+// ServerCooldown describes the cooldown state of the autoscaler
+type ServerCooldown struct {
+	Active           bool       `json:"active"`
+	RemainingSeconds float64    `json:"remainingSeconds"`
+	LastActionTime   *time.Time `json:"lastActionTime,omitempty"`
+}
+
+// ServerBounds are the configured capacity bounds; a maximum of 0 is unbounded
+type ServerBounds struct {
+	MinCapacity int `json:"minCapacity"`
+	MaxCapacity int `json:"maxCapacity"`
+}
+
+// ServerResponse is the recommendation returned by an external policy server.
+// The controller still applies bounds and cooldown to it.
+type ServerResponse struct {
+	DesiredCapacity *int   `json:"desiredCapacity"`
+	Reason          string `json:"reason"`
+}
+
+// HTTP asks an external policy server for the desired capacity on every
+// evaluation. When the server cannot be reached or returns an invalid answer
+// the fallback policy is evaluated instead, if one is configured.
+type HTTP struct {
+	url        string
+	httpClient *http.Client
+	fallback   Policy
+}
+
+// NewHTTP creates an external policy server client
+func NewHTTP(cfg config.PolicyConfig) (*HTTP, error) {
+	target, err := url.Parse(cfg.URL)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("http policy requires an absolute URL, got %q", cfg.URL)
+	}
+	if cfg.Timeout <= 0 {
+		return nil, fmt.Errorf("http policy requires a positive timeout")
+	}
+
+	p := &HTTP{
+		url:        cfg.URL,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+	}
+	if cfg.Fallback != nil {
+		fallback, err := New(*cfg.Fallback)
+		if err != nil {
+			return nil, fmt.Errorf("http policy fallback: %w", err)
+		}
+		p.fallback = fallback
+	}
+	return p, nil
+}
+
+func (p *HTTP) Name() string {
+	return TypeHTTP
+}
+
+func (p *HTTP) Evaluate(ctx context.Context, in Input) (Recommendation, error) {
+	response, err := p.ask(ctx, in)
+	if err == nil {
+		return Recommendation{
+			Policy:          p.Name(),
+			DesiredCapacity: *response.DesiredCapacity,
+			Reason:          response.Reason,
+		}, nil
+	}
+
+	if p.fallback == nil {
+		return Recommendation{}, err
+	}
+	logger.Warn().Err(err).Str("url", p.url).Str("fallback", p.fallback.Name()).Msg("Policy server unavailable, using fallback policy")
+	rec, fallbackErr := p.fallback.Evaluate(ctx, in)
+	if fallbackErr != nil {
+		return Recommendation{}, fmt.Errorf("%v; fallback policy failed: %w", err, fallbackErr)
+	}
+	rec.Reason = fmt.Sprintf("policy server unavailable (%v), fallback %s: %s", err, rec.Policy, rec.Reason)
+	return rec, nil
+}
+
+// ask POSTs the snapshot to the policy server and validates its answer
+func (p *HTTP) ask(ctx context.Context, in Input) (*ServerResponse, error) {
+	request := ServerRequest{
+		Time:     in.Now,
+		Capacity: in.Capacity,
+		Cooldown: ServerCooldown{
+			Active:           in.CooldownRemaining > 0,
+			RemainingSeconds: in.CooldownRemaining.Seconds(),
+		},
+		Bounds:        ServerBounds{MinCapacity: in.MinCapacity, MaxCapacity: in.MaxCapacity},
+		Metrics:       in.Metrics,
+		RecentMetrics: in.RecentMetrics,
+	}
+	if !in.LastActionTime.IsZero() {
+		lastAction := in.LastActionTime
+		request.Cooldown.LastActionTime = &lastAction
+	}
+	if request.Metrics == nil {
+		request.Metrics = map[string]float64{}
+	}
+	if request.RecentMetrics == nil {
+		request.RecentMetrics = []MetricSample{}
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode policy request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create policy request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("policy server request failed: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("policy server returned status %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+	}
+
+	var response ServerResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode policy response: %w", err)
+	}
+	if response.DesiredCapacity == nil {
+		return nil, fmt.Errorf("policy response is missing desiredCapacity")
+	}
+	if *response.DesiredCapacity < 0 {
+		return nil, fmt.Errorf("policy response has negative desiredCapacity %d", *response.DesiredCapacity)
+	}
+	return &response, nil
+}
@@ -0,0 +1,118 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func httpInput() Input {
+	now := time.Unix(1700000000, 0).UTC()
+	return Input{
+		Now:             now,
+		CurrentCapacity: 2,
+		MinCapacity:     1,
+		MaxCapacity:     5,
+		Metrics:         map[string]float64{"queue": 120},
+		Capacity: omnistrate_api.ResourceInstanceCapacity{
+			InstanceID:      "instance-1",
+			Status:          omnistrate_api.ACTIVE,
+			CurrentCapacity: 2,
+		},
+		LastActionTime:    now.Add(-time.Minute),
+		CooldownRemaining: 4 * time.Minute,
+		RecentMetrics:     []MetricSample{{Time: now.Add(-30 * time.Second), Values: map[string]float64{"queue": 80}}},
+	}
+}
+
+func TestHTTP_SendsSnapshotAndUsesRecommendation(t *testing.T) {
+	var received ServerRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		_, _ = w.Write([]byte(`{"desiredCapacity": 4, "reason": "forecast predicts a spike"}`))
+	}))
+	defer server.Close()
+
+	p, err := NewHTTP(config.PolicyConfig{Type: TypeHTTP, URL: server.URL, Timeout: time.Second})
+	require.NoError(t, err)
+
+	rec, err := p.Evaluate(context.Background(), httpInput())
+	require.NoError(t, err)
+	assert.Equal(t, 4, rec.DesiredCapacity)
+	assert.Equal(t, "forecast predicts a spike", rec.Reason)
+	assert.Equal(t, TypeHTTP, rec.Policy)
+
+	assert.Equal(t, "instance-1", received.Capacity.InstanceID)
+	assert.Equal(t, omnistrate_api.ACTIVE, received.Capacity.Status)
+	assert.True(t, received.Cooldown.Active)
+	assert.Equal(t, 240.0, received.Cooldown.RemainingSeconds)
+	assert.Equal(t, ServerBounds{MinCapacity: 1, MaxCapacity: 5}, received.Bounds)
+	assert.Equal(t, map[string]float64{"queue": 120}, received.Metrics)
+	require.Len(t, received.RecentMetrics, 1)
+	assert.Equal(t, 80.0, received.RecentMetrics[0].Values["queue"])
+}
+
+func TestHTTP_FallsBackWhenServerFails(t *testing.T) {
+	responses := []func(w http.ResponseWriter){
+		func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) },
+		func(w http.ResponseWriter) { _, _ = w.Write([]byte(`{"reason": "no capacity given"}`)) },
+		func(w http.ResponseWriter) { _, _ = w.Write([]byte(`{"desiredCapacity": -1}`)) },
+		func(w http.ResponseWriter) { time.Sleep(200 * time.Millisecond) },
+	}
+	for _, respond := range responses {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			respond(w)
+		}))
+
+		p, err := NewHTTP(config.PolicyConfig{
+			Type:     TypeHTTP,
+			URL:      server.URL,
+			Timeout:  50 * time.Millisecond,
+			Fallback: &config.PolicyConfig{Type: TypeConcurrency, Metric: "queue", Target: 50},
+		})
+		require.NoError(t, err)
+
+		rec, err := p.Evaluate(context.Background(), httpInput())
+		require.NoError(t, err)
+		assert.Equal(t, 3, rec.DesiredCapacity)
+		assert.Equal(t, TypeConcurrency, rec.Policy)
+		assert.Contains(t, rec.Reason, "policy server unavailable")
+		server.Close()
+	}
+}
+
+func TestHTTP_FailsWithoutFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	p, err := NewHTTP(config.PolicyConfig{Type: TypeHTTP, URL: server.URL, Timeout: time.Second})
+	require.NoError(t, err)
+
+	_, err = p.Evaluate(context.Background(), httpInput())
+	assert.Error(t, err)
+}
+
+func TestNewHTTP_InvalidConfig(t *testing.T) {
+	_, err := NewHTTP(config.PolicyConfig{Type: TypeHTTP, URL: "policy-server:8080", Timeout: time.Second})
+	assert.Error(t, err)
+
+	_, err = NewHTTP(config.PolicyConfig{
+		Type:     TypeHTTP,
+		URL:      "http://policy-server:8080",
+		Timeout:  time.Second,
+		Fallback: &config.PolicyConfig{Type: TypeTargetTracking},
+	})
+	assert.Error(t, err)
+}
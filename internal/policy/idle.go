@@ -0,0 +1,106 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+)
+
+// TypeIdle is the name reported by the scale-to-zero policy
+const TypeIdle = "idle"
+
+// Idle removes all capacity once a metric has stayed at or below a threshold
+// for the configured period, and wakes the resource when it rises again. In
+// between it defers to the wrapped policy, if any.
+type Idle struct {
+	inner        Policy
+	metric       string
+	threshold    float64
+	period       time.Duration
+	wakeCapacity int
+
+	mu         sync.Mutex
+	lastActive time.Time
+}
+
+// NewIdle wraps a policy with idle detection. The inner policy may be nil.
+func NewIdle(inner Policy, cfg config.IdleConfig) (*Idle, error) {
+	if !cfg.Enabled() {
+		return nil, fmt.Errorf("idle policy requires a positive period")
+	}
+	if cfg.Metric == "" {
+		return nil, fmt.Errorf("idle policy requires a metric")
+	}
+	return &Idle{
+		inner:        inner,
+		metric:       cfg.Metric,
+		threshold:    cfg.Threshold,
+		period:       cfg.Period,
+		wakeCapacity: cfg.WakeCapacity,
+	}, nil
+}
+
+func (p *Idle) Name() string {
+	if p.inner != nil {
+		return p.inner.Name()
+	}
+	return TypeIdle
+}
+
+func (p *Idle) Evaluate(ctx context.Context, in Input) (Recommendation, error) {
+	value, err := metric(in, p.metric)
+	if err != nil {
+		return Recommendation{}, err
+	}
+	busy := value > p.threshold
+
+	p.mu.Lock()
+	if busy || p.lastActive.IsZero() {
+		p.lastActive = in.Now
+	}
+	quiet := in.Now.Sub(p.lastActive)
+	p.mu.Unlock()
+
+	if in.CurrentCapacity == 0 {
+		if !busy {
+			return Recommendation{
+				Policy:          TypeIdle,
+				DesiredCapacity: 0,
+				Reason:          fmt.Sprintf("%s is %.3g, staying scaled to zero", p.metric, value),
+			}, nil
+		}
+		desired := p.wakeCapacity
+		if p.inner != nil {
+			rec, err := p.inner.Evaluate(ctx, in)
+			if err == nil && rec.DesiredCapacity > desired {
+				desired = rec.DesiredCapacity
+			}
+		}
+		return Recommendation{
+			Policy:          TypeIdle,
+			DesiredCapacity: desired,
+			Reason:          fmt.Sprintf("%s is %.3g above idle threshold %.3g, waking from zero", p.metric, value, p.threshold),
+			Wake:            true,
+		}, nil
+	}
+
+	if !busy && quiet >= p.period {
+		return Recommendation{
+			Policy:          TypeIdle,
+			DesiredCapacity: 0,
+			Reason:          fmt.Sprintf("%s has been at or below %.3g for %s, scaling to zero", p.metric, p.threshold, quiet.Round(time.Second)),
+		}, nil
+	}
+
+	if p.inner == nil {
+		return Recommendation{
+			Policy:          TypeIdle,
+			DesiredCapacity: in.CurrentCapacity,
+			Reason:          fmt.Sprintf("%s is %.3g, not idle", p.metric, value),
+		}, nil
+	}
+	return p.inner.Evaluate(ctx, in)
+}
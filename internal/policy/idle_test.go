@@ -0,0 +1,83 @@
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdle_ScalesToZeroAfterQuietPeriod(t *testing.T) {
+	p, err := NewIdle(nil, config.IdleConfig{Metric: "rps", Period: 10 * time.Minute, WakeCapacity: 1})
+	require.NoError(t, err)
+	ctx := context.Background()
+	start := time.Unix(1700000000, 0)
+
+	rec, err := p.Evaluate(ctx, Input{Now: start, CurrentCapacity: 2, Metrics: map[string]float64{"rps": 0}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, rec.DesiredCapacity)
+
+	rec, err = p.Evaluate(ctx, Input{Now: start.Add(5 * time.Minute), CurrentCapacity: 2, Metrics: map[string]float64{"rps": 0}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, rec.DesiredCapacity)
+
+	rec, err = p.Evaluate(ctx, Input{Now: start.Add(10 * time.Minute), CurrentCapacity: 2, Metrics: map[string]float64{"rps": 0}})
+	require.NoError(t, err)
+	assert.Equal(t, 0, rec.DesiredCapacity)
+	assert.Equal(t, TypeIdle, rec.Policy)
+	assert.False(t, rec.Wake)
+}
+
+func TestIdle_ActivityResetsQuietPeriod(t *testing.T) {
+	p, err := NewIdle(nil, config.IdleConfig{Metric: "rps", Threshold: 0.5, Period: 10 * time.Minute, WakeCapacity: 1})
+	require.NoError(t, err)
+	ctx := context.Background()
+	start := time.Unix(1700000000, 0)
+
+	_, err = p.Evaluate(ctx, Input{Now: start, CurrentCapacity: 1, Metrics: map[string]float64{"rps": 0}})
+	require.NoError(t, err)
+	_, err = p.Evaluate(ctx, Input{Now: start.Add(8 * time.Minute), CurrentCapacity: 1, Metrics: map[string]float64{"rps": 3}})
+	require.NoError(t, err)
+
+	rec, err := p.Evaluate(ctx, Input{Now: start.Add(12 * time.Minute), CurrentCapacity: 1, Metrics: map[string]float64{"rps": 0.2}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, rec.DesiredCapacity)
+}
+
+func TestIdle_WakesFromZero(t *testing.T) {
+	inner, err := NewTargetTracking(config.PolicyConfig{Metric: "rps", Target: 10})
+	require.NoError(t, err)
+	p, err := NewIdle(inner, config.IdleConfig{Metric: "rps", Period: time.Minute, WakeCapacity: 2})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	rec, err := p.Evaluate(ctx, Input{Now: time.Now(), CurrentCapacity: 0, Metrics: map[string]float64{"rps": 0}})
+	require.NoError(t, err)
+	assert.Equal(t, 0, rec.DesiredCapacity)
+	assert.False(t, rec.Wake)
+
+	rec, err = p.Evaluate(ctx, Input{Now: time.Now(), CurrentCapacity: 0, Metrics: map[string]float64{"rps": 4}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, rec.DesiredCapacity)
+	assert.True(t, rec.Wake)
+}
+
+func TestIdle_DefersToInnerPolicy(t *testing.T) {
+	inner, err := NewTargetTracking(config.PolicyConfig{Metric: "cpu", Target: 0.5})
+	require.NoError(t, err)
+	p, err := NewIdle(inner, config.IdleConfig{Metric: "rps", Period: time.Minute, WakeCapacity: 1})
+	require.NoError(t, err)
+
+	rec, err := p.Evaluate(context.Background(), Input{
+		Now:             time.Now(),
+		CurrentCapacity: 2,
+		Metrics:         map[string]float64{"rps": 12, "cpu": 1.0},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 4, rec.DesiredCapacity)
+	assert.Equal(t, TypeTargetTracking, rec.Policy)
+	assert.Equal(t, TypeTargetTracking, p.Name())
+}
@@ -0,0 +1,104 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
+)
+
+// Policy types accepted in configuration
+const (
+	TypeTargetTracking = "target-tracking"
+	TypeConcurrency    = "concurrency"
+)
+
+// Input is the snapshot a policy evaluates. Capacity is the full observation
+// CurrentCapacity was taken from, and RecentMetrics holds the readings of
+// previous evaluations, oldest first.
+type Input struct {
+	Now               time.Time
+	CurrentCapacity   int
+	MinCapacity       int
+	MaxCapacity       int
+	Metrics           map[string]float64
+	Capacity          omnistrate_api.ResourceInstanceCapacity
+	LastActionTime    time.Time
+	CooldownRemaining time.Duration
+	RecentMetrics     []MetricSample
+}
+
+// MetricSample is the set of readings taken by one evaluation
+type MetricSample struct {
+	Time   time.Time          `json:"time"`
+	Values map[string]float64 `json:"values"`
+}
+
+// Recommendation is the outcome of a policy evaluation. Wake marks a scale
+// from zero that should not wait for the cooldown period. Details carries the
+// intermediate values the policy based its reasoning on.
+type Recommendation struct {
+	Policy          string
+	DesiredCapacity int
+	Reason          string
+	Wake            bool
+	Details         map[string]float64
+}
+
+// Policy turns an input snapshot into a desired capacity
+type Policy interface {
+	Name() string
+	Evaluate(ctx context.Context, in Input) (Recommendation, error)
+}
+
+// New builds the policy described by the configuration. It returns nil when no
+// policy type is configured, in which case scaling is driven by API requests only.
+func New(cfg config.PolicyConfig) (Policy, error) {
+	switch cfg.Type {
+	case "":
+		return nil, nil
+	case TypeTargetTracking:
+		return NewTargetTracking(cfg)
+	case TypeConcurrency:
+		return NewConcurrency(cfg)
+	case TypeSLO:
+		return NewSLO(cfg)
+	case TypeCEL:
+		return NewCEL(cfg)
+	case TypeHTTP:
+		return NewHTTP(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported policy type %q", cfg.Type)
+	}
+}
+
+// FromConfig builds the policy the autoscaler evaluates, composing the named
+// policies when several are configured and wrapping the result with idle
+// detection when scale-to-zero is enabled
+func FromConfig(cfg *config.Config) (Policy, error) {
+	var p Policy
+	var err error
+	if len(cfg.Policies) > 0 {
+		p, err = NewComposite(cfg.PolicyStrategy, cfg.Policies)
+	} else {
+		p, err = New(cfg.Policy)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Idle.Enabled() {
+		return NewIdle(p, cfg.Idle)
+	}
+	return p, nil
+}
+
+// metric returns the named reading or an error when the source did not report
+func metric(in Input, name string) (float64, error) {
+	value, ok := in.Metrics[name]
+	if !ok {
+		return 0, fmt.Errorf("metric %s is not available", name)
+	}
+	return value, nil
+}
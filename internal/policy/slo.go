@@ -0,0 +1,135 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+)
+
+// TypeSLO scales on the error budget burn rate of a service level objective
+const TypeSLO = "slo"
+
+// SLO adds capacity while the error budget is burning faster than allowed over
+// both a short and a long window, and removes it only once the budget has been
+// consumed slowly for at least the long window. The metric is the fraction of
+// bad events, such as a histogram source reporting requests slower than the
+// latency target.
+type SLO struct {
+	metric            string
+	budget            float64
+	shortWindow       time.Duration
+	longWindow        time.Duration
+	shortBurnRate     float64
+	longBurnRate      float64
+	scaleDownBurnRate float64
+	step              int
+
+	mu      sync.Mutex
+	samples []sloSample
+	since   time.Time
+}
+
+type sloSample struct {
+	time     time.Time
+	badRatio float64
+}
+
+// NewSLO creates an SLO burn rate policy
+func NewSLO(cfg config.PolicyConfig) (*SLO, error) {
+	if cfg.Metric == "" {
+		return nil, fmt.Errorf("slo policy requires a metric")
+	}
+	slo := cfg.SLO
+	if slo.Objective <= 0 || slo.Objective >= 1 {
+		return nil, fmt.Errorf("slo policy requires an objective between 0 and 1, got %v", slo.Objective)
+	}
+	if slo.ShortWindow <= 0 || slo.LongWindow < slo.ShortWindow {
+		return nil, fmt.Errorf("slo policy requires a positive short window no longer than the long window")
+	}
+	if slo.ShortBurnRate <= slo.ScaleDownBurnRate || slo.LongBurnRate <= slo.ScaleDownBurnRate {
+		return nil, fmt.Errorf("slo policy requires scale up burn rates above the scale down burn rate %v", slo.ScaleDownBurnRate)
+	}
+	return &SLO{
+		metric:            cfg.Metric,
+		budget:            1 - slo.Objective,
+		shortWindow:       slo.ShortWindow,
+		longWindow:        slo.LongWindow,
+		shortBurnRate:     slo.ShortBurnRate,
+		longBurnRate:      slo.LongBurnRate,
+		scaleDownBurnRate: slo.ScaleDownBurnRate,
+		step:              max(slo.Step, 1),
+	}, nil
+}
+
+func (p *SLO) Name() string {
+	return TypeSLO
+}
+
+func (p *SLO) Evaluate(ctx context.Context, in Input) (Recommendation, error) {
+	badRatio, err := metric(in, p.metric)
+	if err != nil {
+		return Recommendation{}, err
+	}
+
+	shortBurn, longBurn, observed := p.record(in.Now, badRatio)
+	rec := Recommendation{
+		Policy:          p.Name(),
+		DesiredCapacity: in.CurrentCapacity,
+		Details: map[string]float64{
+			"badRatio":      badRatio,
+			"shortBurnRate": shortBurn,
+			"longBurnRate":  longBurn,
+		},
+	}
+	burning := fmt.Sprintf("error budget burning at %.2fx over %s and %.2fx over %s",
+		shortBurn, p.shortWindow, longBurn, p.longWindow)
+
+	switch {
+	case in.CurrentCapacity == 0:
+		rec.Reason = fmt.Sprintf("%s cannot be measured without capacity", p.metric)
+	case shortBurn >= p.shortBurnRate && longBurn >= p.longBurnRate:
+		rec.DesiredCapacity = in.CurrentCapacity + p.step
+		rec.Reason = fmt.Sprintf("%s, above the %.2gx and %.2gx limits", burning, p.shortBurnRate, p.longBurnRate)
+	case shortBurn <= p.scaleDownBurnRate && longBurn <= p.scaleDownBurnRate && observed >= p.longWindow:
+		rec.DesiredCapacity = max(in.CurrentCapacity-p.step, 0)
+		rec.Reason = fmt.Sprintf("%s, within the %.2gx scale down headroom", burning, p.scaleDownBurnRate)
+	default:
+		rec.Reason = burning
+	}
+	return rec, nil
+}
+
+// record adds a reading to the history and returns the burn rates over both
+// windows along with how long readings have been collected
+func (p *SLO) record(now time.Time, badRatio float64) (shortBurn, longBurn float64, observed time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.since.IsZero() {
+		p.since = now
+	}
+	p.samples = append(p.samples, sloSample{time: now, badRatio: badRatio})
+	kept := p.samples[:0]
+	for _, sample := range p.samples {
+		if now.Sub(sample.time) < p.longWindow {
+			kept = append(kept, sample)
+		}
+	}
+	p.samples = kept
+
+	var shortSum, longSum float64
+	var shortCount int
+	for _, sample := range p.samples {
+		longSum += sample.badRatio
+		if now.Sub(sample.time) < p.shortWindow {
+			shortSum += sample.badRatio
+			shortCount++
+		}
+	}
+	shortBurn = shortSum / float64(shortCount) / p.budget
+	longBurn = longSum / float64(len(p.samples)) / p.budget
+	return shortBurn, longBurn, now.Sub(p.since)
+}
@@ -0,0 +1,98 @@
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSLO(t *testing.T) *SLO {
+	p, err := NewSLO(config.PolicyConfig{
+		Type:   TypeSLO,
+		Metric: "slow-requests",
+		SLO: config.SLOConfig{
+			Objective:         0.95,
+			ShortWindow:       5 * time.Minute,
+			LongWindow:        time.Hour,
+			ShortBurnRate:     2,
+			LongBurnRate:      1,
+			ScaleDownBurnRate: 0.25,
+			Step:              1,
+		},
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func evaluateSLO(t *testing.T, p *SLO, now time.Time, capacity int, badRatio float64) Recommendation {
+	rec, err := p.Evaluate(context.Background(), Input{
+		Now:             now,
+		CurrentCapacity: capacity,
+		Metrics:         map[string]float64{"slow-requests": badRatio},
+	})
+	require.NoError(t, err)
+	return rec
+}
+
+func TestSLO_ScalesUpWhenBothWindowsBurn(t *testing.T) {
+	p := newTestSLO(t)
+	start := time.Unix(1700000000, 0)
+
+	// 20% of requests miss the latency target, four times the 5% budget
+	rec := evaluateSLO(t, p, start, 2, 0.2)
+	assert.Equal(t, 3, rec.DesiredCapacity)
+	assert.InDelta(t, 4.0, rec.Details["shortBurnRate"], 0.0001)
+	assert.InDelta(t, 4.0, rec.Details["longBurnRate"], 0.0001)
+	assert.Contains(t, rec.Reason, "error budget burning at 4.00x")
+}
+
+func TestSLO_ShortSpikeDoesNotScaleUp(t *testing.T) {
+	p := newTestSLO(t)
+	start := time.Unix(1700000000, 0)
+
+	// Forty minutes within budget followed by a brief spike
+	for i := 0; i < 40; i++ {
+		evaluateSLO(t, p, start.Add(time.Duration(i)*time.Minute), 2, 0.02)
+	}
+	rec := evaluateSLO(t, p, start.Add(40*time.Minute), 2, 0.5)
+
+	assert.Greater(t, rec.Details["shortBurnRate"], 2.0)
+	assert.Less(t, rec.Details["longBurnRate"], 1.0)
+	assert.Equal(t, 2, rec.DesiredCapacity)
+}
+
+func TestSLO_ScalesDownOnlyWithHeadroomOverLongWindow(t *testing.T) {
+	p := newTestSLO(t)
+	start := time.Unix(1700000000, 0)
+
+	rec := evaluateSLO(t, p, start, 3, 0)
+	assert.Equal(t, 3, rec.DesiredCapacity)
+
+	rec = evaluateSLO(t, p, start.Add(30*time.Minute), 3, 0.001)
+	assert.Equal(t, 3, rec.DesiredCapacity)
+
+	rec = evaluateSLO(t, p, start.Add(time.Hour), 3, 0.001)
+	assert.Equal(t, 2, rec.DesiredCapacity)
+	assert.Contains(t, rec.Reason, "scale down headroom")
+}
+
+func TestSLO_HoldsWithoutCapacity(t *testing.T) {
+	p := newTestSLO(t)
+
+	rec := evaluateSLO(t, p, time.Unix(1700000000, 0), 0, 1)
+	assert.Equal(t, 0, rec.DesiredCapacity)
+}
+
+func TestNewSLO_InvalidConfig(t *testing.T) {
+	_, err := NewSLO(config.PolicyConfig{Type: TypeSLO})
+	assert.Error(t, err)
+
+	_, err = NewSLO(config.PolicyConfig{Type: TypeSLO, Metric: "slow-requests", SLO: config.SLOConfig{
+		Objective: 0.99, ShortWindow: time.Hour, LongWindow: time.Minute, ShortBurnRate: 2, LongBurnRate: 1,
+	}})
+	assert.Error(t, err)
+}
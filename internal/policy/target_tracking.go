@@ -0,0 +1,55 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+)
+
+// TargetTracking keeps a per-replica utilization metric close to a target value
+// by scaling capacity proportionally to the observed utilization
+type TargetTracking struct {
+	metric string
+	target float64
+}
+
+// NewTargetTracking creates a target tracking policy
+func NewTargetTracking(cfg config.PolicyConfig) (*TargetTracking, error) {
+	if cfg.Metric == "" {
+		return nil, fmt.Errorf("target-tracking policy requires a metric")
+	}
+	if cfg.Target <= 0 {
+		return nil, fmt.Errorf("target-tracking policy requires a positive target, got %v", cfg.Target)
+	}
+	return &TargetTracking{metric: cfg.Metric, target: cfg.Target}, nil
+}
+
+func (p *TargetTracking) Name() string {
+	return TypeTargetTracking
+}
+
+func (p *TargetTracking) Evaluate(ctx context.Context, in Input) (Recommendation, error) {
+	value, err := metric(in, p.metric)
+	if err != nil {
+		return Recommendation{}, err
+	}
+
+	if in.CurrentCapacity == 0 {
+		return Recommendation{
+			Policy:          p.Name(),
+			DesiredCapacity: 0,
+			Reason:          fmt.Sprintf("%s cannot be measured without capacity", p.metric),
+		}, nil
+	}
+
+	// Tolerate floating point noise so that utilization exactly at target holds
+	desired := int(math.Ceil(float64(in.CurrentCapacity)*value/p.target - 1e-9))
+	return Recommendation{
+		Policy:          p.Name(),
+		DesiredCapacity: desired,
+		Reason: fmt.Sprintf("%s is %.3g against a target of %.3g with capacity %d",
+			p.metric, value, p.target, in.CurrentCapacity),
+	}, nil
+}
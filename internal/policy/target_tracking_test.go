@@ -0,0 +1,77 @@
+package policy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetTracking_Evaluate(t *testing.T) {
+	p, err := NewTargetTracking(config.PolicyConfig{Type: TypeTargetTracking, Metric: "cpu", Target: 0.6})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		current  int
+		value    float64
+		expected int
+	}{
+		{"at target", 3, 0.6, 3},
+		{"above target", 2, 0.9, 3},
+		{"below target", 4, 0.15, 1},
+		{"idle", 4, 0, 0},
+		{"no capacity", 0, 0.9, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec, err := p.Evaluate(context.Background(), Input{
+				CurrentCapacity: tc.current,
+				Metrics:         map[string]float64{"cpu": tc.value},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, rec.DesiredCapacity)
+			assert.Equal(t, TypeTargetTracking, rec.Policy)
+			assert.NotEmpty(t, rec.Reason)
+		})
+	}
+}
+
+func TestTargetTracking_MissingMetric(t *testing.T) {
+	p, err := NewTargetTracking(config.PolicyConfig{Metric: "cpu", Target: 0.6})
+	require.NoError(t, err)
+
+	_, err = p.Evaluate(context.Background(), Input{CurrentCapacity: 1, Metrics: map[string]float64{}})
+	assert.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	p, err := New(config.PolicyConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, p)
+
+	_, err = New(config.PolicyConfig{Type: "unknown"})
+	assert.Error(t, err)
+
+	_, err = New(config.PolicyConfig{Type: TypeTargetTracking, Metric: "cpu"})
+	assert.Error(t, err)
+}
+
+func TestConcurrency_Evaluate(t *testing.T) {
+	p, err := New(config.PolicyConfig{Type: TypeConcurrency, Target: 10})
+	require.NoError(t, err)
+
+	rec, err := p.Evaluate(context.Background(), Input{CurrentCapacity: 0, Metrics: map[string]float64{"concurrency": 25}})
+	require.NoError(t, err)
+	assert.Equal(t, 3, rec.DesiredCapacity)
+
+	rec, err = p.Evaluate(context.Background(), Input{CurrentCapacity: 3, Metrics: map[string]float64{"concurrency": 20}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, rec.DesiredCapacity)
+
+	_, err = New(config.PolicyConfig{Type: TypeConcurrency})
+	assert.Error(t, err)
+}
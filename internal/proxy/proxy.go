@@ -0,0 +1,180 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/metrics"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
+)
+
+// Names of the metric sources exposed by the proxy
+const (
+	ConcurrencyMetric = "concurrency"
+	RPSMetric         = "rps"
+)
+
+const (
+	// observationMaxAge bounds how stale the capacity seen by the proxy may be
+	observationMaxAge = time.Second
+	// holdPollInterval is how often held requests check for capacity
+	holdPollInterval = 250 * time.Millisecond
+)
+
+// Activator gives the proxy access to the capacity of the scaled resource
+type Activator interface {
+	ObservedCapacity(ctx context.Context, maxAge time.Duration) (omnistrate_api.ResourceInstanceCapacity, error)
+	Wake(ctx context.Context, reason string) error
+}
+
+// Proxy forwards requests to the scaled service while counting in-flight and
+// per-second requests. When the service has been scaled to zero it wakes it
+// and holds requests until capacity is available.
+type Proxy struct {
+	reverse     *httputil.ReverseProxy
+	activator   Activator
+	holdTimeout time.Duration
+	stats       *requestStats
+	now         func() time.Time
+
+	waking atomic.Bool
+	mu     sync.Mutex
+	cold   bool
+}
+
+// New creates a proxy for the configured target
+func New(cfg config.ProxyConfig, activator Activator) (*Proxy, error) {
+	target, err := url.Parse(cfg.Target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy target %q: %w", cfg.Target, err)
+	}
+	if target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("proxy target %q must be an absolute URL", cfg.Target)
+	}
+
+	p := &Proxy{
+		reverse:     httputil.NewSingleHostReverseProxy(target),
+		activator:   activator,
+		holdTimeout: cfg.HoldTimeout,
+		stats:       newRequestStats(cfg.Window, time.Now()),
+		now:         time.Now,
+	}
+	p.reverse.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		logger.Warn().Err(err).Str("path", r.URL.Path).Msg("Proxy failed to reach upstream")
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	return p, nil
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.stats.start(p.now())
+	defer func() {
+		p.stats.finish(p.now())
+	}()
+
+	if err := p.awaitCapacity(r.Context()); err != nil {
+		logger.Warn().Err(err).Str("path", r.URL.Path).Msg("Proxy gave up waiting for capacity")
+		http.Error(w, "Service is scaling up, please retry", http.StatusServiceUnavailable)
+		return
+	}
+	p.reverse.ServeHTTP(w, r)
+}
+
+// InFlight returns the number of requests currently being served or held
+func (p *Proxy) InFlight() int {
+	return p.stats.current()
+}
+
+// Sources returns the metric sources fed by the proxy
+func (p *Proxy) Sources() []metrics.Source {
+	return []metrics.Source{
+		&statSource{name: ConcurrencyMetric, proxy: p},
+		&statSource{name: RPSMetric, proxy: p},
+	}
+}
+
+// awaitCapacity holds the request while the resource is scaled to zero. Once
+// capacity has been seen the proxy stays warm until it observes zero again, so
+// requests keep flowing while additional capacity is still STARTING.
+func (p *Proxy) awaitCapacity(ctx context.Context) error {
+	if p.activator == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.holdTimeout)
+	defer cancel()
+
+	for {
+		capacity, err := p.activator.ObservedCapacity(ctx, observationMaxAge)
+		if err != nil {
+			// Do not block traffic because the sidecar cannot be reached
+			logger.Debug().Err(err).Msg("Proxy could not observe capacity, forwarding request")
+			return nil
+		}
+		if p.warm(capacity) {
+			return nil
+		}
+		p.wake()
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("no capacity available after %s", p.holdTimeout)
+		case <-time.After(holdPollInterval):
+		}
+	}
+}
+
+// warm reports whether requests can be forwarded given the observed capacity
+func (p *Proxy) warm(capacity omnistrate_api.ResourceInstanceCapacity) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if capacity.CurrentCapacity == 0 {
+		p.cold = true
+		return false
+	}
+	if p.cold && capacity.Status != omnistrate_api.ACTIVE {
+		return false
+	}
+	p.cold = false
+	return true
+}
+
+// wake asks the activator to scale from zero unless a wake is already running
+func (p *Proxy) wake() {
+	if !p.waking.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer p.waking.Store(false)
+		if err := p.activator.Wake(context.Background(), "request received by proxy"); err != nil {
+			logger.Warn().Err(err).Msg("Proxy failed to wake resource")
+		}
+	}()
+}
+
+// statSource exposes one of the proxy's windowed averages as a metric source
+type statSource struct {
+	name  string
+	proxy *Proxy
+}
+
+func (s *statSource) Name() string {
+	return s.name
+}
+
+func (s *statSource) Read(ctx context.Context) (float64, error) {
+	concurrency, rps := s.proxy.stats.averages(s.proxy.now())
+	if s.name == ConcurrencyMetric {
+		return concurrency, nil
+	}
+	return rps, nil
+}
@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/omnistrate_api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeActivator reports a configurable capacity and becomes ready once woken
+type fakeActivator struct {
+	mu       sync.Mutex
+	capacity omnistrate_api.ResourceInstanceCapacity
+	err      error
+	wakes    int
+	onWake   func(*fakeActivator)
+}
+
+func (f *fakeActivator) ObservedCapacity(ctx context.Context, maxAge time.Duration) (omnistrate_api.ResourceInstanceCapacity, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.capacity, f.err
+}
+
+func (f *fakeActivator) Wake(ctx context.Context, reason string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.wakes++
+	if f.onWake != nil {
+		f.onWake(f)
+	}
+	return nil
+}
+
+func newUpstream(t *testing.T) *httptest.Server {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "hello from "+r.URL.Path)
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+func newTestProxy(t *testing.T, activator Activator, holdTimeout time.Duration) *Proxy {
+	p, err := New(config.ProxyConfig{
+		Target:      newUpstream(t).URL,
+		HoldTimeout: holdTimeout,
+		Window:      10 * time.Second,
+	}, activator)
+	require.NoError(t, err)
+	return p
+}
+
+func TestProxy_ForwardsWhenCapacityAvailable(t *testing.T) {
+	activator := &fakeActivator{capacity: omnistrate_api.ResourceInstanceCapacity{Status: omnistrate_api.ACTIVE, CurrentCapacity: 2}}
+	p := newTestProxy(t, activator, time.Second)
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/work", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "hello from /work", recorder.Body.String())
+	assert.Equal(t, 0, activator.wakes)
+	assert.Equal(t, 0, p.InFlight())
+}
+
+func TestProxy_HoldsAndWakesFromZero(t *testing.T) {
+	activator := &fakeActivator{capacity: omnistrate_api.ResourceInstanceCapacity{Status: omnistrate_api.ACTIVE}}
+	activator.onWake = func(f *fakeActivator) {
+		f.capacity = omnistrate_api.ResourceInstanceCapacity{Status: omnistrate_api.ACTIVE, CurrentCapacity: 1}
+	}
+	p := newTestProxy(t, activator, 5*time.Second)
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/work", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, 1, activator.wakes)
+}
+
+func TestProxy_StaysColdUntilActive(t *testing.T) {
+	p := newTestProxy(t, nil, time.Second)
+
+	assert.False(t, p.warm(omnistrate_api.ResourceInstanceCapacity{Status: omnistrate_api.ACTIVE}))
+	assert.False(t, p.warm(omnistrate_api.ResourceInstanceCapacity{Status: omnistrate_api.STARTING, CurrentCapacity: 1}))
+	assert.True(t, p.warm(omnistrate_api.ResourceInstanceCapacity{Status: omnistrate_api.ACTIVE, CurrentCapacity: 1}))
+	// Once warm, scaling up further does not hold requests
+	assert.True(t, p.warm(omnistrate_api.ResourceInstanceCapacity{Status: omnistrate_api.STARTING, CurrentCapacity: 2}))
+}
+
+func TestProxy_TimesOutWhileHolding(t *testing.T) {
+	activator := &fakeActivator{capacity: omnistrate_api.ResourceInstanceCapacity{Status: omnistrate_api.ACTIVE}}
+	p := newTestProxy(t, activator, 300*time.Millisecond)
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/work", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
+
+func TestProxy_ForwardsWhenCapacityUnknown(t *testing.T) {
+	activator := &fakeActivator{err: errors.New("sidecar unavailable")}
+	p := newTestProxy(t, activator, time.Second)
+
+	recorder := httptest.NewRecorder()
+	p.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/work", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestNew_InvalidTarget(t *testing.T) {
+	_, err := New(config.ProxyConfig{Target: "worker:8080", Window: time.Second}, nil)
+	assert.Error(t, err)
+}
+
+func TestRequestStats_Averages(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	stats := newRequestStats(10*time.Second, start)
+
+	// Two requests held open for 5 seconds each, overlapping fully
+	stats.start(start)
+	stats.start(start)
+	stats.finish(start.Add(5 * time.Second))
+	stats.finish(start.Add(5 * time.Second))
+
+	concurrency, rps := stats.averages(start.Add(9 * time.Second))
+	assert.InDelta(t, 1.0, concurrency, 0.0001) // 10 request-seconds over a 10s window
+	assert.InDelta(t, 0.2, rps, 0.0001)
+
+	// Once the requests fall out of the window the averages return to zero
+	concurrency, rps = stats.averages(start.Add(30 * time.Second))
+	assert.Equal(t, 0.0, concurrency)
+	assert.Equal(t, 0.0, rps)
+}
+
+func TestRequestStats_LongRunningRequest(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	stats := newRequestStats(10*time.Second, start)
+
+	stats.start(start)
+	concurrency, _ := stats.averages(start.Add(time.Minute))
+	assert.InDelta(t, 1.0, concurrency, 0.11)
+	assert.Equal(t, 1, stats.current())
+}
@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+)
+
+// requestStats keeps per-second buckets of request counts and concurrency over a
+// sliding window. Concurrency is accumulated as in-flight request seconds so the
+// average reflects how long requests were actually held open.
+type requestStats struct {
+	mu         sync.Mutex
+	buckets    []bucket
+	inFlight   int
+	lastChange time.Time
+}
+
+type bucket struct {
+	second      int64
+	requests    float64
+	concurrency float64
+}
+
+func newRequestStats(window time.Duration, now time.Time) *requestStats {
+	size := int(window / time.Second)
+	if size < 1 {
+		size = 1
+	}
+	return &requestStats{
+		buckets:    make([]bucket, size),
+		lastChange: now,
+	}
+}
+
+// start records a request entering the proxy
+func (s *requestStats) start(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(now)
+	s.bucketFor(now.Unix()).requests++
+	s.inFlight++
+}
+
+// finish records a request leaving the proxy
+func (s *requestStats) finish(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(now)
+	s.inFlight--
+}
+
+// current returns the number of requests currently being served or held
+func (s *requestStats) current() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inFlight
+}
+
+// averages returns the mean concurrency and requests per second over the window
+func (s *requestStats) averages(now time.Time) (concurrency float64, rps float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(now)
+
+	oldest := now.Unix() - int64(len(s.buckets)) + 1
+	for _, b := range s.buckets {
+		if b.second >= oldest && b.second <= now.Unix() {
+			concurrency += b.concurrency
+			rps += b.requests
+		}
+	}
+	window := float64(len(s.buckets))
+	return concurrency / window, rps / window
+}
+
+// advance attributes the in-flight time since the last change to the buckets
+// of the seconds it spans
+func (s *requestStats) advance(now time.Time) {
+	from := s.lastChange
+	if earliest := now.Add(-time.Duration(len(s.buckets)) * time.Second); from.Before(earliest) {
+		from = earliest
+	}
+	for s.inFlight > 0 && from.Before(now) {
+		boundary := time.Unix(from.Unix()+1, 0)
+		if boundary.After(now) {
+			boundary = now
+		}
+		s.bucketFor(from.Unix()).concurrency += float64(s.inFlight) * boundary.Sub(from).Seconds()
+		from = boundary
+	}
+	if now.After(s.lastChange) {
+		s.lastChange = now
+	}
+}
+
+// bucketFor returns the bucket of the given second, recycling stale buckets
+func (s *requestStats) bucketFor(second int64) *bucket {
+	b := &s.buckets[int(second%int64(len(s.buckets)))]
+	if b.second != second {
+		*b = bucket{second: second}
+	}
+	return b
+}
@@ -0,0 +1,117 @@
+package tlsserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/logger"
+)
+
+// NewConfig creates the TLS configuration of the API server. The server
+// certificate is taken from the returned Certificate, which is reloaded while
+// it is watched. Client certificates are verified against the client CAs when
+// configured, and required of every client when RequireClientCert is set.
+func NewConfig(cfg config.TLSConfig) (*tls.Config, *Certificate, error) {
+	cert, err := LoadCertificate(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: cert.GetCertificate,
+	}
+
+	if cfg.ClientCAFile != "" {
+		caPEM, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, nil, fmt.Errorf("failed to parse client CA file: %s", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		if cfg.RequireClientCert {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return tlsConfig, cert, nil
+}
+
+// Certificate is a server certificate that is loaded again when its
+// certificate or key file changes
+type Certificate struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// LoadCertificate loads the certificate chain and private key from PEM files
+func LoadCertificate(certFile, keyFile string) (*Certificate, error) {
+	c := &Certificate{certFile: certFile, keyFile: keyFile}
+	if _, err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetCertificate returns the current certificate, for tls.Config
+func (c *Certificate) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// Reload reads the certificate and key files again and reports whether the
+// certificate changed. The current certificate is kept when the files cannot
+// be loaded, e.g. while only one of them has been replaced.
+func (c *Certificate) Reload() (bool, error) {
+	certPEM, err := os.ReadFile(c.certFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read TLS certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(c.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read TLS key: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cert != nil && bytes.Equal(certPEM, c.certPEM) && bytes.Equal(keyPEM, c.keyPEM) {
+		return false, nil
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false, fmt.Errorf("failed to load TLS certificate %s with key %s: %w", c.certFile, c.keyFile, err)
+	}
+	c.cert, c.certPEM, c.keyPEM = &cert, certPEM, keyPEM
+	return true, nil
+}
+
+// Watch reloads the certificate every interval until the context is
+// cancelled, the same way config.Watch follows the configuration file
+func (c *Certificate) Watch(ctx context.Context, interval time.Duration) {
+	config.Poll(ctx, interval, func() {
+		changed, err := c.Reload()
+		if err != nil {
+			// The files can briefly disappear or mismatch while they are being replaced
+			logger.Warn().Err(err).Str("path", c.certFile).Msg("Failed to reload TLS certificate")
+			return
+		}
+		if changed {
+			logger.Info().Str("path", c.certFile).Msg("TLS certificate reloaded")
+		}
+	})
+}
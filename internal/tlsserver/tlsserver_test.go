@@ -0,0 +1,213 @@
+package tlsserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/auth"
+	"github.com/omnistrate-community/custom-auto-scaling-example/internal/config"
+)
+
+// testCert is a certificate and key issued for a test
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// issue creates a certificate for the subject signed by the parent, or
+// self-signed without one
+func issue(t *testing.T, parent *testCert, subject pkix.Name, isCA bool) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// tlsCertificate returns the certificate for a TLS client
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+	cert, err := tls.X509KeyPair(c.certPEM, c.keyPEM)
+	require.NoError(t, err)
+	return cert
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+func TestCertificate_Reload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	first := issue(t, nil, pkix.Name{CommonName: "first"}, false)
+	writeFile(t, certFile, first.certPEM)
+	writeFile(t, keyFile, first.keyPEM)
+
+	cert, err := LoadCertificate(certFile, keyFile)
+	require.NoError(t, err)
+	served, err := cert.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, first.cert.Raw, served.Certificate[0])
+
+	changed, err := cert.Reload()
+	require.NoError(t, err)
+	assert.False(t, changed, "unchanged files should not reload")
+
+	// A renewed certificate is served after the reload
+	second := issue(t, nil, pkix.Name{CommonName: "second"}, false)
+	writeFile(t, certFile, second.certPEM)
+	writeFile(t, keyFile, second.keyPEM)
+	changed, err = cert.Reload()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	served, _ = cert.GetCertificate(nil)
+	assert.Equal(t, second.cert.Raw, served.Certificate[0])
+
+	// A certificate whose key has not been replaced yet is not loaded
+	third := issue(t, nil, pkix.Name{CommonName: "third"}, false)
+	writeFile(t, certFile, third.certPEM)
+	_, err = cert.Reload()
+	assert.Error(t, err)
+	served, _ = cert.GetCertificate(nil)
+	assert.Equal(t, second.cert.Raw, served.Certificate[0])
+}
+
+func TestLoadCertificate_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	_, err := LoadCertificate(filepath.Join(dir, "missing.crt"), filepath.Join(dir, "missing.key"))
+	assert.ErrorContains(t, err, "failed to read TLS certificate")
+
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeFile(t, certFile, []byte("not a certificate"))
+	writeFile(t, keyFile, []byte("not a key"))
+	_, err = LoadCertificate(certFile, keyFile)
+	assert.ErrorContains(t, err, "failed to load TLS certificate")
+}
+
+func TestNewConfig_ClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	ca := issue(t, nil, pkix.Name{CommonName: "clients"}, true)
+	server := issue(t, ca, pkix.Name{CommonName: "localhost"}, false)
+	writeFile(t, filepath.Join(dir, "tls.crt"), server.certPEM)
+	writeFile(t, filepath.Join(dir, "tls.key"), server.keyPEM)
+	writeFile(t, filepath.Join(dir, "ca.crt"), ca.certPEM)
+	cfg := config.TLSConfig{
+		CertFile:     filepath.Join(dir, "tls.crt"),
+		KeyFile:      filepath.Join(dir, "tls.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+	}
+
+	// The server answers with the principal of the client certificate
+	start := func(cfg config.TLSConfig) string {
+		tlsConfig, _, err := NewConfig(cfg)
+		require.NoError(t, err)
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		srv := &http.Server{
+			TLSConfig: tlsConfig,
+			ErrorLog:  log.New(io.Discard, "", 0),
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal, err := auth.ClientCerts{}.Authenticate(r)
+				if err != nil {
+					fmt.Fprint(w, err)
+					return
+				}
+				fmt.Fprintf(w, "%s %s %s", principal.Name, principal.Method, principal.Role)
+			}),
+		}
+		go srv.ServeTLS(listener, "", "")
+		t.Cleanup(func() { srv.Close() })
+		return "https://" + listener.Addr().String()
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(url string, clientCert *testCert) (string, error) {
+		clientConfig := &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+		if clientCert != nil {
+			// Send the certificate even when the server does not accept its CA
+			cert := clientCert.tlsCertificate(t)
+			clientConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				return &cert, nil
+			}
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}
+		resp, err := client.Get(url)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	deployBot := issue(t, ca, pkix.Name{CommonName: "deploy-bot", Organization: []string{"ci", "operator"}}, false)
+	stranger := issue(t, nil, pkix.Name{CommonName: "stranger", Organization: []string{"admin"}}, false)
+
+	url := start(cfg)
+	body, err := get(url, deployBot)
+	require.NoError(t, err)
+	assert.Equal(t, "deploy-bot client-cert operator", body)
+
+	// Clients without a certificate are left to the other authenticators
+	body, err = get(url, nil)
+	require.NoError(t, err)
+	assert.Equal(t, auth.ErrNoCredentials.Error(), body)
+
+	// Certificates of other CAs are rejected during the handshake
+	_, err = get(url, stranger)
+	assert.Error(t, err)
+
+	// Requiring client certificates rejects clients without one
+	cfg.RequireClientCert = true
+	_, err = get(start(cfg), nil)
+	assert.Error(t, err)
+}